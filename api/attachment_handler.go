@@ -0,0 +1,684 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisbakker/journal/cleanup"
+	"github.com/chrisbakker/journal/config"
+	"github.com/chrisbakker/journal/extract"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/imaging"
+	"github.com/chrisbakker/journal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// multipartOverhead allows for the multipart framing around an uploaded file
+const multipartOverhead = 1 << 20
+
+// sniffLen is how much of an upload http.DetectContentType looks at
+const sniffLen = 512
+
+// thumbnailWidths are the widths thumbnails are made at, for images wider
+var thumbnailWidths = []int{160, 320, 640}
+
+// AttachmentResponse describes an attachment. Referenced reports whether
+// the entry's body links to it; OrphanedAt is when the cleanup job first
+// found it unreferenced.
+type AttachmentResponse struct {
+	ID           string     `json:"id"`
+	EntryID      string     `json:"entry_id"`
+	Filename     string     `json:"filename"`
+	MimeType     string     `json:"mime_type"`
+	SizeBytes    int64      `json:"size_bytes"`
+	URL          string     `json:"url"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	Referenced   bool       `json:"referenced"`
+	OrphanedAt   *time.Time `json:"orphaned_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func attachmentToResponse(attachment db.Attachment, refs map[string]bool) AttachmentResponse {
+	id := attachment.ID.String()
+	response := AttachmentResponse{
+		ID:         id,
+		EntryID:    attachment.EntryID.String(),
+		Filename:   attachment.Filename,
+		MimeType:   attachment.MimeType,
+		SizeBytes:  attachment.SizeBytes,
+		URL:        "/api/attachments/" + id,
+		Referenced: refs[id],
+		CreatedAt:  attachment.CreatedAt.Time,
+	}
+	if inlineContentType(attachment.MimeType) && attachment.MimeType != "application/pdf" {
+		response.ThumbnailURL = "/api/attachments/" + id + "/thumbnail"
+	}
+	if attachment.OrphanedAt.Valid {
+		response.OrphanedAt = &attachment.OrphanedAt.Time
+	}
+	return response
+}
+
+// ListAttachments lists an entry's attachments, oldest first
+func (h *Handler) ListAttachments(c *gin.Context) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.queries.GetEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	if err != nil || entry.UserID != h.getDefaultUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	attachments, err := h.queries.ListAttachmentsForEntry(c.Request.Context(), entry.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	refs := cleanup.References(entry.BodyDelta)
+	response := make([]AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		response = append(response, attachmentToResponse(attachment, refs))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListOrphanedAttachments lists the user's attachments that the cleanup job
+// found unreferenced, longest orphaned first
+func (h *Handler) ListOrphanedAttachments(c *gin.Context) {
+	attachments, err := h.queries.ListOrphanedAttachments(c.Request.Context(), h.getDefaultUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		response = append(response, attachmentToResponse(attachment, nil))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UploadAttachment streams the "file" part of a multipart upload into an
+// attachment. The content type is sniffed from the data rather than trusted
+// from the client, and must be in the configured allowlist. Uploads are
+// limited by the maximum upload size and the user's remaining quota. Images
+// get thumbnails, and JPEGs can have their GPS location stripped. Text is
+// extracted from PDFs and text files for search and Chat.
+func (h *Handler) UploadAttachment(c *gin.Context, cfg config.AttachmentsConfig) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	entry, err := h.queries.GetEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	if err != nil || entry.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	maxBytes := int64(cfg.MaxUploadMB) << 20
+	limit := maxBytes
+	quotaLimited := false
+	if cfg.QuotaMB >= 0 {
+		used, err := h.queries.GetAttachmentUsage(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if remaining := int64(cfg.QuotaMB)<<20 - used; remaining < limit {
+			limit = remaining
+			quotaLimited = true
+		}
+	}
+	quotaError := gin.H{"error": fmt.Sprintf("attachment storage quota of %d MB exceeded", cfg.QuotaMB)}
+	if limit <= 0 {
+		c.JSON(http.StatusInsufficientStorage, quotaError)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+
+	part, err := nextFilePart(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file provided"})
+		return
+	}
+	defer part.Close()
+
+	// Read the head of the file to sniff its type before accepting the rest
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	head = head[:n]
+
+	mimeType := sniffContentType(head, part.FileName())
+	if !allowedContentType(mimeType, cfg.AllowedTypes) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("file type %s is not allowed", mimeType)})
+		return
+	}
+
+	// Spool to disk, hashing on the way, so the upload is stored under its
+	// content hash without being held in memory
+	var content io.Reader = io.MultiReader(bytes.NewReader(head), part)
+	if cfg.StripGPS && mimeType == "image/jpeg" {
+		content = imaging.StripGPS(content)
+	}
+	upload, err := storage.Spool(content, limit)
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, storage.ErrTooLarge) || errors.As(err, &maxBytesErr) {
+		if quotaLimited {
+			c.JSON(http.StatusInsufficientStorage, quotaError)
+			return
+		}
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds the %d MB upload limit", cfg.MaxUploadMB)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer upload.Close()
+
+	store, err := storage.New(cfg.Storage, h.queries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Identical files share stored contents, so record this use before
+	// storing them in case a deletion of the same file is finishing
+	if err := storage.Acquire(c.Request.Context(), h.queries, store.Name(), upload.Key, upload.Size); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := store.Put(c.Request.Context(), upload.Key, upload, upload.Size); err != nil {
+		h.releaseContents(c.Request.Context(), store.Name(), upload.Key, cfg.Storage)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file: " + err.Error()})
+		return
+	}
+
+	attachment, err := h.queries.CreateAttachment(c.Request.Context(), db.CreateAttachmentParams{
+		UserID:         userID,
+		EntryID:        entry.ID,
+		Filename:       part.FileName(),
+		MimeType:       mimeType,
+		SizeBytes:      upload.Size,
+		StorageBackend: store.Name(),
+		StorageKey:     upload.Key,
+	})
+
+	if err != nil {
+		h.releaseContents(c.Request.Context(), store.Name(), upload.Key, cfg.Storage)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if imaging.Supported(mimeType) {
+		if err := h.createThumbnails(c.Request.Context(), store, attachment, upload, cfg.Storage); err != nil {
+			log.Printf("Error creating thumbnails for attachment %s: %v", attachment.ID.String(), err)
+		}
+	}
+	if extract.Supported(mimeType) {
+		if err := h.extractText(c.Request.Context(), attachment, upload); err != nil {
+			log.Printf("Error extracting text from attachment %s: %v", attachment.ID.String(), err)
+		}
+	}
+
+	// Not referenced yet: the editor links it after the upload
+	c.JSON(http.StatusCreated, attachmentToResponse(attachment, nil))
+}
+
+// GetAttachment serves an attachment's contents from its storage backend
+func (h *Handler) GetAttachment(c *gin.Context, cfg config.AttachmentsConfig) {
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+
+	attachment, err := h.queries.GetAttachment(c.Request.Context(), pgtype.UUID{Bytes: attachmentID, Valid: true})
+	if err != nil || attachment.UserID != h.getDefaultUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+
+	content, err := openContent(c.Request.Context(), h.queries, attachment.StorageBackend, attachment.StorageKey, cfg.Storage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if inlineContentType(attachment.MimeType) {
+		disposition = "inline"
+	}
+	serveImmutable(c, content, attachment.StorageKey, attachment.MimeType, contentDisposition(disposition, attachment.Filename), attachment.CreatedAt.Time)
+}
+
+// GetAttachmentThumbnail serves the smallest thumbnail of an image attachment
+// at least w pixels wide (default 320). Images with no thumbnail that wide
+// are served as they are, since thumbnails are only made for larger images.
+func (h *Handler) GetAttachmentThumbnail(c *gin.Context, cfg config.AttachmentsConfig) {
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+
+	width := 320
+	if w := c.Query("w"); w != "" {
+		width, err = strconv.Atoi(w)
+		if err != nil || width <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid width"})
+			return
+		}
+	}
+
+	attachment, err := h.queries.GetAttachment(c.Request.Context(), pgtype.UUID{Bytes: attachmentID, Valid: true})
+	if err != nil || attachment.UserID != h.getDefaultUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+
+	thumbnails, err := h.queries.ListAttachmentThumbnails(c.Request.Context(), attachment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	backend, key, mimeType := attachment.StorageBackend, attachment.StorageKey, attachment.MimeType
+	found := false
+	for _, thumbnail := range thumbnails {
+		if int(thumbnail.Width) >= width {
+			backend, key, mimeType = thumbnail.StorageBackend, thumbnail.StorageKey, thumbnail.MimeType
+			found = true
+			break
+		}
+	}
+	// Otherwise serve the original, if it's an image browsers can show
+	if !found && (mimeType == "application/pdf" || !inlineContentType(mimeType)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment has no thumbnail"})
+		return
+	}
+
+	content, err := openContent(c.Request.Context(), h.queries, backend, key, cfg.Storage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	serveImmutable(c, content, key, mimeType, contentDisposition("inline", attachment.Filename), attachment.CreatedAt.Time)
+}
+
+// serveImmutable serves stored content, with Range support for large files
+// and media. Contents never change for a given storage key, so the key is
+// the ETag and responses are cached as immutable.
+func serveImmutable(c *gin.Context, content io.ReadSeeker, key, mimeType, disposition string, modTime time.Time) {
+	// Override the no-cache headers set for API responses
+	header := c.Writer.Header()
+	header.Del("Pragma")
+	header.Del("Expires")
+	header.Set("Cache-Control", "private, max-age=31536000, immutable")
+	header.Set("ETag", `"`+key+`"`)
+	header.Set("Content-Type", mimeType)
+	header.Set("Content-Disposition", disposition)
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent answers Range and If-None-Match/If-Modified-Since requests
+	http.ServeContent(c.Writer, c.Request, "", modTime, content)
+}
+
+// DeleteAttachment deletes an attachment and its thumbnails, and their stored
+// contents once nothing else shares them
+func (h *Handler) DeleteAttachment(c *gin.Context, cfg config.AttachmentsConfig) {
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+
+	attachment, err := h.queries.GetAttachment(c.Request.Context(), pgtype.UUID{Bytes: attachmentID, Valid: true})
+	if err != nil || attachment.UserID != h.getDefaultUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+
+	if err := h.RemoveAttachment(c.Request.Context(), attachment, cfg.Storage); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RemoveAttachment deletes an attachment and its thumbnails, then their
+// stored contents once nothing else shares them. Failing to delete contents
+// is only logged: the unused blobs are swept up by the cleanup service.
+func (h *Handler) RemoveAttachment(ctx context.Context, attachment db.Attachment, cfg config.StorageConfig) error {
+	var unused []db.DeleteBlobParams
+	err := h.entries.WithTx(ctx, func(q *db.Queries) error {
+		var err error
+		unused, err = removeAttachmentRows(ctx, q, attachment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.purgeContents(ctx, unused, cfg)
+	return nil
+}
+
+// RemoveOrphanedAttachment deletes an attachment the cleanup service found
+// orphaned, unless its entry refers to it again. The entry is locked while
+// its body is checked, so an edit can't embed the file in between. It
+// reports whether the attachment was removed.
+func (h *Handler) RemoveOrphanedAttachment(ctx context.Context, id pgtype.UUID, cfg config.StorageConfig) (bool, error) {
+	var unused []db.DeleteBlobParams
+	removed := false
+	err := h.entries.WithTx(ctx, func(q *db.Queries) error {
+		attachment, err := q.GetAttachment(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Archived entries are left alone, as by the scan
+		entry, err := q.GetEntryForUpdate(ctx, attachment.EntryID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if cleanup.References(entry.BodyDelta)[attachment.ID.String()] {
+			return q.ClearAttachmentsOrphaned(ctx, []pgtype.UUID{attachment.ID})
+		}
+
+		unused, err = removeAttachmentRows(ctx, q, attachment)
+		removed = err == nil
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	h.purgeContents(ctx, unused, cfg)
+	return removed, nil
+}
+
+// markReferencedAttachments records which of an entry's attachments its body
+// refers to, so that removing one from the body later can orphan it
+func markReferencedAttachments(ctx context.Context, q *db.Queries, entry db.Entry) error {
+	var ids []pgtype.UUID
+	for ref := range cleanup.References(entry.BodyDelta) {
+		var id pgtype.UUID
+		if err := id.Scan(ref); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return q.MarkAttachmentsReferenced(ctx, db.MarkAttachmentsReferencedParams{
+		EntryID: entry.ID,
+		Ids:     ids,
+	})
+}
+
+// removeAttachmentRows deletes an attachment and its thumbnails on q and
+// releases their stored contents, returning those no longer used by
+// anything, to be purged once q's transaction commits
+func removeAttachmentRows(ctx context.Context, q *db.Queries, attachment db.Attachment) ([]db.DeleteBlobParams, error) {
+	thumbnails, err := q.ListAttachmentThumbnails(ctx, attachment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Thumbnails and extracted text go with the attachment by ON DELETE CASCADE
+	if err := q.DeleteAttachment(ctx, attachment.ID); err != nil {
+		return nil, err
+	}
+
+	var unused []db.DeleteBlobParams
+	release := func(backend, key string) error {
+		last, err := storage.Release(ctx, q, backend, key)
+		if last {
+			unused = append(unused, db.DeleteBlobParams{StorageBackend: backend, StorageKey: key})
+		}
+		return err
+	}
+	if err := release(attachment.StorageBackend, attachment.StorageKey); err != nil {
+		return nil, err
+	}
+	for _, thumbnail := range thumbnails {
+		if err := release(thumbnail.StorageBackend, thumbnail.StorageKey); err != nil {
+			return nil, err
+		}
+	}
+	return unused, nil
+}
+
+// purgeContents deletes stored contents released by removeAttachmentRows,
+// logging failures for the cleanup service to retry
+func (h *Handler) purgeContents(ctx context.Context, unused []db.DeleteBlobParams, cfg config.StorageConfig) {
+	for _, blob := range unused {
+		if err := storage.Purge(ctx, h.dbpool, blob.StorageBackend, blob.StorageKey, cfg); err != nil {
+			log.Printf("Error deleting stored contents %s: %v", blob.StorageKey, err)
+		}
+	}
+}
+
+// releaseContents undoes storage.Acquire when storing an upload fails,
+// deleting the contents if nothing else uses them
+func (h *Handler) releaseContents(ctx context.Context, backend, key string, cfg config.StorageConfig) {
+	last, err := storage.Release(ctx, h.queries, backend, key)
+	if err == nil && last {
+		err = storage.Purge(ctx, h.dbpool, backend, key, cfg)
+	}
+	if err != nil {
+		log.Printf("Error releasing stored contents %s: %v", key, err)
+	}
+}
+
+// createThumbnails stores downscaled copies of an uploaded image at each
+// thumbnail width smaller than the image. They are encoded as JPEG, or PNG
+// if the image has transparency.
+func (h *Handler) createThumbnails(ctx context.Context, store storage.Store, attachment db.Attachment, upload io.ReadSeeker, cfg config.StorageConfig) error {
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, release, err := imaging.Decode(ctx, upload)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, width := range thumbnailWidths {
+		if width >= img.Rect.Dx() {
+			break
+		}
+		thumbnail := imaging.Resize(img, width)
+
+		var buf bytes.Buffer
+		mimeType, err := imaging.Encode(&buf, thumbnail)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		key := hex.EncodeToString(sum[:])
+
+		if err := storage.Acquire(ctx, h.queries, store.Name(), key, int64(buf.Len())); err != nil {
+			return err
+		}
+		if err := store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			h.releaseContents(ctx, store.Name(), key, cfg)
+			return err
+		}
+		created, err := h.queries.CreateAttachmentThumbnail(ctx, db.CreateAttachmentThumbnailParams{
+			AttachmentID:   attachment.ID,
+			Width:          int32(thumbnail.Rect.Dx()),
+			Height:         int32(thumbnail.Rect.Dy()),
+			MimeType:       mimeType,
+			SizeBytes:      int64(buf.Len()),
+			StorageBackend: store.Name(),
+			StorageKey:     key,
+		})
+		// A thumbnail that already exists keeps its own contents
+		if err != nil || created == 0 {
+			h.releaseContents(ctx, store.Name(), key, cfg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractText stores the text of an uploaded attachment. Files with no
+// readable text, such as encrypted PDFs, are stored with empty text so
+// they aren't retried.
+func (h *Handler) extractText(ctx context.Context, attachment db.Attachment, upload io.ReadSeeker) error {
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	text, err := extract.Text(upload, attachment.MimeType)
+	if err != nil {
+		log.Printf("No text extracted from attachment %s: %v", attachment.ID.String(), err)
+	}
+	return h.queries.CreateAttachmentText(ctx, db.CreateAttachmentTextParams{
+		AttachmentID: attachment.ID,
+		Content:      text,
+	})
+}
+
+// openContent opens stored contents in the backend they were stored in
+func openContent(ctx context.Context, q *db.Queries, backend, key string, cfg config.StorageConfig) (io.ReadSeekCloser, error) {
+	store, err := storage.ForBackend(backend, cfg, q)
+	if err != nil {
+		return nil, err
+	}
+	return store.Open(ctx, key)
+}
+
+// nextFilePart returns the "file" part of a multipart request without
+// buffering the parts before it to disk
+func nextFilePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// sniffContentType returns the media type of a file from its first bytes,
+// using the extension only to tell plain text formats apart
+func sniffContentType(head []byte, filename string) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if mediaType == "text/plain" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".md", ".markdown":
+			return "text/markdown"
+		case ".csv":
+			return "text/csv"
+		}
+	}
+	return mediaType
+}
+
+// inlineContentType reports whether a type is shown in the browser rather
+// than downloaded. SVG is excluded as it can carry scripts.
+func inlineContentType(mediaType string) bool {
+	return mediaType == "application/pdf" ||
+		(strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml")
+}
+
+// contentDisposition formats a Content-Disposition header per RFC 6266: an
+// ASCII filename fallback for old clients and the UTF-8 name in filename*
+func contentDisposition(disposition, filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r > 0x7e || r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback.String(), encoded.String())
+}
+
+// isAttrChar reports whether b may appear unencoded in an RFC 5987 value
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// allowedContentType reports whether mediaType matches an allowlist entry,
+// where "image/*" matches any image type
+func allowedContentType(mediaType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrEntryNotFound is returned when an entry doesn't exist or is archived
+var ErrEntryNotFound = errors.New("entry not found")
+
+// EntryService sits between the handlers and db.Queries. Each entry mutation
+// runs in one transaction together with the data derived from the entry
+// (attendee counts, tasks, links and tags), so a failure leaves none of it
+// behind and concurrent updates to an entry are serialized.
+type EntryService struct {
+	dbpool  *pgxpool.Pool
+	queries *db.Queries
+}
+
+func NewEntryService(dbpool *pgxpool.Pool, queries *db.Queries) *EntryService {
+	return &EntryService{
+		dbpool:  dbpool,
+		queries: queries,
+	}
+}
+
+// EntryPatch holds the fields of an entry update; nil fields are unchanged.
+// BodyHTML must already be sanitized, Type must name an existing entry type
+// and Tags must be normalized.
+type EntryPatch struct {
+	Title             *string
+	BodyDelta         *json.RawMessage
+	BodyHTML          *string
+	BodyText          *string
+	AttendeesOriginal *string
+	Type              *string
+	Tags              *[]string
+}
+
+// CreateEntry creates an entry and its derived data. Attendees are resolved
+// from params.AttendeesOriginal; params.Attendees is ignored.
+func (s *EntryService) CreateEntry(ctx context.Context, params db.CreateEntryParams, tags []string) (db.Entry, error) {
+	var entry db.Entry
+	err := s.WithTx(ctx, func(q *db.Queries) error {
+		var err error
+		entry, err = createEntry(ctx, q, params, tags)
+		return err
+	})
+	return entry, err
+}
+
+// createEntry is CreateEntry within a transaction the caller has begun
+func createEntry(ctx context.Context, q *db.Queries, params db.CreateEntryParams, tags []string) (db.Entry, error) {
+	params.Attendees = resolveAttendees(ctx, q, params.UserID, params.AttendeesOriginal)
+
+	entry, err := q.CreateEntry(ctx, params)
+	if err != nil {
+		return entry, err
+	}
+
+	if err := recordAttendees(ctx, q, entry.UserID, entry.Attendees, nil); err != nil {
+		return entry, err
+	}
+	if err := syncEntryTasks(ctx, q, entry); err != nil {
+		return entry, err
+	}
+	if err := syncEntryLinks(ctx, q, entry); err != nil {
+		return entry, err
+	}
+	if len(tags) > 0 {
+		return entry, setEntryTags(ctx, q, entry.UserID, entry.ID, tags)
+	}
+	return entry, nil
+}
+
+// UpdateEntry applies patch to an entry
+func (s *EntryService) UpdateEntry(ctx context.Context, id pgtype.UUID, patch EntryPatch) (db.Entry, error) {
+	return s.EditEntry(ctx, id, func(db.Entry) (EntryPatch, error) {
+		return patch, nil
+	})
+}
+
+// EditEntry locks an entry, builds a patch from its current state with edit
+// and applies it, re-deriving whatever the patch affects. An error from edit
+// is returned unchanged.
+func (s *EntryService) EditEntry(ctx context.Context, id pgtype.UUID, edit func(entry db.Entry) (EntryPatch, error)) (db.Entry, error) {
+	var entry db.Entry
+	err := s.WithTx(ctx, func(q *db.Queries) error {
+		existing, err := q.GetEntryForUpdate(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEntryNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		patch, err := edit(existing)
+		if err != nil {
+			return err
+		}
+
+		params := db.UpdateEntryParams{
+			ID:                existing.ID,
+			Title:             existing.Title,
+			BodyDelta:         existing.BodyDelta,
+			BodyHtml:          existing.BodyHtml,
+			BodyText:          existing.BodyText,
+			AttendeesOriginal: existing.AttendeesOriginal,
+			Attendees:         existing.Attendees,
+			Type:              existing.Type,
+		}
+		if patch.Title != nil {
+			params.Title = *patch.Title
+		}
+		if patch.BodyDelta != nil {
+			params.BodyDelta = *patch.BodyDelta
+		}
+		if patch.BodyHTML != nil {
+			params.BodyHtml = *patch.BodyHTML
+		}
+		if patch.BodyText != nil {
+			params.BodyText = *patch.BodyText
+		}
+		if patch.AttendeesOriginal != nil {
+			params.AttendeesOriginal = *patch.AttendeesOriginal
+			params.Attendees = resolveAttendees(ctx, q, existing.UserID, *patch.AttendeesOriginal)
+		}
+		if patch.Type != nil {
+			params.Type = *patch.Type
+		}
+
+		entry, err = q.UpdateEntry(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		if patch.AttendeesOriginal != nil {
+			if err := recordAttendees(ctx, q, entry.UserID, entry.Attendees, existing.Attendees); err != nil {
+				return err
+			}
+		}
+
+		// Re-parse action items when the body or the attendees they're assigned to change
+		if patch.BodyDelta != nil || patch.AttendeesOriginal != nil {
+			if err := syncEntryTasks(ctx, q, entry); err != nil {
+				return err
+			}
+		}
+
+		if patch.BodyDelta != nil {
+			if err := markReferencedAttachments(ctx, q, entry); err != nil {
+				return err
+			}
+		}
+
+		// Re-parse links when the body changes, and resolve [[Title]] links to a new title
+		if patch.BodyDelta != nil || patch.BodyHTML != nil || patch.Title != nil {
+			if err := syncEntryLinks(ctx, q, entry); err != nil {
+				return err
+			}
+		}
+
+		if patch.Tags != nil {
+			return setEntryTags(ctx, q, entry.UserID, entry.ID, *patch.Tags)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// DeleteEntry archives an entry. Archived entries no longer count towards
+// their attendees' use counts, and [[Title]] links to them are moved to
+// another entry with the title or left unresolved.
+func (s *EntryService) DeleteEntry(ctx context.Context, id pgtype.UUID) error {
+	return s.WithTx(ctx, func(q *db.Queries) error {
+		entry, err := q.GetEntryForUpdate(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEntryNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := q.SoftDeleteEntry(ctx, entry.ID); err != nil {
+			return err
+		}
+		if err := q.RebindTitleLinks(ctx, entry.ID); err != nil {
+			return err
+		}
+		return recordAttendees(ctx, q, entry.UserID, nil, entry.Attendees)
+	})
+}
+
+// SyncDerived re-derives an entry's tasks and links from its body, for
+// entries saved before they were derived
+func (s *EntryService) SyncDerived(ctx context.Context, id pgtype.UUID) error {
+	return s.WithTx(ctx, func(q *db.Queries) error {
+		entry, err := q.GetEntryForUpdate(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEntryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := syncEntryTasks(ctx, q, entry); err != nil {
+			return err
+		}
+		return syncEntryLinks(ctx, q, entry)
+	})
+}
+
+// WithTx runs fn with queries bound to a single transaction, committing if
+// fn succeeds and rolling back otherwise. Handlers use it to change entries'
+// derived data together with data of their own.
+func (s *EntryService) WithTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.dbpool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReadSnapshot runs fn with queries bound to a read-only repeatable read
+// transaction, so everything fn reads comes from one snapshot even while
+// entries are edited
+func (s *EntryService) ReadSnapshot(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.dbpool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// badTag can't be stored: Postgres rejects text containing NUL, so tagging
+// with it fails after the entry and its attendees have been written
+const badTag = "bad\x00tag"
+
+// newTestService connects to the migrated database at TEST_DATABASE_URL and
+// creates a user of its own, deleted with all its data when the test ends.
+// Tests are skipped without TEST_DATABASE_URL.
+func newTestService(t *testing.T) (*EntryService, *pgxpool.Pool, pgtype.UUID) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	queries := db.New(pool)
+	user, err := queries.CreateUser(ctx, db.CreateUserParams{
+		Email:    "test-" + uuid.NewString() + "@example.com",
+		Timezone: "UTC",
+	})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), "DELETE FROM users WHERE id = $1", user.ID); err != nil {
+			t.Errorf("delete user: %v", err)
+		}
+	})
+
+	return NewEntryService(pool, queries), pool, user.ID
+}
+
+func testEntryParams(userID pgtype.UUID, title, attendees string) db.CreateEntryParams {
+	return db.CreateEntryParams{
+		UserID:            userID,
+		Title:             title,
+		BodyDelta:         []byte(emptyDelta),
+		BodyHtml:          "<p><br></p>",
+		AttendeesOriginal: attendees,
+		Type:              "notes",
+		DayYear:           2024,
+		DayMonth:          5,
+		DayDay:            14,
+	}
+}
+
+// useCount returns an attendee's use count, or -1 if there is no such attendee
+func useCount(t *testing.T, q *db.Queries, userID pgtype.UUID, name string) int32 {
+	t.Helper()
+	attendee, err := q.GetAttendeeByName(context.Background(), db.GetAttendeeByNameParams{UserID: userID, Name: name})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1
+	}
+	if err != nil {
+		t.Fatalf("get attendee %s: %v", name, err)
+	}
+	return attendee.UseCount
+}
+
+func entryCount(t *testing.T, pool *pgxpool.Pool, userID pgtype.UUID) int {
+	t.Helper()
+	var n int
+	if err := pool.QueryRow(context.Background(), "SELECT count(*) FROM entries WHERE user_id = $1", userID).Scan(&n); err != nil {
+		t.Fatalf("count entries: %v", err)
+	}
+	return n
+}
+
+func TestCreateEntryRollsBackOnFailure(t *testing.T) {
+	s, pool, userID := newTestService(t)
+	ctx := context.Background()
+
+	_, err := s.CreateEntry(ctx, testEntryParams(userID, "Standup", "Alice"), []string{badTag})
+	if err == nil {
+		t.Fatal("expected tagging to fail")
+	}
+
+	if n := entryCount(t, pool, userID); n != 0 {
+		t.Errorf("entries after failed create = %d, want 0", n)
+	}
+	if n := useCount(t, s.queries, userID, "Alice"); n != -1 {
+		t.Errorf("Alice use count after failed create = %d, want no attendee", n)
+	}
+}
+
+func TestEditEntryRollsBackOnFailure(t *testing.T) {
+	s, _, userID := newTestService(t)
+	ctx := context.Background()
+
+	entry, err := s.CreateEntry(ctx, testEntryParams(userID, "Standup", "Alice"), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	title := "Retro"
+	attendees := "Bob"
+	tags := []string{badTag}
+	if _, err := s.UpdateEntry(ctx, entry.ID, EntryPatch{Title: &title, AttendeesOriginal: &attendees, Tags: &tags}); err == nil {
+		t.Fatal("expected tagging to fail")
+	}
+
+	got, err := s.queries.GetEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("get entry: %v", err)
+	}
+	if got.Title != "Standup" || len(got.Attendees) != 1 || got.Attendees[0] != "Alice" {
+		t.Errorf("entry after failed update = %q with %v, want Standup with [Alice]", got.Title, got.Attendees)
+	}
+	if n := useCount(t, s.queries, userID, "Alice"); n != 1 {
+		t.Errorf("Alice use count = %d, want 1", n)
+	}
+	if n := useCount(t, s.queries, userID, "Bob"); n != -1 {
+		t.Errorf("Bob use count = %d, want no attendee", n)
+	}
+}
+
+func TestEditEntrySerializesConcurrentEdits(t *testing.T) {
+	s, _, userID := newTestService(t)
+	ctx := context.Background()
+
+	entry, err := s.CreateEntry(ctx, testEntryParams(userID, "0", ""), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	var secondRan atomic.Bool
+	var secondSaw string
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+
+	// The first edit holds the row lock until released
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := s.EditEntry(ctx, entry.ID, func(existing db.Entry) (EntryPatch, error) {
+			close(locked)
+			<-release
+			title := existing.Title + "1"
+			return EntryPatch{Title: &title}, nil
+		})
+		errs <- err
+	}()
+	<-locked
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := s.EditEntry(ctx, entry.ID, func(existing db.Entry) (EntryPatch, error) {
+			secondRan.Store(true)
+			secondSaw = existing.Title
+			title := existing.Title + "2"
+			return EntryPatch{Title: &title}, nil
+		})
+		errs <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	if secondRan.Load() {
+		t.Error("second edit read the entry while the first held the lock")
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("edit: %v", err)
+		}
+	}
+
+	if secondSaw != "01" {
+		t.Errorf("second edit saw title %q, want the first edit's %q", secondSaw, "01")
+	}
+	got, err := s.queries.GetEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("get entry: %v", err)
+	}
+	if got.Title != "012" {
+		t.Errorf("title = %q, want %q", got.Title, "012")
+	}
+}
+
+func TestDeleteEntryNotFoundWhenArchived(t *testing.T) {
+	s, _, userID := newTestService(t)
+	ctx := context.Background()
+
+	entry, err := s.CreateEntry(ctx, testEntryParams(userID, "Standup", ""), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := s.DeleteEntry(ctx, entry.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := s.DeleteEntry(ctx, entry.ID); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("deleting an archived entry: err = %v, want ErrEntryNotFound", err)
+	}
+	if err := s.DeleteEntry(ctx, pgtype.UUID{Bytes: uuid.New(), Valid: true}); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("deleting a missing entry: err = %v, want ErrEntryNotFound", err)
+	}
+}
+
+func TestAttendeeUseCounts(t *testing.T) {
+	s, _, userID := newTestService(t)
+	ctx := context.Background()
+
+	check := func(step string, want map[string]int32) {
+		t.Helper()
+		for name, n := range want {
+			if got := useCount(t, s.queries, userID, name); got != n {
+				t.Errorf("after %s: %s use count = %d, want %d", step, name, got, n)
+			}
+		}
+	}
+
+	first, err := s.CreateEntry(ctx, testEntryParams(userID, "Standup", "Alice, Bob"), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := s.CreateEntry(ctx, testEntryParams(userID, "1:1", "Alice"), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	check("create", map[string]int32{"Alice": 2, "Bob": 1})
+
+	attendees := "Bob, Carol"
+	if _, err := s.UpdateEntry(ctx, first.ID, EntryPatch{AttendeesOriginal: &attendees}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	check("update", map[string]int32{"Alice": 1, "Bob": 1, "Carol": 1})
+
+	// Edits that don't touch attendees leave the counts alone
+	title := "Standup notes"
+	if _, err := s.UpdateEntry(ctx, first.ID, EntryPatch{Title: &title}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	check("retitle", map[string]int32{"Alice": 1, "Bob": 1, "Carol": 1})
+
+	if err := s.DeleteEntry(ctx, second.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	check("delete", map[string]int32{"Alice": 0, "Bob": 1, "Carol": 1})
+}
+
+func TestTitleLinksFollowRetitleAndArchive(t *testing.T) {
+	s, _, userID := newTestService(t)
+	ctx := context.Background()
+
+	target := func(step string, source pgtype.UUID, want pgtype.UUID) {
+		t.Helper()
+		links, err := s.queries.ListLinksForEntries(ctx, []pgtype.UUID{source})
+		if err != nil {
+			t.Fatalf("list links: %v", err)
+		}
+		if len(links) != 1 {
+			t.Fatalf("after %s: %d links, want 1", step, len(links))
+		}
+		if links[0].TargetEntryID != want {
+			t.Errorf("after %s: link target = %v, want %v", step, links[0].TargetEntryID, want)
+		}
+	}
+
+	older := testEntryParams(userID, "Standup", "")
+	older.DayDay = 13
+	first, err := s.CreateEntry(ctx, older, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := s.CreateEntry(ctx, testEntryParams(userID, "Standup", ""), nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	params := testEntryParams(userID, "Notes", "")
+	params.BodyDelta = []byte(`{"ops":[{"insert":"See [[Standup]]\n"}]}`)
+	source, err := s.CreateEntry(ctx, params, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	target("create", source.ID, second.ID)
+
+	title := "Retro"
+	if _, err := s.UpdateEntry(ctx, second.ID, EntryPatch{Title: &title}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	target("retitle", source.ID, first.ID)
+
+	if err := s.DeleteEntry(ctx, first.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	target("archive", source.ID, pgtype.UUID{})
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// EntryTypeRequest represents a create or partial update of an entry type
+type EntryTypeRequest struct {
+	Name                 *string          `json:"name,omitempty"`
+	Color                *string          `json:"color,omitempty"`
+	Icon                 *string          `json:"icon,omitempty"`
+	DefaultTemplateDelta *json.RawMessage `json:"default_template_delta,omitempty"`
+	HasAttendees         *bool            `json:"has_attendees,omitempty"`
+	Position             *int32           `json:"position,omitempty"`
+}
+
+// EntryTypeResponse represents a user-defined entry type
+type EntryTypeResponse struct {
+	ID                   string          `json:"id"`
+	Name                 string          `json:"name"`
+	Color                string          `json:"color"`
+	Icon                 string          `json:"icon"`
+	DefaultTemplateDelta json.RawMessage `json:"default_template_delta"`
+	HasAttendees         bool            `json:"has_attendees"`
+	Position             int32           `json:"position"`
+	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+// ListEntryTypes lists the current user's entry types in display order
+func (h *Handler) ListEntryTypes(c *gin.Context) {
+	userID := h.getDefaultUserID(c)
+
+	types, err := h.queries.ListEntryTypes(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list entry types"})
+		return
+	}
+
+	response := make([]EntryTypeResponse, len(types))
+	for i, entryType := range types {
+		response[i] = entryTypeToResponse(entryType)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateEntryType creates a new entry type for the current user
+func (h *Handler) CreateEntryType(c *gin.Context) {
+	var req EntryTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	params := db.CreateEntryTypeParams{
+		UserID: h.getDefaultUserID(c),
+		Name:   normalizeEntryTypeName(*req.Name),
+	}
+	if req.Color != nil {
+		params.Color = *req.Color
+	}
+	if req.Icon != nil {
+		params.Icon = strings.TrimSpace(*req.Icon)
+	}
+	if req.DefaultTemplateDelta != nil {
+		params.DefaultTemplateDelta = *req.DefaultTemplateDelta
+	}
+	if req.HasAttendees != nil {
+		params.HasAttendees = *req.HasAttendees
+	}
+	if req.Position != nil {
+		params.Position = *req.Position
+	}
+
+	if err := validateEntryType(params.Name, params.Color, params.DefaultTemplateDelta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entryType, err := h.queries.CreateEntryType(c.Request.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "an entry type with that name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entryTypeToResponse(entryType))
+}
+
+// UpdateEntryType updates an entry type. Renaming cascades to existing entries.
+func (h *Handler) UpdateEntryType(c *gin.Context) {
+	typeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry type ID"})
+		return
+	}
+
+	var req EntryTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetEntryType(c.Request.Context(), db.GetEntryTypeParams{
+		ID:     pgtype.UUID{Bytes: typeID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry type not found"})
+		return
+	}
+
+	params := db.UpdateEntryTypeParams{
+		ID:                   existing.ID,
+		UserID:               userID,
+		Name:                 existing.Name,
+		Color:                existing.Color,
+		Icon:                 existing.Icon,
+		DefaultTemplateDelta: existing.DefaultTemplateDelta,
+		HasAttendees:         existing.HasAttendees,
+		Position:             existing.Position,
+	}
+	if req.Name != nil {
+		params.Name = normalizeEntryTypeName(*req.Name)
+	}
+	if req.Color != nil {
+		params.Color = *req.Color
+	}
+	if req.Icon != nil {
+		params.Icon = strings.TrimSpace(*req.Icon)
+	}
+	if req.DefaultTemplateDelta != nil {
+		params.DefaultTemplateDelta = *req.DefaultTemplateDelta
+	}
+	if req.HasAttendees != nil {
+		params.HasAttendees = *req.HasAttendees
+	}
+	if req.Position != nil {
+		params.Position = *req.Position
+	}
+
+	if err := validateEntryType(params.Name, params.Color, params.DefaultTemplateDelta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entryType, err := h.queries.UpdateEntryType(c.Request.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "an entry type with that name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entryTypeToResponse(entryType))
+}
+
+// DeleteEntryType deletes an entry type that no entries use
+func (h *Handler) DeleteEntryType(c *gin.Context) {
+	typeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry type ID"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetEntryType(c.Request.Context(), db.GetEntryTypeParams{
+		ID:     pgtype.UUID{Bytes: typeID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry type not found"})
+		return
+	}
+
+	count, err := h.queries.CountEntriesWithType(c.Request.Context(), db.CountEntriesWithTypeParams{
+		UserID: userID,
+		Type:   existing.Name,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("entry type is used by %d entries", count)})
+		return
+	}
+
+	err = h.queries.DeleteEntryType(c.Request.Context(), db.DeleteEntryTypeParams{
+		ID:     existing.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "entry type is still referenced by a template"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// lookupEntryType returns the user's entry type with the given name
+func (h *Handler) lookupEntryType(ctx context.Context, userID pgtype.UUID, name string) (db.EntryType, error) {
+	entryType, err := h.queries.GetEntryTypeByName(ctx, db.GetEntryTypeByNameParams{
+		UserID: userID,
+		Name:   normalizeEntryTypeName(name),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entryType, fmt.Errorf("unknown entry type %q", name)
+	}
+	return entryType, err
+}
+
+func normalizeEntryTypeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func validateEntryType(name, color string, templateDelta []byte) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > 50 {
+		return fmt.Errorf("name must be at most 50 characters")
+	}
+	if color != "" && !hexColorPattern.MatchString(color) {
+		return fmt.Errorf("color must be a hex color like #3b82f6")
+	}
+	if len(templateDelta) > 0 && !json.Valid(templateDelta) {
+		return fmt.Errorf("default_template_delta must be valid JSON")
+	}
+	return nil
+}
+
+func entryTypeToResponse(entryType db.EntryType) EntryTypeResponse {
+	return EntryTypeResponse{
+		ID:                   entryType.ID.String(),
+		Name:                 entryType.Name,
+		Color:                entryType.Color,
+		Icon:                 entryType.Icon,
+		DefaultTemplateDelta: entryType.DefaultTemplateDelta,
+		HasAttendees:         entryType.HasAttendees,
+		Position:             entryType.Position,
+		CreatedAt:            entryType.CreatedAt.Time,
+		UpdatedAt:            entryType.UpdatedAt.Time,
+	}
+}
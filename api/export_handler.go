@@ -4,106 +4,835 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/markdown"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// ExportEntries exports all entries as a zip file
-func (h *Handler) ExportEntries(c *gin.Context) {
-	// Fetch all entries
-	entries, err := h.queries.ListAllEntries(context.Background())
+// attachmentURLPattern matches src and href attributes holding an
+// attachment's URL or its thumbnail's
+var attachmentURLPattern = regexp.MustCompile(`(src|href)="/api/attachments/([0-9a-fA-F-]{36})(?:/thumbnail)?(?:\?[^"]*)?"`)
+
+// attachmentPathPattern matches an attachment's URL, or its thumbnail's, on
+// its own, as links and images in a delta hold them
+var attachmentPathPattern = regexp.MustCompile(`^/api/attachments/([0-9a-fA-F-]{36})(?:/thumbnail)?(?:\?.*)?$`)
+
+// exportPageSize is how many entries the export reads from the database at
+// a time
+const exportPageSize = 100
+
+// exportSchemaVersion is recorded in metadata.json and bumped whenever files
+// or fields in the export change in a way readers need to know about
+const exportSchemaVersion = 2
+
+// exportSchema documents the layout of the export in its metadata.json
+var exportSchema = map[string]interface{}{
+	"entry_files":      "<YYYY-MM-DD>_<first 8 characters of id>_<title>.json at the top level, one per entry",
+	"attachment_files": "attachments/<entry id>/<filename>, numbered if an entry has two files of the same name",
+	"manifest":         "manifest.sha256 lists the SHA-256 of every other file, in sha256sum format",
+	"entry_fields": map[string]string{
+		"id":                 "entry UUID",
+		"title":              "title",
+		"body_html":          "rendered body; links to other entries and attachments point at their files in the export",
+		"body_delta":         "Quill Delta the body is edited as",
+		"body_text":          "plain text of the body",
+		"type":               "entry type key",
+		"date":               "the day the entry belongs to: year, month, day",
+		"attendees":          "people, as parsed",
+		"attendees_original": "people, as typed",
+		"archived":           "whether the entry is archived",
+		"tags":               "tag names",
+		"links":              "outgoing links: title, and entry_id and file when the target exists",
+		"attachments":        "attached files (see attachment_fields)",
+		"created_at":         "RFC 3339, in the export timezone",
+		"updated_at":         "RFC 3339, in the export timezone",
+	},
+	"attachment_fields": map[string]string{
+		"id":         "attachment UUID",
+		"filename":   "name as uploaded",
+		"mime_type":  "content type",
+		"size_bytes": "size of the contents",
+		"sha256":     "hex SHA-256 of the contents",
+		"path":       "file in the export; absent if the contents could not be read",
+		"created_at": "RFC 3339, in the export timezone",
+	},
+}
+
+// markdownSchema documents the layout of a Markdown export, which has the
+// same attachment files and manifest as the JSON one
+var markdownSchema = map[string]interface{}{
+	"entry_files":      "<YYYY>/<MM>/<YYYY-MM-DD>_<first 8 characters of id>_<title>.md, one per entry",
+	"attachment_files": exportSchema["attachment_files"],
+	"manifest":         exportSchema["manifest"],
+	"front_matter": map[string]string{
+		"id":        "entry UUID",
+		"title":     "title",
+		"date":      "the day the entry belongs to, YYYY-MM-DD",
+		"type":      "entry type key",
+		"attendees": "people, as parsed",
+		"tags":      "tag names",
+		"archived":  "whether the entry is archived",
+		"created":   "RFC 3339, in the export timezone",
+		"updated":   "RFC 3339, in the export timezone",
+	},
+	"body": "the title as a heading, then the body as Markdown, then links to the entry's attachments; links to other entries and attachments are relative to the entry's file",
+}
+
+// htmlSchema documents the layout of an HTML export, which has the same
+// attachment files and manifest as the JSON one
+var htmlSchema = map[string]interface{}{
+	"index":            "index.html lists the entries newest first, linking to their pages",
+	"entry_files":      "<YYYY>/<MM>/<YYYY-MM-DD>_<first 8 characters of id>_<title>.html, one page per entry",
+	"attachment_files": exportSchema["attachment_files"],
+	"manifest":         exportSchema["manifest"],
+	"body":             "the title, date, type, attendees and tags, then the body, then links to the entry's attachments; links to other entries and attachments are relative to the page",
+}
+
+// ExportEntries streams the current user's entries and their attachments as
+// a zip file, with an entry per JSON file or, with ?format=markdown or html,
+// per Markdown or HTML file. ?format=print instead returns one HTML page to
+// print from the browser. ?from= and ?to= (YYYY-MM-DD, inclusive), ?type=,
+// ?attendee=, ?tag= and ?archived= narrow the export (see
+// parseExportFilter). Entries are read a page at a time from one snapshot
+// and written straight to the response, so memory use doesn't grow with the
+// journal. Once the download has started an error can only cut it short,
+// leaving an unreadable zip.
+func (h *Handler) ExportEntries(c *gin.Context, cfg config.AttachmentsConfig) {
+	ctx := c.Request.Context()
+	userID := h.getDefaultUserID(c)
+
+	format := c.DefaultQuery("format", "json")
+	switch format {
+	case "json", "markdown", "html", "print":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, markdown, html or print"})
+		return
+	}
+	filter, err := parseExportFilter(c, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Export timestamps are written in the user's local timezone
+	loc := h.userLocation(ctx, userID)
+
+	// Reading from one snapshot keeps the pages consistent with each other
+	// if entries are edited during the download
+	err = h.entries.ReadSnapshot(ctx, func(queries *db.Queries) error {
+		h.writeExport(c, queries, userID, format, filter, loc, cfg)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error starting export: %v", err)
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch entries"})
+		}
+	}
+}
+
+// writeExport writes the export response from queries bound to a snapshot.
+// Errors are reported to the client while it can still get one, and only
+// logged once the download has started.
+func (h *Handler) writeExport(c *gin.Context, queries *db.Queries, userID pgtype.UUID, format string, filter exportFilter, loc *time.Location, cfg config.AttachmentsConfig) {
+	ctx := c.Request.Context()
+
+	params := db.ListEntriesForExportParams{
+		UserID:          userID,
+		FromDate:        filter.from,
+		ToDate:          filter.to,
+		Types:           filter.types,
+		AttendeeNames:   filter.attendeeNames(),
+		Tags:            filter.tags,
+		IncludeArchived: filter.includeArchived,
+		PageSize:        exportPageSize,
+	}
+	entries, err := queries.ListEntriesForExport(ctx, params)
 	if err != nil {
 		log.Printf("Error fetching entries for export: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch entries"})
 		return
 	}
 
-	// Create a buffer to write the zip file
-	buf := new(bytes.Buffer)
-	zipWriter := zip.NewWriter(buf)
+	e := &exporter{
+		queries: queries,
+		userID:  userID,
+		storage: cfg.Storage,
+		loc:     loc,
+		format:  format,
+		filter:  filter,
+		out:     c.Writer,
+	}
+	exportedAt := time.Now().In(loc)
 
-	// Add each entry as a separate file
-	for _, entry := range entries {
-		// Create filename based on date and title
-		date := fmt.Sprintf("%04d-%02d-%02d", entry.DayYear, entry.DayMonth, entry.DayDay)
-		safeTitle := sanitizeFilename(entry.Title)
-		filename := fmt.Sprintf("%s_%s_%s.json", date, entry.ID.String()[:8], safeTitle)
+	if format == "print" {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=journal_%s.html", exportedAt.Format("2006-01-02")))
+		c.Status(http.StatusOK)
+		if err := e.writePrint(ctx, entries, params, exportedAt); err != nil {
+			log.Printf("Error writing printable export: %v", err)
+		}
+		return
+	}
 
-		// Create the file in the zip
-		writer, err := zipWriter.Create(filename)
+	// Checksums are spooled to disk until the manifest is written last, and
+	// the index of an HTML export until it is written
+	manifest, err := os.CreateTemp("", "journal-manifest-*")
+	if err != nil {
+		log.Printf("Error creating export manifest: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
+		return
+	}
+	defer os.Remove(manifest.Name())
+	defer manifest.Close()
+	e.manifest = manifest
+	if format == "html" {
+		index, err := os.CreateTemp("", "journal-index-*")
 		if err != nil {
-			log.Printf("Error creating zip entry: %v", err)
-			continue
+			log.Printf("Error creating export index: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
+			return
+		}
+		defer os.Remove(index.Name())
+		defer index.Close()
+		e.index = index
+	}
+
+	// Set headers for file download; the length isn't known up front
+	filename := fmt.Sprintf("journal_export_%s.zip", exportedAt.Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	e.zip = zip.NewWriter(c.Writer)
+	for len(entries) > 0 {
+		if err := e.writePage(ctx, entries); err != nil {
+			log.Printf("Error writing export: %v", err)
+			return
 		}
 
-		// Prepare entry data for export
-		exportData := map[string]interface{}{
-			"id":         entry.ID.String(),
-			"title":      entry.Title,
-			"body_html":  entry.BodyHtml,
-			"body_delta": entry.BodyDelta,
-			"type":       entry.Type,
-			"date": map[string]int32{
-				"year":  entry.DayYear,
-				"month": entry.DayMonth,
-				"day":   entry.DayDay,
-			},
-			"attendees":  entry.Attendees,
-			"created_at": entry.CreatedAt.Time.Format(time.RFC3339),
-			"updated_at": entry.UpdatedAt.Time.Format(time.RFC3339),
-		}
-
-		// Marshal to JSON with pretty print
-		jsonData, err := json.MarshalIndent(exportData, "", "  ")
+		entries, err = e.nextPage(ctx, &params, entries)
 		if err != nil {
-			log.Printf("Error marshaling entry: %v", err)
+			log.Printf("Error fetching entries for export: %v", err)
+			return
+		}
+	}
+
+	if format == "html" {
+		if err := e.writeIndex(exportedAt); err != nil {
+			log.Printf("Error writing export index: %v", err)
+			return
+		}
+	}
+
+	schema := exportSchema
+	switch format {
+	case "markdown":
+		schema = markdownSchema
+	case "html":
+		schema = htmlSchema
+	}
+	metadata := map[string]interface{}{
+		"export_date":      exportedAt.Format(time.RFC3339),
+		"timezone":         loc.String(),
+		"entry_count":      e.entries,
+		"attachment_count": e.attachments,
+		"export_format":    format,
+		"version":          fmt.Sprintf("%d.0", exportSchemaVersion),
+		"schema_version":   exportSchemaVersion,
+		"schema":           schema,
+	}
+	if filter.active() {
+		metadata["filters"] = filter.metadata()
+	}
+	if err := e.writeJSON("metadata.json", metadata); err != nil {
+		log.Printf("Error writing export metadata: %v", err)
+		return
+	}
+	if err := e.writeManifest(); err != nil {
+		log.Printf("Error writing export manifest: %v", err)
+		return
+	}
+
+	if err := e.zip.Close(); err != nil {
+		log.Printf("Error closing zip writer: %v", err)
+	}
+}
+
+// exportFilter narrows an export to the entries of a date range with any of
+// the given types and attendees and all of the given tags, leaving archived
+// entries out unless includeArchived is set
+type exportFilter struct {
+	from, to        pgtype.Date
+	types           []string
+	attendees       []string
+	tags            []string
+	includeArchived bool
+}
+
+// parseExportFilter reads ?from=, ?to=, ?archived= and repeated ?type=,
+// ?attendee= and ?tag= parameters. Archived entries are included by
+// ?archived=include, and by default only in an unfiltered JSON export, which
+// is a complete backup; other exports are for reading.
+func parseExportFilter(c *gin.Context, format string) (exportFilter, error) {
+	filter := exportFilter{
+		types:     normalizeNames(c.QueryArray("type")),
+		attendees: normalizeNames(c.QueryArray("attendee")),
+		tags:      normalizeTags(c.QueryArray("tag")),
+	}
+	for i, name := range filter.types {
+		filter.types[i] = normalizeEntryTypeName(name)
+	}
+
+	var err error
+	if filter.from, err = parseDate(c.Query("from")); err != nil {
+		return filter, errors.New("from must be YYYY-MM-DD")
+	}
+	if filter.to, err = parseDate(c.Query("to")); err != nil {
+		return filter, errors.New("to must be YYYY-MM-DD")
+	}
+	if filter.from.Valid && filter.to.Valid && filter.to.Time.Before(filter.from.Time) {
+		return filter, errors.New("to must not be before from")
+	}
+
+	switch c.Query("archived") {
+	case "":
+		filter.includeArchived = format == "json" && !filter.narrows()
+	case "include":
+		filter.includeArchived = true
+	case "exclude":
+	default:
+		return filter, errors.New("archived must be include or exclude")
+	}
+	return filter, nil
+}
+
+// normalizeNames trims names and drops empty and repeated ones, ignoring
+// case. The result is never nil, as the export query reads a null list as
+// matching nothing.
+func normalizeNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || seen[strings.ToLower(name)] {
 			continue
 		}
+		seen[strings.ToLower(name)] = true
+		normalized = append(normalized, name)
+	}
+	return normalized
+}
+
+// active reports whether the filter leaves any entries out
+func (f exportFilter) active() bool {
+	return !f.includeArchived || f.narrows()
+}
+
+// narrows reports whether any dates, types, attendees or tags were given
+func (f exportFilter) narrows() bool {
+	return f.from.Valid || f.to.Valid || len(f.types) > 0 || len(f.attendees) > 0 || len(f.tags) > 0
+}
+
+// attendeeNames lowercases the attendees, as the export query compares them
+func (f exportFilter) attendeeNames() []string {
+	names := make([]string, len(f.attendees))
+	for i, name := range f.attendees {
+		names[i] = strings.ToLower(name)
+	}
+	return names
+}
+
+// metadata describes the filter for an export's metadata.json
+func (f exportFilter) metadata() map[string]interface{} {
+	filters := map[string]interface{}{}
+	if f.from.Valid {
+		filters["from"] = f.from.Time.Format("2006-01-02")
+	}
+	if f.to.Valid {
+		filters["to"] = f.to.Time.Format("2006-01-02")
+	}
+	if len(f.types) > 0 {
+		filters["types"] = f.types
+	}
+	if len(f.attendees) > 0 {
+		filters["attendees"] = f.attendees
+	}
+	if len(f.tags) > 0 {
+		filters["tags"] = f.tags
+	}
+	filters["include_archived"] = f.includeArchived
+	return filters
+}
+
+// exporter writes entries and their attachments into an export zip,
+// noting each file's checksum for the manifest, or for a printable export
+// writes entries straight to out
+type exporter struct {
+	queries     *db.Queries
+	userID      pgtype.UUID
+	zip         *zip.Writer
+	out         io.Writer
+	storage     config.StorageConfig
+	loc         *time.Location
+	manifest    *os.File
+	index       *os.File
+	format      string
+	filter      exportFilter
+	entries     int
+	attachments int
+}
+
+// nextPage fetches the page of entries after the last of the given ones
+func (e *exporter) nextPage(ctx context.Context, params *db.ListEntriesForExportParams, entries []db.Entry) ([]db.Entry, error) {
+	last := entries[len(entries)-1]
+	params.AfterID = last.ID
+	params.AfterYear = last.DayYear
+	params.AfterMonth = last.DayMonth
+	params.AfterDay = last.DayDay
+	params.AfterCreatedAt = last.CreatedAt
+	return e.queries.ListEntriesForExport(ctx, *params)
+}
+
+// exportPage is a page of entries' tags, attachments and outgoing links, and
+// the files of the linked entries the export includes
+type exportPage struct {
+	tags        map[pgtype.UUID][]string
+	attachments map[pgtype.UUID][]db.Attachment
+	links       map[pgtype.UUID][]db.ListLinksForEntriesRow
+	files       map[pgtype.UUID]string
+}
+
+// loadPage fetches the tags, attachments and links of a page of entries
+func (e *exporter) loadPage(ctx context.Context, entries []db.Entry) (*exportPage, error) {
+	entryIDs := make([]pgtype.UUID, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.ID
+	}
+	page := &exportPage{
+		tags:        make(map[pgtype.UUID][]string, len(entries)),
+		attachments: make(map[pgtype.UUID][]db.Attachment, len(entries)),
+		links:       make(map[pgtype.UUID][]db.ListLinksForEntriesRow),
+		files:       make(map[pgtype.UUID]string),
+	}
+
+	tags, err := e.queries.ListTagsForEntries(ctx, entryIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	for _, tag := range tags {
+		page.tags[tag.EntryID] = append(page.tags[tag.EntryID], tag.Name)
+	}
+
+	attachments, err := e.queries.ListAttachmentsForEntries(ctx, entryIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attachments: %w", err)
+	}
+	for _, attachment := range attachments {
+		page.attachments[attachment.EntryID] = append(page.attachments[attachment.EntryID], attachment)
+	}
+
+	// Entry links are rewritten to point at the linked entry's file in the zip
+	links, err := e.queries.ListLinksForEntries(ctx, entryIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch links: %w", err)
+	}
+	for _, link := range links {
+		page.links[link.SourceEntryID] = append(page.links[link.SourceEntryID], link)
+		if link.TargetEntryTitle.Valid {
+			page.files[link.TargetEntryID] = e.entryFile(db.Entry{
+				ID:       link.TargetEntryID,
+				Title:    link.TargetEntryTitle.String,
+				DayYear:  link.TargetDayYear.Int32,
+				DayMonth: link.TargetDayMonth.Int32,
+				DayDay:   link.TargetDayDay.Int32,
+			})
+		}
+	}
+
+	// Links to entries the filters leave out keep their in-app URLs
+	if e.filter.active() && len(page.files) > 0 {
+		targets := make([]pgtype.UUID, 0, len(page.files))
+		for id := range page.files {
+			targets = append(targets, id)
+		}
+		included, err := e.queries.FilterEntriesForExport(ctx, db.FilterEntriesForExportParams{
+			UserID:          e.userID,
+			EntryIds:        targets,
+			FromDate:        e.filter.from,
+			ToDate:          e.filter.to,
+			Types:           e.filter.types,
+			AttendeeNames:   e.filter.attendeeNames(),
+			Tags:            e.filter.tags,
+			IncludeArchived: e.filter.includeArchived,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter links: %w", err)
+		}
+		files := make(map[pgtype.UUID]string, len(included))
+		for _, id := range included {
+			files[id] = page.files[id]
+		}
+		page.files = files
+	}
+	return page, nil
+}
+
+// writePage adds a page of entries to the export with their tags, links and
+// attachments
+func (e *exporter) writePage(ctx context.Context, entries []db.Entry) error {
+	page, err := e.loadPage(ctx, entries)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// Attachments go first so the entry can refer to their files
+		exported, paths, err := e.writeAttachments(ctx, entry, page.attachments[entry.ID])
+		if err != nil {
+			return err
+		}
 
-		// Write JSON to zip file
-		_, err = writer.Write(jsonData)
+		switch e.format {
+		case "markdown":
+			err = e.writeMarkdown(entry, page.tags[entry.ID], page.attachments[entry.ID], paths, page.links[entry.ID], page.files)
+		case "html":
+			err = e.writeHTML(entry, page.tags[entry.ID], page.attachments[entry.ID], paths, page.links[entry.ID], page.files)
+		default:
+			err = e.writeJSONEntry(entry, page.tags[entry.ID], exported, paths, page.links[entry.ID], page.files)
+		}
 		if err != nil {
-			log.Printf("Error writing to zip: %v", err)
+			return err
+		}
+		e.entries++
+	}
+	return nil
+}
+
+// writeJSONEntry adds an entry's JSON file to the export
+func (e *exporter) writeJSONEntry(entry db.Entry, tags []string, attachments []map[string]interface{}, paths map[string]string, links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) error {
+	bodyHTML := rewriteExportLinks(entry.BodyHtml, links, files)
+	exportData := map[string]interface{}{
+		"id":         entry.ID.String(),
+		"title":      entry.Title,
+		"body_html":  rewriteAttachmentLinks(bodyHTML, paths),
+		"body_delta": entry.BodyDelta,
+		"body_text":  entry.BodyText,
+		"type":       entry.Type,
+		"date": map[string]int32{
+			"year":  entry.DayYear,
+			"month": entry.DayMonth,
+			"day":   entry.DayDay,
+		},
+		"attendees":          entry.Attendees,
+		"attendees_original": entry.AttendeesOriginal,
+		"archived":           entry.Archived,
+		"tags":               tags,
+		"links":              exportLinks(links, files),
+		"attachments":        attachments,
+		"created_at":         entry.CreatedAt.Time.In(e.loc).Format(time.RFC3339),
+		"updated_at":         entry.UpdatedAt.Time.In(e.loc).Format(time.RFC3339),
+	}
+	return e.writeJSON(e.entryFile(entry), exportData)
+}
+
+// writeMarkdown adds an entry's Markdown file to the export. Links to other
+// entries and to attachments become links relative to the file.
+func (e *exporter) writeMarkdown(entry db.Entry, tags []string, attachments []db.Attachment, paths map[string]string, links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) error {
+	// Entry files are two folders down, in YYYY/MM
+	relative := func(file string) string {
+		return "../../" + file
+	}
+
+	body := markdown.FromDelta(entry.BodyDelta, func(url string) string {
+		if id, ok := entryIDFromHref(url); ok {
+			if file, ok := files[pgtype.UUID{Bytes: id, Valid: true}]; ok {
+				return relative(file)
+			}
+		}
+		if m := attachmentPathPattern.FindStringSubmatch(url); m != nil {
+			if zipPath, ok := paths[strings.ToLower(m[1])]; ok {
+				return relative(zipPath)
+			}
+		}
+		return url
+	})
+
+	byTitle := linkFilesByTitle(links, files)
+	body = wikiLinkPattern.ReplaceAllStringFunc(body, func(match string) string {
+		m := wikiLinkPattern.FindStringSubmatch(match)
+		file, ok := byTitle[strings.ToLower(strings.TrimSpace(markdown.Unescape(m[1])))]
+		if !ok {
+			return match
+		}
+		label := strings.TrimSpace(m[1])
+		if m[2] != "" {
+			label = strings.TrimSpace(m[2])
+		}
+		return fmt.Sprintf("[%s](%s)", label, markdown.EscapeURL(relative(file)))
+	})
+
+	if tags == nil {
+		tags = []string{}
+	}
+	attendees := entry.Attendees
+	if attendees == nil {
+		attendees = []string{}
+	}
+
+	var b strings.Builder
+	b.WriteString(markdown.FrontMatter(
+		markdown.Field{Name: "id", Value: entry.ID.String()},
+		markdown.Field{Name: "title", Value: entry.Title},
+		markdown.Field{Name: "date", Value: fmt.Sprintf("%04d-%02d-%02d", entry.DayYear, entry.DayMonth, entry.DayDay)},
+		markdown.Field{Name: "type", Value: entry.Type},
+		markdown.Field{Name: "attendees", Value: attendees},
+		markdown.Field{Name: "tags", Value: tags},
+		markdown.Field{Name: "archived", Value: entry.Archived},
+		markdown.Field{Name: "created", Value: entry.CreatedAt.Time.In(e.loc).Format(time.RFC3339)},
+		markdown.Field{Name: "updated", Value: entry.UpdatedAt.Time.In(e.loc).Format(time.RFC3339)},
+	))
+	if entry.Title != "" {
+		b.WriteString("\n# " + markdown.Escape(entry.Title) + "\n")
+	}
+	if strings.TrimSpace(body) != "" {
+		b.WriteString("\n" + body)
+	}
+
+	// Attachments are listed whether or not the body shows them
+	var listed []string
+	for _, attachment := range attachments {
+		if zipPath, ok := paths[attachment.ID.String()]; ok {
+			listed = append(listed, fmt.Sprintf("- [%s](%s)\n", markdown.Escape(attachment.Filename), markdown.EscapeURL(relative(zipPath))))
+		}
+	}
+	if len(listed) > 0 {
+		b.WriteString("\n## Attachments\n\n" + strings.Join(listed, ""))
+	}
+
+	return e.writeFile(e.entryFile(entry), strings.NewReader(b.String()))
+}
+
+// entryFile names an entry's file in the export's format. In a printable
+// export, where every entry is on the one page, it is the entry's anchor.
+func (e *exporter) entryFile(entry db.Entry) string {
+	switch e.format {
+	case "markdown":
+		return markdownFilename(entry)
+	case "html":
+		return htmlFilename(entry)
+	case "print":
+		return "#" + entryAnchor(entry.ID)
+	}
+	return exportFilename(entry)
+}
+
+// writeAttachments adds an entry's attachments under attachments/<entry id>/,
+// returning their descriptions for the entry's JSON and their paths by ID.
+// Contents that can't be read are logged and left out rather than failing
+// the whole export.
+func (e *exporter) writeAttachments(ctx context.Context, entry db.Entry, attachments []db.Attachment) ([]map[string]interface{}, map[string]string, error) {
+	exported := make([]map[string]interface{}, 0, len(attachments))
+	paths := make(map[string]string, len(attachments))
+	used := make(map[string]bool, len(attachments))
+
+	for _, attachment := range attachments {
+		item := map[string]interface{}{
+			"id":         attachment.ID.String(),
+			"filename":   attachment.Filename,
+			"mime_type":  attachment.MimeType,
+			"size_bytes": attachment.SizeBytes,
+			"sha256":     attachment.StorageKey,
+			"created_at": attachment.CreatedAt.Time.In(e.loc).Format(time.RFC3339),
+		}
+		exported = append(exported, item)
+
+		content, err := openContent(ctx, e.queries, attachment.StorageBackend, attachment.StorageKey, e.storage)
+		if err != nil {
+			log.Printf("Error reading attachment %s for export: %v", attachment.ID.String(), err)
 			continue
 		}
+		zipPath := "attachments/" + entry.ID.String() + "/" + exportAttachmentName(attachment.Filename, used)
+		err = e.writeFile(zipPath, content)
+		content.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		item["path"] = zipPath
+		paths[attachment.ID.String()] = zipPath
+		e.attachments++
 	}
+	return exported, paths, nil
+}
 
-	// Create a metadata file
-	metadata := map[string]interface{}{
-		"export_date":   time.Now().Format(time.RFC3339),
-		"entry_count":   len(entries),
-		"export_format": "json",
-		"version":       "1.0",
+// writeJSON adds a pretty-printed JSON file to the export
+func (e *exporter) writeJSON(name string, data interface{}) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
 	}
+	return e.writeFile(name, bytes.NewReader(jsonData))
+}
 
-	metaWriter, err := zipWriter.Create("metadata.json")
-	if err == nil {
-		metaData, _ := json.MarshalIndent(metadata, "", "  ")
-		metaWriter.Write(metaData)
+// writeFile adds a file to the export and its checksum to the manifest
+func (e *exporter) writeFile(name string, r io.Reader) error {
+	writer, err := e.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(writer, hash), r); err != nil {
+		return err
 	}
+	_, err = fmt.Fprintf(e.manifest, "%x  %s\n", hash.Sum(nil), name)
+	return err
+}
 
-	// Close the zip writer
-	err = zipWriter.Close()
+// writeManifest adds the spooled checksums as the export's last file
+func (e *exporter) writeManifest() error {
+	if _, err := e.manifest.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	writer, err := e.zip.Create("manifest.sha256")
 	if err != nil {
-		log.Printf("Error closing zip writer: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
-		return
+		return err
 	}
+	_, err = io.Copy(writer, e.manifest)
+	return err
+}
 
-	// Set headers for file download
-	filename := fmt.Sprintf("journal_export_%s.zip", time.Now().Format("2006-01-02"))
-	c.Header("Content-Type", "application/zip")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Header("Content-Length", fmt.Sprintf("%d", buf.Len()))
+// exportAttachmentName makes an attachment's filename safe to use as a path
+// in the export, numbering it if the entry already has a file of that name
+func exportAttachmentName(filename string, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Trim(name, ".") == "" {
+		name = "attachment"
+	}
+
+	unique := name
+	ext := path.Ext(name)
+	for n := 2; used[strings.ToLower(unique)]; n++ {
+		unique = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
 
-	// Send the zip file
-	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+// rewriteAttachmentLinks points attachment URLs in body HTML, including
+// thumbnail URLs, at the attachments' files in the export
+func rewriteAttachmentLinks(bodyHTML string, paths map[string]string) string {
+	if len(paths) == 0 {
+		return bodyHTML
+	}
+	return attachmentURLPattern.ReplaceAllStringFunc(bodyHTML, func(match string) string {
+		m := attachmentURLPattern.FindStringSubmatch(match)
+		zipPath, ok := paths[strings.ToLower(m[2])]
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf(`%s="%s"`, m[1], html.EscapeString(zipPath))
+	})
+}
+
+// exportFilename names an entry's file by date, short ID and title
+func exportFilename(entry db.Entry) string {
+	date := fmt.Sprintf("%04d-%02d-%02d", entry.DayYear, entry.DayMonth, entry.DayDay)
+	return fmt.Sprintf("%s_%s_%s.json", date, entry.ID.String()[:8], sanitizeFilename(entry.Title))
+}
+
+// markdownFilename files an entry's Markdown under its year and month
+func markdownFilename(entry db.Entry) string {
+	name := strings.TrimSuffix(exportFilename(entry), ".json") + ".md"
+	return fmt.Sprintf("%04d/%02d/%s", entry.DayYear, entry.DayMonth, name)
+}
+
+// htmlFilename files an entry's HTML page under its year and month
+func htmlFilename(entry db.Entry) string {
+	name := strings.TrimSuffix(exportFilename(entry), ".json") + ".html"
+	return fmt.Sprintf("%04d/%02d/%s", entry.DayYear, entry.DayMonth, name)
+}
+
+// exportLinks lists an entry's outgoing links with the linked entry's file
+func exportLinks(links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) []map[string]string {
+	exported := make([]map[string]string, 0, len(links))
+	for _, link := range links {
+		item := map[string]string{"title": link.TargetTitle}
+		if link.TargetEntryID.Valid {
+			item["entry_id"] = link.TargetEntryID.String()
+			item["file"] = files[link.TargetEntryID]
+		}
+		exported = append(exported, item)
+	}
+	return exported
+}
+
+// rewriteExportLinks turns [[Title]] links and in-app entry URLs in body HTML
+// into relative links to the linked entries' files in the export
+func rewriteExportLinks(bodyHTML string, links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) string {
+	if len(links) == 0 {
+		return bodyHTML
+	}
+
+	byTitle := linkFilesByTitle(links, files)
+	bodyHTML = wikiLinkPattern.ReplaceAllStringFunc(bodyHTML, func(match string) string {
+		m := wikiLinkPattern.FindStringSubmatch(match)
+		file, ok := byTitle[strings.ToLower(strings.TrimSpace(m[1]))]
+		if !ok {
+			return match
+		}
+		label := strings.TrimSpace(m[1])
+		if m[2] != "" {
+			label = strings.TrimSpace(m[2])
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(file), label)
+	})
+
+	return hrefPattern.ReplaceAllStringFunc(bodyHTML, func(match string) string {
+		id, ok := entryIDFromHref(hrefPattern.FindStringSubmatch(match)[1])
+		if !ok {
+			return match
+		}
+		file, ok := files[pgtype.UUID{Bytes: id, Valid: true}]
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf(`href="%s"`, html.EscapeString(file))
+	})
+}
+
+// linkFilesByTitle maps the lowercased titles of an entry's links, as its
+// [[Title]] links name them, to the linked entries' files
+func linkFilesByTitle(links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) map[string]string {
+	byTitle := make(map[string]string)
+	for _, link := range links {
+		if file, ok := files[link.TargetEntryID]; ok && link.TargetTitle != "" {
+			byTitle[strings.ToLower(link.TargetTitle)] = file
+		}
+	}
+	return byTitle
 }
 
 // sanitizeFilename removes or replaces characters that are problematic in filenames
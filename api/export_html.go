@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportStyles lays out HTML and printable exports for reading on screen and
+// on paper. Lists come from the editor as <ol> items marked with data-list.
+const exportStyles = `
+body { font: 15px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: #222; max-width: 800px; margin: 2em auto; padding: 0 1em; }
+header.export { border-bottom: 2px solid #222; margin-bottom: 2em; }
+.details, .exported, .date { color: #666; font-size: 0.9em; }
+article { margin-bottom: 2.5em; }
+article + article { border-top: 1px solid #ddd; padding-top: 1.5em; }
+img { max-width: 100%; height: auto; }
+pre { background: #f5f5f5; padding: 0.75em; white-space: pre-wrap; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #555; }
+table { border-collapse: collapse; margin: 1em 0; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.6em; vertical-align: top; }
+td p { margin: 0; }
+li[data-list="bullet"] { list-style-type: disc; }
+li[data-list="checked"], li[data-list="unchecked"] { list-style: none; }
+li[data-list="checked"]::before { content: "☑ "; }
+li[data-list="unchecked"]::before { content: "☐ "; }
+ul.entries { list-style: none; padding: 0; }
+@media print {
+  body { margin: 0; max-width: none; font-size: 11pt; }
+  a { color: inherit; }
+  nav { display: none; }
+  h2, .details { break-after: avoid; }
+  img, pre, table { break-inside: avoid; }
+}
+`
+
+// htmlDocumentEnd closes the document htmlDocumentStart opens
+const htmlDocumentEnd = "</body>\n</html>\n"
+
+// htmlDocumentStart opens an HTML document with the export styles
+func htmlDocumentStart(title string) string {
+	return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n",
+		html.EscapeString(title), exportStyles)
+}
+
+// exportHeader heads a printable export or an HTML export's index with what
+// the filters chose and when it was exported
+func exportHeader(filter exportFilter, exportedAt time.Time) string {
+	return fmt.Sprintf("<header class=\"export\">\n<h1>Journal</h1>\n<p>%s</p>\n<p class=\"exported\">Exported %s</p>\n</header>\n",
+		html.EscapeString(filter.describe()), exportedAt.Format("2 January 2006 15:04 MST"))
+}
+
+// describe summarises the filter for people, such as "1 January 2024 –
+// 31 March 2024 · Types: meeting · With: Alice"
+func (f exportFilter) describe() string {
+	var parts []string
+	const layout = "2 January 2006"
+	switch {
+	case f.from.Valid && f.to.Valid:
+		parts = append(parts, f.from.Time.Format(layout)+" – "+f.to.Time.Format(layout))
+	case f.from.Valid:
+		parts = append(parts, "From "+f.from.Time.Format(layout))
+	case f.to.Valid:
+		parts = append(parts, "Until "+f.to.Time.Format(layout))
+	}
+	if len(f.types) > 0 {
+		parts = append(parts, "Types: "+strings.Join(f.types, ", "))
+	}
+	if len(f.attendees) > 0 {
+		parts = append(parts, "With: "+strings.Join(f.attendees, ", "))
+	}
+	if len(f.tags) > 0 {
+		parts = append(parts, "Tags: #"+strings.Join(f.tags, " #"))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "All entries")
+	}
+	if f.includeArchived {
+		parts = append(parts, "Archived entries included")
+	} else {
+		parts = append(parts, "Archived entries left out")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// exportedFile is a link to one of an entry's attachments
+type exportedFile struct {
+	name string
+	href string
+}
+
+// entryArticle renders an entry with its details, its body and links to its
+// attachments. bodyHTML has already had its links rewritten.
+func entryArticle(entry db.Entry, tags []string, bodyHTML string, attachments []exportedFile) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<article id=\"%s\">\n<h2>%s</h2>\n", entryAnchor(entry.ID), html.EscapeString(entryTitle(entry)))
+
+	details := []string{entryDate(entry).Format("Monday, 2 January 2006"), entry.Type}
+	if len(entry.Attendees) > 0 {
+		details = append(details, "With "+strings.Join(entry.Attendees, ", "))
+	}
+	if len(tags) > 0 {
+		details = append(details, "#"+strings.Join(tags, " #"))
+	}
+	if entry.Archived {
+		details = append(details, "Archived")
+	}
+	fmt.Fprintf(&b, "<p class=\"details\">%s</p>\n", html.EscapeString(strings.Join(details, " · ")))
+	b.WriteString("<div class=\"body\">" + bodyHTML + "</div>\n")
+
+	if len(attachments) > 0 {
+		b.WriteString("<section class=\"attachments\">\n<h3>Attachments</h3>\n<ul>\n")
+		for _, file := range attachments {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(file.href), html.EscapeString(file.name))
+		}
+		b.WriteString("</ul>\n</section>\n")
+	}
+	b.WriteString("</article>\n")
+	return b.String()
+}
+
+// writeHTML adds an entry's page to an HTML export and its line to the
+// spooled index. Links to other entries and to attachments become links
+// relative to the page.
+func (e *exporter) writeHTML(entry db.Entry, tags []string, attachments []db.Attachment, paths map[string]string, links []db.ListLinksForEntriesRow, files map[pgtype.UUID]string) error {
+	// Entry pages are two folders down, in YYYY/MM
+	const up = "../../"
+	relativeFiles := make(map[pgtype.UUID]string, len(files))
+	for id, file := range files {
+		relativeFiles[id] = up + file
+	}
+	relativePaths := make(map[string]string, len(paths))
+	for id, zipPath := range paths {
+		relativePaths[id] = up + zipPath
+	}
+	bodyHTML := rewriteAttachmentLinks(rewriteExportLinks(entry.BodyHtml, links, relativeFiles), relativePaths)
+
+	var listed []exportedFile
+	for _, attachment := range attachments {
+		if zipPath, ok := relativePaths[attachment.ID.String()]; ok {
+			listed = append(listed, exportedFile{name: attachment.Filename, href: zipPath})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(htmlDocumentStart(entryTitle(entry)))
+	b.WriteString("<nav><a href=\"" + up + "index.html\">All entries</a></nav>\n")
+	b.WriteString(entryArticle(entry, tags, bodyHTML, listed))
+	b.WriteString(htmlDocumentEnd)
+
+	file := e.entryFile(entry)
+	if err := e.writeFile(file, strings.NewReader(b.String())); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(e.index, "<li><span class=\"date\">%s</span> <a href=\"%s\">%s</a></li>\n",
+		entryDate(entry).Format("2 Jan 2006"), html.EscapeString(file), html.EscapeString(entryTitle(entry)))
+	return err
+}
+
+// writeIndex adds index.html, listing the entries spooled by writeHTML, to an
+// HTML export
+func (e *exporter) writeIndex(exportedAt time.Time) error {
+	if _, err := e.index.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := io.MultiReader(
+		strings.NewReader(htmlDocumentStart("Journal")+exportHeader(e.filter, exportedAt)+"<ul class=\"entries\">\n"),
+		e.index,
+		strings.NewReader("</ul>\n"+htmlDocumentEnd),
+	)
+	return e.writeFile("index.html", r)
+}
+
+// writePrint writes a printable export: one page with every entry, starting
+// with the given page of them. Images and attachments keep their in-app
+// URLs, so the page is meant to be opened from the app and printed.
+func (e *exporter) writePrint(ctx context.Context, entries []db.Entry, params db.ListEntriesForExportParams, exportedAt time.Time) error {
+	if _, err := io.WriteString(e.out, htmlDocumentStart("Journal")+exportHeader(e.filter, exportedAt)); err != nil {
+		return err
+	}
+
+	for len(entries) > 0 {
+		page, err := e.loadPage(ctx, entries)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			var listed []exportedFile
+			for _, attachment := range page.attachments[entry.ID] {
+				listed = append(listed, exportedFile{name: attachment.Filename, href: "/api/attachments/" + attachment.ID.String()})
+			}
+			bodyHTML := rewriteExportLinks(entry.BodyHtml, page.links[entry.ID], page.files)
+			if _, err := io.WriteString(e.out, entryArticle(entry, page.tags[entry.ID], bodyHTML, listed)); err != nil {
+				return err
+			}
+			e.entries++
+		}
+
+		entries, err = e.nextPage(ctx, &params, entries)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries: %w", err)
+		}
+	}
+
+	if e.entries == 0 {
+		if _, err := io.WriteString(e.out, "<p>No entries match.</p>\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(e.out, htmlDocumentEnd)
+	return err
+}
+
+// entryAnchor is the id of an entry's article in a printable export
+func entryAnchor(id pgtype.UUID) string {
+	return "entry-" + id.String()
+}
+
+// entryTitle is an entry's title, or a placeholder for an untitled one
+func entryTitle(entry db.Entry) string {
+	if strings.TrimSpace(entry.Title) == "" {
+		return "Untitled"
+	}
+	return entry.Title
+}
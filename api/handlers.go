@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,19 +16,29 @@ import (
 	"github.com/chrisbakker/journal/vectorservice"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/microcosm-cc/bluemonday"
 )
 
+// DefaultUserID is the user every request acts as
+const DefaultUserID = "02a0aa58-b88a-46f1-9799-f103e04c0b72"
+
+// emptyDelta is the Quill delta of an empty body
+const emptyDelta = `{"ops":[{"insert":"\n"}]}`
+
 type Handler struct {
+	dbpool          *pgxpool.Pool
 	queries         *db.Queries
+	entries         *EntryService
 	defaultTimezone string
 	sanitizer       *bluemonday.Policy
 	vectorService   *vectorservice.VectorService
 	ollamaClient    *ollama.Client
 }
 
-func NewHandler(queries *db.Queries, defaultTimezone string, vectorService *vectorservice.VectorService, ollamaClient *ollama.Client) *Handler {
+func NewHandler(dbpool *pgxpool.Pool, queries *db.Queries, defaultTimezone string, vectorService *vectorservice.VectorService, ollamaClient *ollama.Client) *Handler {
 	// Create a custom sanitizer policy that allows formatting tags
 	sanitizer := bluemonday.UGCPolicy()
 	sanitizer.AllowElements("br", "strong", "em", "u", "ul", "ol", "li", "p", "table", "thead", "tbody", "tr", "td", "th", "h1", "h2", "h3")
@@ -37,7 +48,9 @@ func NewHandler(queries *db.Queries, defaultTimezone string, vectorService *vect
 	sanitizer.AllowAttrs("colspan", "rowspan").OnElements("td", "th")
 
 	return &Handler{
+		dbpool:          dbpool,
 		queries:         queries,
+		entries:         NewEntryService(dbpool, queries),
 		defaultTimezone: defaultTimezone,
 		sanitizer:       sanitizer,
 		vectorService:   vectorService,
@@ -53,7 +66,9 @@ type CreateEntryRequest struct {
 	BodyText          string          `json:"body_text"`
 	AttendeesOriginal string          `json:"attendees_original"`
 	Type              string          `json:"type"`
-	Date              string          `json:"date"` // YYYY-MM-DD
+	Tags              []string        `json:"tags"`
+	Date              string          `json:"date"`        // YYYY-MM-DD, defaults to today in the user's timezone
+	TemplateID        string          `json:"template_id"` // optional; fills empty fields from the template
 }
 
 type UpdateEntryRequest struct {
@@ -63,6 +78,7 @@ type UpdateEntryRequest struct {
 	BodyText          *string          `json:"body_text,omitempty"`
 	AttendeesOriginal *string          `json:"attendees_original,omitempty"`
 	Type              *string          `json:"type,omitempty"`
+	Tags              *[]string        `json:"tags,omitempty"`
 }
 
 type EntryResponse struct {
@@ -75,6 +91,9 @@ type EntryResponse struct {
 	AttendeesOriginal string          `json:"attendees_original"`
 	Attendees         []string        `json:"attendees"`
 	Type              string          `json:"type"`
+	Tags              []string        `json:"tags"`
+	Pinned            bool            `json:"pinned"`
+	Favorite          bool            `json:"favorite"`
 	DayYear           int32           `json:"day_year"`
 	DayMonth          int32           `json:"day_month"`
 	DayDay            int32           `json:"day_day"`
@@ -83,17 +102,14 @@ type EntryResponse struct {
 }
 
 func (h *Handler) ListEntriesForDay(c *gin.Context) {
-	dateStr := c.Param("date") // Format: YYYY-MM-DD
-	dateParts := strings.Split(dateStr, "-")
-	if len(dateParts) != 3 {
+	dateStr := c.Param("date") // Format: YYYY-MM-DD or "today"
+	userID := h.getDefaultUserID(c)
+
+	year, month, day, err := h.resolveDay(c.Request.Context(), userID, dateStr)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format"})
 		return
 	}
-	year, _ := strconv.Atoi(dateParts[0])
-	month, _ := strconv.Atoi(dateParts[1])
-	day, _ := strconv.Atoi(dateParts[2])
-
-	userID := h.getDefaultUserID(c)
 
 	entries, err := h.queries.ListEntriesForDay(c.Request.Context(), db.ListEntriesForDayParams{
 		UserID:   userID,
@@ -107,9 +123,10 @@ func (h *Handler) ListEntriesForDay(c *gin.Context) {
 		return
 	}
 
-	response := make([]EntryResponse, len(entries))
-	for i, entry := range entries {
-		response[i] = entryToResponse(entry)
+	response, err := h.entriesToResponse(c.Request.Context(), entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -122,48 +139,80 @@ func (h *Handler) CreateEntry(c *gin.Context) {
 		return
 	}
 
-	dateParts := strings.Split(req.Date, "-")
-	if len(dateParts) != 3 {
+	userID := h.getDefaultUserID(c)
+
+	// An empty date means "today" in the user's timezone
+	year, month, day, err := h.resolveDay(c.Request.Context(), userID, req.Date)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, expected YYYY-MM-DD"})
 		return
 	}
-	year, _ := strconv.Atoi(dateParts[0])
-	month, _ := strconv.Atoi(dateParts[1])
-	day, _ := strconv.Atoi(dateParts[2])
 
-	if req.Type != "meeting" && req.Type != "notes" && req.Type != "other" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be meeting, notes, or other"})
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, h.userLocation(c.Request.Context(), userID))
+	if req.TemplateID != "" {
+		templateID, err := uuid.Parse(req.TemplateID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+			return
+		}
+		template, err := h.queries.GetTemplate(c.Request.Context(), db.GetTemplateParams{
+			ID:     pgtype.UUID{Bytes: templateID, Valid: true},
+			UserID: userID,
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+		if err := h.applyTemplate(&req, template, date); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entryType, err := h.lookupEntryType(c.Request.Context(), userID, req.Type)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Use HTML from Quill directly, sanitize it
-	bodyHTML := h.sanitizer.Sanitize(req.BodyHTML)
-	attendees := normalizeAttendees(req.AttendeesOriginal)
-	userID := h.getDefaultUserID(c)
+	// Without a body or a template, the entry starts from its type's default
+	// template, if it has one, or empty
+	if len(req.BodyDelta) == 0 || string(req.BodyDelta) == "null" {
+		bodyDelta := json.RawMessage(emptyDelta)
+		if req.TemplateID == "" && len(entryType.DefaultTemplateDelta) > 0 {
+			bodyDelta, err = renderDeltaPlaceholders(entryType.DefaultTemplateDelta, date)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to render the type's template: %v", err)})
+				return
+			}
+		}
+		req.BodyDelta = bodyDelta
+		req.BodyHTML = h.deltaToHTML(bodyDelta)
+		req.BodyText = deltaToText(bodyDelta)
+	}
 
-	// Record attendees in the attendees table
-	h.recordAttendees(c.Request.Context(), userID, attendees)
+	tags := normalizeTags(req.Tags)
 
-	entry, err := h.queries.CreateEntry(c.Request.Context(), db.CreateEntryParams{
+	entry, err := h.entries.CreateEntry(c.Request.Context(), db.CreateEntryParams{
 		UserID:            userID,
 		Title:             req.Title,
 		BodyDelta:         req.BodyDelta,
-		BodyHtml:          bodyHTML,
+		BodyHtml:          h.sanitizer.Sanitize(req.BodyHTML), // HTML from Quill, sanitized
 		BodyText:          req.BodyText,
 		AttendeesOriginal: req.AttendeesOriginal,
-		Attendees:         attendees,
-		Type:              req.Type,
+		Type:              entryType.Name,
 		DayYear:           int32(year),
 		DayMonth:          int32(month),
 		DayDay:            int32(day),
-	})
-
+	}, tags)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, entryToResponse(entry))
+	response := entryToResponse(entry)
+	response.Tags = tags
+	c.JSON(http.StatusCreated, response)
 }
 
 func (h *Handler) UpdateEntry(c *gin.Context) {
@@ -179,64 +228,47 @@ func (h *Handler) UpdateEntry(c *gin.Context) {
 		return
 	}
 
-	existing, err := h.queries.GetEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
-		return
-	}
-
-	title := existing.Title
-	bodyDelta := existing.BodyDelta
-	bodyHTML := existing.BodyHtml
-	bodyText := existing.BodyText
-	attendeesOriginal := existing.AttendeesOriginal
-	attendees := existing.Attendees
-	entryType := existing.Type
-
-	if req.Title != nil {
-		title = *req.Title
-	}
-	if req.BodyDelta != nil {
-		bodyDelta = *req.BodyDelta
+	patch := EntryPatch{
+		Title:             req.Title,
+		BodyDelta:         req.BodyDelta,
+		BodyText:          req.BodyText,
+		AttendeesOriginal: req.AttendeesOriginal,
 	}
 	if req.BodyHTML != nil {
 		// Use HTML from Quill directly, sanitize it
-		bodyHTML = h.sanitizer.Sanitize(*req.BodyHTML)
-	}
-	if req.BodyText != nil {
-		bodyText = *req.BodyText
-	}
-	if req.AttendeesOriginal != nil {
-		attendeesOriginal = *req.AttendeesOriginal
-		attendees = normalizeAttendees(*req.AttendeesOriginal)
-		// Record attendees in the attendees table
-		h.recordAttendees(c.Request.Context(), existing.UserID, attendees)
+		bodyHTML := h.sanitizer.Sanitize(*req.BodyHTML)
+		patch.BodyHTML = &bodyHTML
 	}
 	if req.Type != nil {
-		if *req.Type != "meeting" && *req.Type != "notes" && *req.Type != "other" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be meeting, notes, or other"})
+		t, err := h.lookupEntryType(c.Request.Context(), h.getDefaultUserID(c), *req.Type)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		entryType = *req.Type
-	}
-
-	entry, err := h.queries.UpdateEntry(c.Request.Context(), db.UpdateEntryParams{
-		ID:                pgtype.UUID{Bytes: entryID, Valid: true},
-		Title:             title,
-		BodyDelta:         bodyDelta,
-		BodyHtml:          bodyHTML,
-		BodyText:          bodyText,
-		AttendeesOriginal: attendeesOriginal,
-		Attendees:         attendees,
-		Type:              entryType,
-	})
+		patch.Type = &t.Name
+	}
+	if req.Tags != nil {
+		tags := normalizeTags(*req.Tags)
+		patch.Tags = &tags
+	}
 
+	entry, err := h.entries.UpdateEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true}, patch)
+	if errors.Is(err, ErrEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.entriesToResponse(c.Request.Context(), []db.Entry{entry})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, entryToResponse(entry))
+	c.JSON(http.StatusOK, response[0])
 }
 
 func (h *Handler) DeleteEntry(c *gin.Context) {
@@ -246,7 +278,11 @@ func (h *Handler) DeleteEntry(c *gin.Context) {
 		return
 	}
 
-	err = h.queries.SoftDeleteEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	err = h.entries.DeleteEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	if errors.Is(err, ErrEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -285,91 +321,45 @@ func (h *Handler) GetDaysWithEntries(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"daysWithEntries": dayNumbers})
 }
 
-func (h *Handler) UploadAttachment(c *gin.Context) {
-	entryID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
-		return
-	}
-
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no file provided"})
-		return
-	}
-	defer file.Close()
-
-	fileData := make([]byte, header.Size)
-	if _, err := file.Read(fileData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
-		return
-	}
-
-	userID := h.getDefaultUserID(c)
-
-	attachment, err := h.queries.CreateAttachment(c.Request.Context(), db.CreateAttachmentParams{
-		UserID:    userID,
-		EntryID:   pgtype.UUID{Bytes: entryID, Valid: true},
-		Filename:  header.Filename,
-		MimeType:  header.Header.Get("Content-Type"),
-		SizeBytes: header.Size,
-		Data:      fileData,
-	})
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+// Helper functions
 
-	c.JSON(http.StatusCreated, gin.H{
-		"id":         attachment.ID,
-		"filename":   attachment.Filename,
-		"mime_type":  attachment.MimeType,
-		"size_bytes": attachment.SizeBytes,
-		"created_at": attachment.CreatedAt,
-	})
+func (h *Handler) getDefaultUserID(c *gin.Context) pgtype.UUID {
+	userID, _ := uuid.Parse(DefaultUserID)
+	return pgtype.UUID{Bytes: userID, Valid: true}
 }
 
-func (h *Handler) GetAttachment(c *gin.Context) {
-	attachmentID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
-		return
+// userLocation returns the user's configured timezone, falling back to the
+// server default (and finally UTC) if the user has none or it is invalid
+func (h *Handler) userLocation(ctx context.Context, userID pgtype.UUID) *time.Location {
+	if user, err := h.queries.GetUser(ctx, userID); err == nil {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc
+		}
 	}
-
-	attachment, err := h.queries.GetAttachment(c.Request.Context(), pgtype.UUID{Bytes: attachmentID, Valid: true})
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
-		return
+	if loc, err := time.LoadLocation(h.defaultTimezone); err == nil {
+		return loc
 	}
+	return time.UTC
+}
 
-	c.Header("Content-Type", attachment.MimeType)
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", attachment.Filename))
-	c.Data(http.StatusOK, attachment.MimeType, attachment.Data)
+// userToday returns the current date in the user's timezone
+func (h *Handler) userToday(ctx context.Context, userID pgtype.UUID) time.Time {
+	return time.Now().In(h.userLocation(ctx, userID))
 }
 
-func (h *Handler) DeleteAttachment(c *gin.Context) {
-	attachmentID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
-		return
+// resolveDay parses a YYYY-MM-DD date, treating "" and "today" as the
+// current day in the user's timezone
+func (h *Handler) resolveDay(ctx context.Context, userID pgtype.UUID, dateStr string) (int, int, int, error) {
+	if dateStr == "" || dateStr == "today" {
+		today := h.userToday(ctx, userID)
+		return today.Year(), int(today.Month()), today.Day(), nil
 	}
 
-	err = h.queries.DeleteAttachment(c.Request.Context(), pgtype.UUID{Bytes: attachmentID, Valid: true})
+	date, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return 0, 0, 0, err
 	}
-
-	c.JSON(http.StatusNoContent, nil)
-}
-
-// Helper functions
-
-func (h *Handler) getDefaultUserID(c *gin.Context) pgtype.UUID {
-	userIDStr := "02a0aa58-b88a-46f1-9799-f103e04c0b72"
-	userID, _ := uuid.Parse(userIDStr)
-	return pgtype.UUID{Bytes: userID, Valid: true}
+	return date.Year(), int(date.Month()), date.Day(), nil
 }
 
 func (h *Handler) deltaToHTML(delta json.RawMessage) string {
@@ -407,7 +397,11 @@ func (h *Handler) deltaToHTML(delta json.RawMessage) string {
 			if list, ok := op.Attributes["list"].(string); ok {
 				isListItem = true
 				listType = list
-				text = "<li>" + strings.TrimSuffix(text, "\n") + "</li>"
+				if list == "checked" || list == "unchecked" {
+					text = `<li data-list="` + list + `">` + strings.TrimSuffix(text, "\n") + "</li>"
+				} else {
+					text = "<li>" + strings.TrimSuffix(text, "\n") + "</li>"
+				}
 			}
 		}
 
@@ -472,6 +466,18 @@ func (h *Handler) deltaToHTML(delta json.RawMessage) string {
 	return h.sanitizer.Sanitize(result)
 }
 
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
 func normalizeAttendees(original string) []string {
 	if original == "" {
 		return []string{}
@@ -499,6 +505,7 @@ func entryToResponse(entry db.Entry) EntryResponse {
 		AttendeesOriginal: entry.AttendeesOriginal,
 		Attendees:         entry.Attendees,
 		Type:              entry.Type,
+		Tags:              []string{},
 		DayYear:           entry.DayYear,
 		DayMonth:          entry.DayMonth,
 		DayDay:            entry.DayDay,
@@ -507,10 +514,12 @@ func entryToResponse(entry db.Entry) EntryResponse {
 	}
 }
 
-// SearchEntries searches for entries by title, body, or attendees
+// SearchEntries searches for entries by title, body, or attendees,
+// optionally restricted to entries carrying every given ?tag=
 func (h *Handler) SearchEntries(c *gin.Context) {
 	query := c.Query("q")
-	if query == "" {
+	tags := normalizeTags(c.QueryArray("tag"))
+	if query == "" && len(tags) == 0 {
 		c.JSON(http.StatusOK, []EntryResponse{})
 		return
 	}
@@ -521,15 +530,17 @@ func (h *Handler) SearchEntries(c *gin.Context) {
 	entries, err := h.queries.SearchEntries(c.Request.Context(), db.SearchEntriesParams{
 		UserID:  pgtype.UUID{Bytes: userID, Valid: true},
 		Column2: pgtype.Text{String: query, Valid: true},
+		Tags:    tags,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search entries"})
 		return
 	}
 
-	response := make([]EntryResponse, len(entries))
-	for i, entry := range entries {
-		response[i] = entryToResponse(entry)
+	response, err := h.entriesToResponse(c.Request.Context(), entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search entries"})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -542,9 +553,19 @@ type ChatRequest struct {
 
 // ChatResponse represents the AI assistant's response
 type ChatResponse struct {
-	Response      string          `json:"response"`
-	SourceEntries []EntryResponse `json:"source_entries"`
-	MessageID     string          `json:"message_id"` // Unique ID for this response
+	Response          string             `json:"response"`
+	SourceEntries     []EntryResponse    `json:"source_entries"`
+	SourceAttachments []AttachmentSource `json:"source_attachments"`
+	MessageID         string             `json:"message_id"` // Unique ID for this response
+}
+
+// AttachmentSource is an attachment whose text the assistant cited
+type AttachmentSource struct {
+	ID         string `json:"id"`
+	EntryID    string `json:"entry_id"`
+	EntryTitle string `json:"entry_title"`
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mime_type"`
 }
 
 // Chat handles AI chat interactions (Phase 3 - RAG)
@@ -563,17 +584,18 @@ func (h *Handler) Chat(c *gin.Context) {
 	// Use test user for now
 	testUserID := uuid.MustParse("02a0aa58-b88a-46f1-9799-f103e04c0b72")
 
-	// Search for similar journal entries using RAG
-	similarEntries, err := h.vectorService.SearchSimilarEntries(c.Request.Context(), testUserID, req.Message, 5)
+	// Search for similar journal entries and attachments using RAG
+	similarEntries, similarAttachments, err := h.vectorService.SearchSimilar(c.Request.Context(), testUserID, req.Message, 5, 3)
 	if err != nil {
 		log.Printf("Error searching similar entries: %v", err)
 		// Continue without context if search fails
-		similarEntries = nil
+		similarEntries, similarAttachments = nil, nil
 	}
 
-	log.Printf("Chat search found %d similar entries for query: %s", len(similarEntries), req.Message)
+	log.Printf("Chat search found %d similar entries and %d attachments for query: %s", len(similarEntries), len(similarAttachments), req.Message)
 
-	// Build context from similar entries
+	// Build context from similar entries, then attachments, numbered in one
+	// sequence so citations can refer to either
 	var contextBuilder strings.Builder
 	if len(similarEntries) > 0 {
 		contextBuilder.WriteString("Here are some relevant journal entries:\n\n")
@@ -583,11 +605,25 @@ func (h *Handler) Chat(c *gin.Context) {
 				i+1, entry.Title, entry.DayYear, entry.DayMonth, entry.DayDay, entry.BodyText))
 		}
 	}
+	if len(similarAttachments) > 0 {
+		contextBuilder.WriteString("Here are excerpts from relevant files attached to journal entries:\n\n")
+		for i, attachment := range similarAttachments {
+			contextBuilder.WriteString(fmt.Sprintf("%d. File \"%s\" attached to \"%s\" (Date: %d-%02d-%02d)\n%s\n\n",
+				len(similarEntries)+i+1, attachment.Filename, attachment.EntryTitle,
+				attachment.DayYear, attachment.DayMonth, attachment.DayDay, attachment.Excerpt))
+		}
+	}
+	sourceCount := len(similarEntries) + len(similarAttachments)
 
 	// Build prompt for LLM
 	var promptBuilder strings.Builder
 	promptBuilder.WriteString("You are a helpful AI assistant with access to the user's journal entries. ")
-	promptBuilder.WriteString("Use the provided context to answer questions about past events, meetings, and notes.\n\n")
+	promptBuilder.WriteString("Use the provided context to answer questions about past events, meetings, and notes.\n")
+
+	// Give the model the user's local date so relative questions ("last week") resolve correctly
+	today := h.userToday(c.Request.Context(), pgtype.UUID{Bytes: testUserID, Valid: true})
+	promptBuilder.WriteString(fmt.Sprintf("Today is %s, %s (timezone: %s).\n\n",
+		today.Weekday(), today.Format("2006-01-02"), today.Location()))
 
 	if contextBuilder.Len() > 0 {
 		promptBuilder.WriteString(contextBuilder.String())
@@ -595,7 +631,7 @@ func (h *Handler) Chat(c *gin.Context) {
 
 	promptBuilder.WriteString("User Question: ")
 	promptBuilder.WriteString(req.Message)
-	promptBuilder.WriteString("\n\nIMPORTANT: After your response, on a new line, add 'CITATIONS: ' followed by ONLY the numbers of the journal entries you actually used (e.g., 'CITATIONS: 1, 3' or 'CITATIONS: none' if you didn't use any). Provide a helpful response based on the journal entries above.")
+	promptBuilder.WriteString("\n\nIMPORTANT: After your response, on a new line, add 'CITATIONS: ' followed by ONLY the numbers of the journal entries and files you actually used (e.g., 'CITATIONS: 1, 3' or 'CITATIONS: none' if you didn't use any). Provide a helpful response based on the journal entries above.")
 
 	// Get response from Ollama
 	llmResponse, err := h.ollamaClient.Chat(c.Request.Context(), promptBuilder.String())
@@ -620,7 +656,7 @@ func (h *Handler) Chat(c *gin.Context) {
 			citationParts := strings.Split(citationsStr, ",")
 			for _, citStr := range citationParts {
 				citStr = strings.TrimSpace(citStr)
-				if num, err := strconv.Atoi(citStr); err == nil && num > 0 && num <= len(similarEntries) {
+				if num, err := strconv.Atoi(citStr); err == nil && num > 0 && num <= sourceCount {
 					citedIndices = append(citedIndices, num-1) // Convert to 0-based index
 				}
 			}
@@ -629,9 +665,21 @@ func (h *Handler) Chat(c *gin.Context) {
 		actualResponse = llmResponse
 	}
 
-	// Only include entries that were actually cited
-	var sourceEntries []EntryResponse
+	// Only include entries and attachments that were actually cited
+	var citedEntries []db.Entry
+	sourceAttachments := []AttachmentSource{}
 	for _, idx := range citedIndices {
+		if idx >= len(similarEntries) {
+			attachment := similarAttachments[idx-len(similarEntries)]
+			sourceAttachments = append(sourceAttachments, AttachmentSource{
+				ID:         attachment.ID.String(),
+				EntryID:    attachment.EntryID.String(),
+				EntryTitle: attachment.EntryTitle,
+				Filename:   attachment.Filename,
+				MimeType:   attachment.MimeType,
+			})
+			continue
+		}
 		entry := similarEntries[idx]
 		// Need to fetch full entry details
 		fullEntry, err := h.queries.GetEntry(c.Request.Context(), entry.ID)
@@ -639,18 +687,28 @@ func (h *Handler) Chat(c *gin.Context) {
 			log.Printf("Error fetching entry %s: %v", entry.ID, err)
 			continue
 		}
-		sourceEntries = append(sourceEntries, entryToResponse(fullEntry))
+		citedEntries = append(citedEntries, fullEntry)
+	}
+
+	var sourceEntries []EntryResponse
+	if len(citedEntries) > 0 {
+		sourceEntries, err = h.entriesToResponse(c.Request.Context(), citedEntries)
+		if err != nil {
+			log.Printf("Error loading tags for cited entries: %v", err)
+			sourceEntries = nil
+		}
 	}
 
-	log.Printf("LLM cited %d out of %d entries", len(citedIndices), len(similarEntries))
+	log.Printf("LLM cited %d out of %d entries and attachments", len(citedIndices), sourceCount)
 
 	// Generate unique message ID
 	messageID := uuid.New().String()
 
 	response := ChatResponse{
-		Response:      actualResponse,
-		SourceEntries: sourceEntries,
-		MessageID:     messageID,
+		Response:          actualResponse,
+		SourceEntries:     sourceEntries,
+		SourceAttachments: sourceAttachments,
+		MessageID:         messageID,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -674,20 +732,32 @@ func stripHTMLTags(html string) string {
 }
 
 // recordAttendees records attendees in the attendees table for autocomplete
-func (h *Handler) recordAttendees(ctx context.Context, userID pgtype.UUID, attendees []string) {
+// and recounts use_count for them and for any previous attendees of the
+// entry. It runs on q so the counts commit with the entry write.
+func recordAttendees(ctx context.Context, q *db.Queries, userID pgtype.UUID, attendees, previous []string) error {
+	names := make([]string, 0, len(attendees)+len(previous))
 	for _, name := range attendees {
 		if name == "" {
 			continue
 		}
-		_, err := h.queries.GetOrCreateAttendee(ctx, db.GetOrCreateAttendeeParams{
+		if _, err := q.GetOrCreateAttendee(ctx, db.GetOrCreateAttendeeParams{
 			UserID: userID,
 			Name:   name,
-		})
-		if err != nil {
-			log.Printf("Error recording attendee %s: %v", name, err)
-			// Don't fail the entire operation if attendee recording fails
+		}); err != nil {
+			return fmt.Errorf("failed to record attendee %s: %w", name, err)
 		}
+		names = append(names, strings.ToLower(name))
 	}
+	for _, name := range previous {
+		if name != "" {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	return q.RecountAttendees(ctx, db.RecountAttendeesParams{UserID: userID, Names: names})
 }
 
 // SearchAttendees provides autocomplete suggestions for attendee names
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chrisbakker/journal/markdown"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// datePattern finds an entry's date in front matter or a file name
+var datePattern = regexp.MustCompile(`(\d{4})[-_.](\d{2})[-_.](\d{2})`)
+
+// folderDatePattern finds an entry's date in folders named by year, month
+// and day
+var folderDatePattern = regexp.MustCompile(`(\d{4})/(\d{2})/(\d{2})`)
+
+// titleHeadingPattern matches a level 1 heading at the start of a body,
+// which becomes the entry's title
+var titleHeadingPattern = regexp.MustCompile(`^[ \t]*#[ \t]+(.+?)(?:[ \t]+#+)?[ \t]*(?:\n|$)`)
+
+// dayOneMediaPattern matches Day One's references to videos, audio and PDFs,
+// which are linked rather than shown as images
+var dayOneMediaPattern = regexp.MustCompile(`!\[([^\]]*)\]\((dayone-moment:/(?:video|audio|pdfAttachment)/[^)\s]+)\)`)
+
+// dayOneMomentPattern matches any Day One reference to an entry's media
+var dayOneMomentPattern = regexp.MustCompile(`^dayone-moment:/{1,2}(?:[A-Za-z]+/)?([0-9A-Za-z-]+)$`)
+
+// maxTitleLength caps titles taken from an entry's first line
+const maxTitleLength = 100
+
+// markdownDocument is a Markdown file being imported
+type markdownDocument struct {
+	file   string
+	fields markdown.Fields
+	body   string
+	id     pgtype.UUID
+	action string
+	err    error
+}
+
+// planMarkdown reads every Markdown file, in any folder, as an entry. The
+// front matter gives the entry's title, date, type, attendees and tags;
+// without them the title comes from a leading heading or the file name and
+// the date from the file name or its folders. Files the Markdown links to or
+// embeds become the entry's attachments, and links to other Markdown files
+// become links to their entries.
+func (imp *importer) planMarkdown(ctx context.Context) ([]importPlan, error) {
+	var docs []*markdownDocument
+	byName := make(map[string]string) // lowercased file name to path, for Obsidian's links by name
+	err := fs.WalkDir(imp.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip hidden folders such as .obsidian and .git, and macOS's zip
+		// metadata
+		if name != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "__MACOSX") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, seen := byName[strings.ToLower(d.Name())]; !seen {
+			byName[strings.ToLower(d.Name())] = name
+		}
+		if ext := strings.ToLower(path.Ext(name)); ext == ".md" || ext == ".markdown" {
+			docs = append(docs, &markdownDocument{file: name})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &importError{http.StatusBadRequest, err}
+	}
+	if len(docs) == 0 {
+		return nil, &importError{http.StatusBadRequest, errors.New("archive has no Markdown files")}
+	}
+
+	// IDs are assigned first so that links between the files can point at
+	// the entries they become
+	for _, doc := range docs {
+		data, err := readImportFile(imp.fsys, doc.file, maxImportEntryBytes)
+		if err != nil {
+			doc.err = err
+			continue
+		}
+		doc.fields, doc.body, doc.err = markdown.SplitFrontMatter(string(data))
+		if doc.err != nil {
+			continue
+		}
+		doc.id, doc.action, err = imp.resolveEntryID(ctx, doc.fields.String("id"))
+		if err != nil {
+			return nil, err
+		}
+		if id := doc.fields.String("id"); id != "" {
+			imp.ids[strings.ToLower(id)] = doc.id.String()
+		}
+		imp.entryFiles[doc.file] = doc.id.String()
+	}
+
+	var plans []importPlan
+	for _, doc := range docs {
+		result := ImportedEntry{File: doc.file, ID: doc.fields.String("id")}
+		if doc.err != nil {
+			imp.fail(&result, doc.err)
+			imp.report.Entries = append(imp.report.Entries, result)
+			continue
+		}
+		entry, err := imp.markdownEntry(doc, byName)
+		if err == nil {
+			err = validateExportedEntry(entry)
+		}
+		if err != nil {
+			result.Title = entry.Title
+			imp.fail(&result, err)
+			imp.report.Entries = append(imp.report.Entries, result)
+			continue
+		}
+		plans = append(plans, imp.addPlan(result, entry, doc.id, doc.action))
+	}
+	return plans, nil
+}
+
+// markdownEntry converts a Markdown file to the entry it is imported as
+func (imp *importer) markdownEntry(doc *markdownDocument, byName map[string]string) (exportedEntry, error) {
+	fields := doc.fields
+	entry := exportedEntry{
+		ID:       fields.String("id"),
+		Title:    fields.String("title"),
+		Type:     fields.String("type"),
+		Archived: fields.Bool("archived"),
+	}
+	if entry.Type == "" {
+		entry.Type = imp.opts.Type
+	}
+
+	// A leading heading is the title, unless it only repeats it
+	body := strings.TrimLeft(doc.body, "\n")
+	if m := titleHeadingPattern.FindStringSubmatch(body); m != nil {
+		heading := markdown.Unescape(strings.TrimSpace(m[1]))
+		if entry.Title == "" {
+			entry.Title = heading
+		}
+		if strings.EqualFold(heading, entry.Title) {
+			body = body[len(m[0]):]
+		}
+	}
+	if entry.Title == "" {
+		stem := strings.TrimSuffix(path.Base(doc.file), path.Ext(doc.file))
+		entry.Title = strings.TrimSpace(strings.Trim(datePattern.ReplaceAllString(stem, ""), " _-."))
+	}
+
+	date := datePattern.FindStringSubmatch(fields.String("date"))
+	if date == nil {
+		date = datePattern.FindStringSubmatch(path.Base(doc.file))
+	}
+	if date == nil {
+		date = folderDatePattern.FindStringSubmatch(doc.file)
+	}
+	if date == nil {
+		return entry, errors.New("no date in the front matter or file name")
+	}
+	setEntryDate(&entry, date)
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, fields.String("created"))
+	entry.UpdatedAt, _ = time.Parse(time.RFC3339, fields.String("updated"))
+
+	entry.Attendees = fields.List("attendees")
+	attendeesOriginal := strings.Join(entry.Attendees, ", ")
+	entry.AttendeesOriginal = &attendeesOriginal
+	for _, tag := range fields.List("tags") {
+		entry.Tags = append(entry.Tags, strings.TrimPrefix(tag, "#"))
+	}
+
+	// Links to files in the archive become links to their entries or to
+	// the entry's attachments
+	dir := path.Dir(doc.file)
+	urls := make(map[string]string)
+	parsed := markdown.Parse(body, func(link string) string {
+		name, ok := imp.localFile(dir, link, byName)
+		if !ok {
+			return link
+		}
+		if mapped, ok := urls[name]; ok {
+			return mapped
+		}
+		if ext := strings.ToLower(path.Ext(name)); ext == ".md" || ext == ".markdown" {
+			if id, ok := imp.entryFiles[name]; ok {
+				return "/entries/" + id
+			}
+			return link
+		}
+		attachment, ok := imp.documentAttachment(doc.file, name)
+		if !ok {
+			return link
+		}
+		entry.Attachments = append(entry.Attachments, attachment)
+		urls[name] = "/api/attachments/" + attachment.ID
+		return urls[name]
+	})
+	entry.BodyDelta = parsed.Delta()
+	entry.BodyHTML = parsed.HTML()
+	entry.BodyText = deltaToText(entry.BodyDelta)
+	return entry, nil
+}
+
+// localFile resolves a link in a Markdown file in dir to a file in the
+// archive: relative to the Markdown, or by name anywhere in it, as Obsidian
+// links attachments
+func (imp *importer) localFile(dir, link string, byName map[string]string) (string, bool) {
+	if link == "" || strings.HasPrefix(link, "/") || strings.HasPrefix(link, "#") {
+		return "", false
+	}
+	if u, err := url.Parse(link); err == nil && u.Scheme != "" {
+		return "", false
+	}
+	link, _, _ = strings.Cut(link, "#")
+	link, _, _ = strings.Cut(link, "?")
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+
+	name := path.Join(dir, link)
+	if info, err := fs.Stat(imp.fsys, name); fs.ValidPath(name) && err == nil && !info.IsDir() {
+		return name, true
+	}
+	if name, ok := byName[strings.ToLower(path.Base(link))]; ok {
+		return name, true
+	}
+	// [[Note]] embeds leave off the extension
+	if name, ok := byName[strings.ToLower(path.Base(link))+".md"]; ok {
+		return name, true
+	}
+	return "", false
+}
+
+// documentAttachment describes a file a Markdown or Day One entry refers
+// to, to be stored as a new attachment of the entry as if it were uploaded.
+// Files that can't be are reported as warnings and left out.
+func (imp *importer) documentAttachment(file, name string) (exportedAttachment, bool) {
+	warn := func(reason string) (exportedAttachment, bool) {
+		imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf("%s: skipped attachment %s: %s", file, name, reason))
+		return exportedAttachment{}, false
+	}
+
+	f, err := imp.fsys.Open(name)
+	if err != nil {
+		return warn("missing from the archive")
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return warn("missing from the archive")
+	}
+	if info.Size() > int64(imp.cfg.MaxUploadMB)<<20 {
+		return warn(fmt.Sprintf("exceeds the %d MB upload limit", imp.cfg.MaxUploadMB))
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return warn(err.Error())
+	}
+	mimeType := sniffContentType(head[:n], name)
+	if !allowedContentType(mimeType, imp.cfg.AllowedTypes) {
+		return warn(fmt.Sprintf("file type %s is not allowed", mimeType))
+	}
+
+	return exportedAttachment{
+		ID:        uuid.NewString(),
+		Filename:  path.Base(name),
+		MimeType:  mimeType,
+		SizeBytes: info.Size(),
+		Path:      name,
+	}, true
+}
+
+// dayOneJournal is a journal in a Day One JSON export
+type dayOneJournal struct {
+	Entries []dayOneEntry `json:"entries"`
+}
+
+type dayOneEntry struct {
+	UUID         string        `json:"uuid"`
+	CreationDate time.Time     `json:"creationDate"`
+	ModifiedDate time.Time     `json:"modifiedDate"`
+	TimeZone     string        `json:"timeZone"`
+	Text         string        `json:"text"`
+	Tags         []string      `json:"tags"`
+	Photos       []dayOneMedia `json:"photos"`
+	Videos       []dayOneMedia `json:"videos"`
+	Audios       []dayOneMedia `json:"audios"`
+	PDFs         []dayOneMedia `json:"pdfAttachments"`
+}
+
+// dayOneMedia is a file attached to a Day One entry, stored in the export as
+// <folder>/<md5>.<type>
+type dayOneMedia struct {
+	Identifier string `json:"identifier"`
+	MD5        string `json:"md5"`
+	Type       string `json:"type"`
+	Format     string `json:"format"` // audio has its extension here instead
+}
+
+// planDayOne reads the entries of each journal in a Day One JSON export.
+// Day One entries have no title, so it is the first line. Photos, videos,
+// audio and PDFs become attachments.
+func (imp *importer) planDayOne(ctx context.Context) ([]importPlan, error) {
+	files, err := fs.Glob(imp.fsys, "*.json")
+	if err != nil || len(files) == 0 {
+		return nil, &importError{http.StatusBadRequest, errors.New("archive has no Day One journal (.json) files")}
+	}
+	loc := imp.h.userLocation(ctx, imp.userID)
+
+	var plans []importPlan
+	for _, file := range files {
+		f, err := imp.fsys.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		var journal dayOneJournal
+		err = json.NewDecoder(f).Decode(&journal)
+		f.Close()
+		if err != nil {
+			imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf("%s: not a Day One journal: %v", file, err))
+			continue
+		}
+
+		for _, dayOne := range journal.Entries {
+			entry := imp.dayOneEntry(file, dayOne, loc)
+			result := ImportedEntry{File: file, ID: entry.ID, Title: entry.Title}
+			err := validateExportedEntry(entry)
+			if dayOne.CreationDate.IsZero() {
+				err = errors.New("entry has no creation date")
+			}
+			if err != nil {
+				imp.fail(&result, err)
+				imp.report.Entries = append(imp.report.Entries, result)
+				continue
+			}
+
+			id, action, err := imp.resolveEntryID(ctx, entry.ID)
+			if err != nil {
+				return nil, err
+			}
+			plans = append(plans, imp.addPlan(result, entry, id, action))
+		}
+	}
+	return plans, nil
+}
+
+// dayOneEntry converts a Day One entry to the entry it is imported as. loc
+// is used for entries without a time zone.
+func (imp *importer) dayOneEntry(file string, dayOne dayOneEntry, loc *time.Location) exportedEntry {
+	entry := exportedEntry{
+		ID:        dayOne.UUID,
+		Type:      imp.opts.Type,
+		Tags:      dayOne.Tags,
+		CreatedAt: dayOne.CreationDate,
+		UpdatedAt: dayOne.ModifiedDate,
+	}
+	if parsed, err := uuid.Parse(dayOne.UUID); err == nil {
+		entry.ID = parsed.String()
+	}
+	attendeesOriginal := ""
+	entry.AttendeesOriginal = &attendeesOriginal
+
+	// The entry belongs to the day it was written where it was written
+	if tz, err := time.LoadLocation(dayOne.TimeZone); err == nil && dayOne.TimeZone != "" {
+		loc = tz
+	}
+	created := dayOne.CreationDate.In(loc)
+	entry.Date.Year = int32(created.Year())
+	entry.Date.Month = int32(created.Month())
+	entry.Date.Day = int32(created.Day())
+
+	// Media are stored as attachments and Day One's references to them
+	// pointed at the attachments
+	urls := make(map[string]string)
+	media := func(folder string, items []dayOneMedia) {
+		for _, item := range items {
+			ext := item.Type
+			if ext == "" {
+				ext = item.Format
+			}
+			name := fmt.Sprintf("%s/%s.%s", folder, item.MD5, ext)
+			if _, err := fs.Stat(imp.fsys, name); err != nil {
+				if matches, _ := fs.Glob(imp.fsys, fmt.Sprintf("%s/%s.*", folder, item.MD5)); len(matches) > 0 {
+					name = matches[0]
+				}
+			}
+			attachment, ok := imp.documentAttachment(file, name)
+			if !ok {
+				continue
+			}
+			entry.Attachments = append(entry.Attachments, attachment)
+			urls[strings.ToLower(item.Identifier)] = "/api/attachments/" + attachment.ID
+		}
+	}
+	media("photos", dayOne.Photos)
+	media("videos", dayOne.Videos)
+	media("audios", dayOne.Audios)
+	media("pdfs", dayOne.PDFs)
+
+	// Day One has no titles: a leading heading, or else the first line, is
+	// the title
+	body := strings.TrimLeft(dayOne.Text, "\n")
+	if m := titleHeadingPattern.FindStringSubmatch(body); m != nil {
+		entry.Title = markdown.Unescape(strings.TrimSpace(m[1]))
+		body = body[len(m[0]):]
+	} else {
+		first, _, _ := strings.Cut(body, "\n")
+		entry.Title = strings.TrimSpace(deltaToText(markdown.Parse(first, nil).Delta()))
+	}
+	if utf8.RuneCountInString(entry.Title) > maxTitleLength {
+		entry.Title = string([]rune(entry.Title)[:maxTitleLength-1]) + "…"
+	}
+
+	body = dayOneMediaPattern.ReplaceAllString(body, "[$1]($2)")
+	parsed := markdown.Parse(body, func(link string) string {
+		m := dayOneMomentPattern.FindStringSubmatch(link)
+		if m == nil {
+			return link
+		}
+		if mapped, ok := urls[strings.ToLower(m[1])]; ok {
+			return mapped
+		}
+		return link
+	})
+	entry.BodyDelta = parsed.Delta()
+	entry.BodyHTML = parsed.HTML()
+	entry.BodyText = deltaToText(entry.BodyDelta)
+	return entry
+}
+
+// setEntryDate sets an entry's date from a match of datePattern or
+// folderDatePattern
+func setEntryDate(entry *exportedEntry, m []string) {
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	entry.Date.Year = int32(year)
+	entry.Date.Month = int32(month)
+	entry.Date.Day = int32(day)
+}
@@ -0,0 +1,965 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chrisbakker/journal/config"
+	"github.com/chrisbakker/journal/extract"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/imaging"
+	"github.com/chrisbakker/journal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxImportBytes caps the size of an uploaded export archive
+const maxImportBytes = 4 << 30
+
+// maxImportEntryBytes caps the size of one entry's JSON in an archive
+const maxImportEntryBytes = 64 << 20
+
+// errDryRun rolls back an entry imported in a dry run
+var errDryRun = errors.New("dry run")
+
+// importError stops an import before it changes anything, with the status
+// to respond with
+type importError struct {
+	status int
+	err    error
+}
+
+func (e *importError) Error() string {
+	return e.err.Error()
+}
+
+// exportedSrcPattern matches the src attributes of images in exported HTML
+var exportedSrcPattern = regexp.MustCompile(`src="([^"]*)"`)
+
+// uuidPattern matches the entry and attachment IDs in an imported body that
+// may need remapping
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// ImportOptions controls an import
+type ImportOptions struct {
+	// Format is json for an export archive, markdown for a folder of
+	// Markdown files or dayone for a Day One JSON export
+	Format string
+	// DryRun reports what would happen without changing anything
+	DryRun bool
+	// Remap gives every entry and attachment a new ID rather than keeping
+	// the archive's where they are free
+	Remap bool
+	// Conflict is what happens to entries that already exist: skip,
+	// overwrite or duplicate
+	Conflict string
+	// Type is the entry type of Markdown and Day One entries that don't name
+	// one
+	Type string
+}
+
+// ImportReport describes what an import did, or would do in a dry run
+type ImportReport struct {
+	Format        string          `json:"format"`
+	DryRun        bool            `json:"dry_run"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Created       int             `json:"created"`
+	Overwritten   int             `json:"overwritten"`
+	Skipped       int             `json:"skipped"`
+	Failed        int             `json:"failed"`
+	Attachments   int             `json:"attachments"`
+	Entries       []ImportedEntry `json:"entries"`
+	Warnings      []string        `json:"warnings"`
+}
+
+// ImportedEntry is the outcome for one entry of an import. NewID is set when
+// the entry was given a different ID from the one in the archive.
+type ImportedEntry struct {
+	File        string `json:"file"`
+	ID          string `json:"id"`
+	NewID       string `json:"new_id,omitempty"`
+	Title       string `json:"title"`
+	Action      string `json:"action"` // created, overwritten, skipped or failed
+	Attachments int    `json:"attachments"`
+	Error       string `json:"error,omitempty"`
+}
+
+// exportedEntry is an entry file of an export archive. Version 1 archives
+// have no body_text, attendees_original, archived or attachments.
+type exportedEntry struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	BodyHTML  string `json:"body_html"`
+	BodyDelta []byte `json:"body_delta"`
+	BodyText  string `json:"body_text"`
+	Type      string `json:"type"`
+	Date      struct {
+		Year  int32 `json:"year"`
+		Month int32 `json:"month"`
+		Day   int32 `json:"day"`
+	} `json:"date"`
+	Attendees         []string             `json:"attendees"`
+	AttendeesOriginal *string              `json:"attendees_original"`
+	Archived          bool                 `json:"archived"`
+	Tags              []string             `json:"tags"`
+	Attachments       []exportedAttachment `json:"attachments"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+type exportedAttachment struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// importPlan is what an import will do with one entry of the archive
+type importPlan struct {
+	file   string
+	entry  exportedEntry
+	id     pgtype.UUID
+	action string // created, overwritten or skipped
+	report int    // index in ImportReport.Entries
+}
+
+// importer restores entries from an export archive, or imports them from
+// Markdown or Day One, for one user
+type importer struct {
+	h        *Handler
+	userID   pgtype.UUID
+	cfg      config.AttachmentsConfig
+	fsys     fs.FS
+	manifest map[string]string // path to hex SHA-256; nil for version 1 archives and other formats
+	opts     ImportOptions
+	report   ImportReport
+
+	// ids maps IDs in the archive to the IDs they are imported as, and
+	// entryFiles maps entry file names to imported entry IDs, for rewriting
+	// references between entries and to attachments
+	ids        map[string]string
+	entryFiles map[string]string
+}
+
+// ImportEntries imports entries from a zip uploaded as the "file" form
+// field: by default one made by ExportEntries, restoring its entries, or
+// with format=markdown a folder of Markdown files, or with format=dayone a
+// Day One JSON export. Query parameters:
+//
+//	format=json        an export archive (the default), markdown or dayone
+//	dry_run=true       report what would happen without changing anything
+//	ids=preserve       keep entry and attachment IDs where free (the default),
+//	ids=remap          or give everything new IDs
+//	conflict=skip      leave entries that already exist alone (the default),
+//	conflict=overwrite replace them with the archive's version,
+//	conflict=duplicate or import them again under new IDs
+//	type=notes         entry type of Markdown and Day One entries without one
+//
+// Entries that fail, such as on a checksum mismatch, are reported and the
+// rest are still imported.
+func (h *Handler) ImportEntries(c *gin.Context, cfg config.AttachmentsConfig) {
+	opts := ImportOptions{
+		Format:   c.DefaultQuery("format", "json"),
+		DryRun:   c.Query("dry_run") == "true",
+		Conflict: c.DefaultQuery("conflict", "skip"),
+		Type:     c.DefaultQuery("type", "notes"),
+	}
+	switch c.DefaultQuery("ids", "preserve") {
+	case "preserve":
+	case "remap":
+		opts.Remap = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must be preserve or remap"})
+		return
+	}
+	if err := opts.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// archive/zip needs random access, so spool the upload to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes+multipartOverhead)
+	part, err := nextFilePart(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file provided"})
+		return
+	}
+	defer part.Close()
+
+	upload, err := storage.Spool(part, maxImportBytes)
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, storage.ErrTooLarge) || errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("archive exceeds the %d GB import limit", maxImportBytes>>30)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer upload.Close()
+
+	archive, err := zip.NewReader(upload, upload.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is not a zip archive"})
+		return
+	}
+
+	report, err := h.Import(c.Request.Context(), h.getDefaultUserID(c), archive, opts, cfg)
+	var importErr *importError
+	if errors.As(err, &importErr) {
+		c.JSON(importErr.status, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Import imports the entries in fsys, an unpacked archive, for a user.
+// Errors that stop the import before it starts are returned; failures of
+// single entries are in the report.
+func (h *Handler) Import(ctx context.Context, userID pgtype.UUID, fsys fs.FS, opts ImportOptions, cfg config.AttachmentsConfig) (*ImportReport, error) {
+	if err := opts.validate(); err != nil {
+		return nil, &importError{http.StatusBadRequest, err}
+	}
+	imp := &importer{
+		h:      h,
+		userID: userID,
+		cfg:    cfg,
+		fsys:   fsys,
+		opts:   opts,
+		report: ImportReport{
+			Format:   opts.Format,
+			DryRun:   opts.DryRun,
+			Entries:  []ImportedEntry{},
+			Warnings: []string{},
+		},
+		ids:        make(map[string]string),
+		entryFiles: make(map[string]string),
+	}
+
+	var plans []importPlan
+	var err error
+	switch opts.Format {
+	case "json":
+		if err := imp.readMetadata(); err != nil {
+			return nil, &importError{http.StatusBadRequest, err}
+		}
+		plans, err = imp.plan(ctx)
+	case "markdown":
+		plans, err = imp.planMarkdown(ctx)
+	case "dayone":
+		plans, err = imp.planDayOne(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.QuotaMB >= 0 {
+		used, err := h.queries.GetAttachmentUsage(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if total := imp.attachmentBytes(plans); used+total > int64(cfg.QuotaMB)<<20 {
+			return nil, &importError{http.StatusInsufficientStorage, fmt.Errorf("importing %d MB of attachments would exceed the %d MB quota", total>>20, cfg.QuotaMB)}
+		}
+	}
+
+	for i := range plans {
+		imp.apply(ctx, &plans[i])
+	}
+	if !opts.DryRun {
+		imp.syncLinks(ctx, plans)
+	}
+	return &imp.report, nil
+}
+
+// validate checks the options name a known format and conflict handling
+func (opts ImportOptions) validate() error {
+	switch opts.Format {
+	case "json", "markdown", "dayone":
+	default:
+		return errors.New("format must be json, markdown or dayone")
+	}
+	switch opts.Conflict {
+	case "skip", "overwrite", "duplicate":
+	default:
+		return errors.New("conflict must be skip, overwrite or duplicate")
+	}
+	if opts.Format != "json" && normalizeEntryTypeName(opts.Type) == "" {
+		return errors.New("type is required")
+	}
+	return nil
+}
+
+// readMetadata checks the archive is an export this version can read, and
+// loads its manifest
+func (imp *importer) readMetadata() error {
+	data, err := readImportFile(imp.fsys, "metadata.json", maxImportEntryBytes)
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.New("archive has no metadata.json; is it a journal export?")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metadata.json: %w", err)
+	}
+	var metadata struct {
+		ExportFormat  string `json:"export_format"`
+		SchemaVersion int    `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("invalid metadata.json: %w", err)
+	}
+	if metadata.ExportFormat == "markdown" {
+		return errors.New("this is a Markdown export; import it with format=markdown")
+	}
+	if metadata.ExportFormat != "json" {
+		return fmt.Errorf("cannot import %q exports", metadata.ExportFormat)
+	}
+
+	// Version 1 exports predate schema_version
+	imp.report.SchemaVersion = max(metadata.SchemaVersion, 1)
+	if imp.report.SchemaVersion > exportSchemaVersion {
+		return fmt.Errorf("archive schema version %d is newer than this server supports (%d)", imp.report.SchemaVersion, exportSchemaVersion)
+	}
+
+	r, err := imp.fsys.Open("manifest.sha256")
+	if errors.Is(err, fs.ErrNotExist) {
+		if imp.report.SchemaVersion >= 2 {
+			return errors.New("archive has no manifest.sha256")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest.sha256: %w", err)
+	}
+	defer r.Close()
+
+	imp.manifest = make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if ok {
+			imp.manifest[name] = strings.ToLower(sum)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read manifest.sha256: %w", err)
+	}
+	return imp.verifyChecksum("metadata.json", sha256Hex(data))
+}
+
+// plan reads every entry in the archive and decides what to do with it,
+// assigning IDs so that references between entries can be rewritten before
+// any of them is stored
+func (imp *importer) plan(ctx context.Context) ([]importPlan, error) {
+	files, err := fs.ReadDir(imp.fsys, ".")
+	if err != nil {
+		return nil, &importError{http.StatusBadRequest, err}
+	}
+
+	var plans []importPlan
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || path.Ext(name) != ".json" || name == "metadata.json" {
+			continue
+		}
+		result := ImportedEntry{File: name}
+
+		var entry exportedEntry
+		data, err := readImportFile(imp.fsys, name, maxImportEntryBytes)
+		if err == nil {
+			err = imp.verifyChecksum(name, sha256Hex(data))
+		}
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err == nil {
+			err = validateExportedEntry(entry)
+		}
+		result.ID = entry.ID
+		result.Title = entry.Title
+		if err != nil {
+			imp.fail(&result, err)
+			imp.report.Entries = append(imp.report.Entries, result)
+			continue
+		}
+
+		id, action, err := imp.resolveEntryID(ctx, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		imp.entryFiles[name] = id.String()
+		plans = append(plans, imp.addPlan(result, entry, id, action))
+	}
+	return plans, nil
+}
+
+// addPlan records the ID an entry is imported as and adds it to the report
+func (imp *importer) addPlan(result ImportedEntry, entry exportedEntry, id pgtype.UUID, action string) importPlan {
+	if entry.ID != "" {
+		imp.ids[strings.ToLower(entry.ID)] = id.String()
+	}
+	if id.String() != strings.ToLower(entry.ID) {
+		result.NewID = id.String()
+	}
+	result.ID = entry.ID
+	result.Title = entry.Title
+	result.Action = action
+	imp.report.Entries = append(imp.report.Entries, result)
+	return importPlan{
+		file:   result.File,
+		entry:  entry,
+		id:     id,
+		action: action,
+		report: len(imp.report.Entries) - 1,
+	}
+}
+
+// resolveEntryID picks the ID an entry is imported as and whether it is
+// created, overwrites the existing entry or is skipped. An ID belonging to
+// another user, or repeated within the archive, is always replaced.
+func (imp *importer) resolveEntryID(ctx context.Context, archived string) (pgtype.UUID, string, error) {
+	fresh := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	parsed, err := uuid.Parse(archived)
+	if err != nil || imp.opts.Remap {
+		return fresh, "created", nil
+	}
+	id := pgtype.UUID{Bytes: parsed, Valid: true}
+	if _, seen := imp.ids[id.String()]; seen {
+		return fresh, "created", nil
+	}
+
+	existing, err := imp.h.queries.GetEntryForImport(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return id, "created", nil
+	}
+	if err != nil {
+		return id, "", fmt.Errorf("failed to look up entry %s: %w", archived, err)
+	}
+	if existing.UserID != imp.userID {
+		return fresh, "created", nil
+	}
+
+	switch imp.opts.Conflict {
+	case "overwrite":
+		return id, "overwritten", nil
+	case "duplicate":
+		return fresh, "created", nil
+	default:
+		return id, "skipped", nil
+	}
+}
+
+// attachmentBytes totals the size of the attachments an import will store.
+// Sizes come from the files themselves, not what the archive says about
+// them; files that are missing are left out, as they will be skipped.
+func (imp *importer) attachmentBytes(plans []importPlan) int64 {
+	var total int64
+	for _, plan := range plans {
+		if plan.action == "skipped" {
+			continue
+		}
+		for _, attachment := range plan.entry.Attachments {
+			if attachment.Path == "" {
+				continue
+			}
+			if info, err := fs.Stat(imp.fsys, attachment.Path); err == nil && !info.IsDir() {
+				total += info.Size()
+			}
+		}
+	}
+	return total
+}
+
+// restoredAttachment is an attachment whose contents have been stored (or,
+// in a dry run, verified) and which is ready to be inserted
+type restoredAttachment struct {
+	params db.ImportAttachmentParams
+	upload *storage.Upload
+}
+
+// apply imports one planned entry with its attachments, recording the
+// outcome in the report
+func (imp *importer) apply(ctx context.Context, plan *importPlan) {
+	result := &imp.report.Entries[plan.report]
+	if plan.action == "skipped" {
+		imp.report.Skipped++
+		return
+	}
+
+	store, err := storage.New(imp.cfg.Storage, imp.h.queries)
+	if err != nil {
+		imp.fail(result, err)
+		return
+	}
+
+	// Contents are stored before the entry's transaction, as on upload, and
+	// released again if it fails
+	var restored []restoredAttachment
+	defer func() {
+		for _, attachment := range restored {
+			if attachment.upload != nil {
+				attachment.upload.Close()
+			}
+		}
+	}()
+	release := func() {
+		if imp.opts.DryRun {
+			return
+		}
+		for _, attachment := range restored {
+			imp.h.releaseContents(ctx, attachment.params.StorageBackend, attachment.params.StorageKey, imp.cfg.Storage)
+		}
+	}
+
+	paths := make(map[string]string)
+	for _, exported := range plan.entry.Attachments {
+		attachment, err := imp.restoreAttachment(ctx, store, plan, exported)
+		if err != nil {
+			release()
+			imp.fail(result, err)
+			return
+		}
+		if attachment == nil {
+			continue
+		}
+		restored = append(restored, *attachment)
+		paths[exported.Path] = attachment.params.ID.String()
+	}
+
+	params := imp.entryParams(plan, paths)
+	var unused []db.DeleteBlobParams
+	var entry db.Entry
+	err = imp.h.entries.WithTx(ctx, func(q *db.Queries) error {
+		if err := imp.ensureEntryType(ctx, q, params.Type, len(plan.entry.Attendees) > 0); err != nil {
+			return err
+		}
+		params.Attendees = resolveAttendees(ctx, q, imp.userID, params.AttendeesOriginal)
+
+		var previous []string
+		if plan.action == "overwritten" {
+			existing, err := q.GetEntryForImport(ctx, plan.id)
+			if err != nil {
+				return err
+			}
+			previous = existing.Attendees
+
+			// The archive's attachments replace the entry's current ones
+			current, err := q.ListAttachmentsForEntry(ctx, plan.id)
+			if err != nil {
+				return err
+			}
+			for _, attachment := range current {
+				released, err := removeAttachmentRows(ctx, q, attachment)
+				if err != nil {
+					return err
+				}
+				unused = append(unused, released...)
+			}
+
+			entry, err = q.RestoreEntry(ctx, db.RestoreEntryParams{
+				ID:                params.ID,
+				Title:             params.Title,
+				BodyDelta:         params.BodyDelta,
+				BodyHtml:          params.BodyHtml,
+				BodyText:          params.BodyText,
+				AttendeesOriginal: params.AttendeesOriginal,
+				Attendees:         params.Attendees,
+				Type:              params.Type,
+				DayYear:           params.DayYear,
+				DayMonth:          params.DayMonth,
+				DayDay:            params.DayDay,
+				Archived:          params.Archived,
+				CreatedAt:         params.CreatedAt,
+				UpdatedAt:         params.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			var err error
+			entry, err = q.ImportEntry(ctx, params)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := recordAttendees(ctx, q, entry.UserID, entry.Attendees, previous); err != nil {
+			return err
+		}
+		if err := syncEntryTasks(ctx, q, entry); err != nil {
+			return err
+		}
+		if err := setEntryTags(ctx, q, entry.UserID, entry.ID, normalizeTags(plan.entry.Tags)); err != nil {
+			return err
+		}
+		for _, attachment := range restored {
+			// A dry run stored no contents, so it counts their use here, to be
+			// rolled back with the rest
+			if imp.opts.DryRun {
+				if err := storage.Acquire(ctx, q, attachment.params.StorageBackend, attachment.params.StorageKey, attachment.params.SizeBytes); err != nil {
+					return err
+				}
+			}
+			if _, err := q.ImportAttachment(ctx, attachment.params); err != nil {
+				return fmt.Errorf("failed to restore attachment %s: %w", attachment.params.Filename, err)
+			}
+		}
+
+		if imp.opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		release()
+		imp.fail(result, err)
+		return
+	}
+
+	result.Attachments = len(restored)
+	imp.report.Attachments += len(restored)
+	if plan.action == "overwritten" {
+		imp.report.Overwritten++
+	} else {
+		imp.report.Created++
+	}
+	if imp.opts.DryRun {
+		return
+	}
+
+	imp.h.purgeContents(ctx, unused, imp.cfg.Storage)
+	for _, attachment := range restored {
+		imp.postProcess(ctx, store, attachment)
+	}
+}
+
+// restoreAttachment verifies an attachment's contents in the archive and
+// stores them, returning nil if the attachment is to be left out
+func (imp *importer) restoreAttachment(ctx context.Context, store storage.Store, plan *importPlan, exported exportedAttachment) (*restoredAttachment, error) {
+	warn := func(reason string) (*restoredAttachment, error) {
+		imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf("%s: skipped attachment %s: %s", plan.file, exported.Filename, reason))
+		return nil, nil
+	}
+	if exported.Path == "" {
+		return warn("its contents were not exported")
+	}
+	info, err := fs.Stat(imp.fsys, exported.Path)
+	if err != nil || info.IsDir() {
+		return warn("missing from the archive")
+	}
+	if info.Size() > int64(imp.cfg.MaxUploadMB)<<20 {
+		return warn(fmt.Sprintf("exceeds the %d MB upload limit", imp.cfg.MaxUploadMB))
+	}
+	if info.Size() != exported.SizeBytes {
+		return nil, fmt.Errorf("size mismatch for %s: the archive says %d bytes but the file has %d", exported.Path, exported.SizeBytes, info.Size())
+	}
+	if !allowedContentType(exported.MimeType, imp.cfg.AllowedTypes) {
+		return warn(fmt.Sprintf("file type %s is not allowed", exported.MimeType))
+	}
+
+	f, err := imp.fsys.Open(exported.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// New files from other apps are treated as uploads; an export's are
+	// restored as they were
+	var r io.Reader = f
+	if imp.opts.Format != "json" && imp.cfg.StripGPS && exported.MimeType == "image/jpeg" {
+		r = imaging.StripGPS(r)
+	}
+
+	attachment := &restoredAttachment{
+		params: db.ImportAttachmentParams{
+			ID:             imp.attachmentID(ctx, plan, exported.ID),
+			UserID:         imp.userID,
+			EntryID:        plan.id,
+			Filename:       exported.Filename,
+			MimeType:       exported.MimeType,
+			StorageBackend: store.Name(),
+			CreatedAt:      importTimestamp(exported.CreatedAt),
+		},
+	}
+
+	// A dry run only checks the contents
+	if imp.opts.DryRun {
+		hash := sha256.New()
+		size, err := io.Copy(hash, r)
+		if err != nil {
+			return nil, err
+		}
+		attachment.params.SizeBytes = size
+		attachment.params.StorageKey = hex.EncodeToString(hash.Sum(nil))
+	} else {
+		upload, err := storage.Spool(r, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		attachment.upload = upload
+		attachment.params.SizeBytes = upload.Size
+		attachment.params.StorageKey = upload.Key
+	}
+
+	if err := imp.verifyChecksum(exported.Path, attachment.params.StorageKey); err != nil {
+		attachment.close()
+		return nil, err
+	}
+	if exported.SHA256 != "" && !strings.EqualFold(exported.SHA256, attachment.params.StorageKey) {
+		attachment.close()
+		return nil, fmt.Errorf("checksum mismatch for %s", exported.Path)
+	}
+	if imp.opts.DryRun {
+		return attachment, nil
+	}
+
+	if err := storage.Acquire(ctx, imp.h.queries, store.Name(), attachment.params.StorageKey, attachment.params.SizeBytes); err != nil {
+		attachment.close()
+		return nil, err
+	}
+	if err := store.Put(ctx, attachment.params.StorageKey, attachment.upload, attachment.params.SizeBytes); err != nil {
+		imp.h.releaseContents(ctx, store.Name(), attachment.params.StorageKey, imp.cfg.Storage)
+		attachment.close()
+		return nil, fmt.Errorf("failed to store %s: %w", exported.Filename, err)
+	}
+	return attachment, nil
+}
+
+func (a *restoredAttachment) close() {
+	if a.upload != nil {
+		a.upload.Close()
+		a.upload = nil
+	}
+}
+
+// attachmentID keeps an attachment's ID unless IDs are remapped or it is
+// taken by an attachment that isn't about to be replaced
+func (imp *importer) attachmentID(ctx context.Context, plan *importPlan, archived string) pgtype.UUID {
+	id := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	if parsed, err := uuid.Parse(archived); err == nil && !imp.opts.Remap {
+		existing, err := imp.h.queries.GetAttachment(ctx, pgtype.UUID{Bytes: parsed, Valid: true})
+		free := errors.Is(err, pgx.ErrNoRows) || (err == nil && plan.action == "overwritten" && existing.EntryID == plan.id)
+		if _, seen := imp.ids[strings.ToLower(archived)]; free && !seen {
+			id = pgtype.UUID{Bytes: parsed, Valid: true}
+		}
+	}
+	imp.ids[strings.ToLower(archived)] = id.String()
+	return id
+}
+
+// entryParams builds the entry to insert, pointing links to other entries
+// and attachments in its body at their imported IDs. The body HTML is
+// sanitized as if it had come from the editor.
+func (imp *importer) entryParams(plan *importPlan, attachmentPaths map[string]string) db.ImportEntryParams {
+	entry := plan.entry
+
+	// The export points links at files in the archive; point them back at
+	// the app
+	bodyHTML := hrefPattern.ReplaceAllStringFunc(entry.BodyHTML, func(match string) string {
+		href := html.UnescapeString(hrefPattern.FindStringSubmatch(match)[1])
+		if id, ok := imp.entryFiles[href]; ok {
+			return fmt.Sprintf(`href="/entries/%s"`, id)
+		}
+		if id, ok := attachmentPaths[href]; ok {
+			return fmt.Sprintf(`href="/api/attachments/%s"`, id)
+		}
+		return match
+	})
+	bodyHTML = exportedSrcPattern.ReplaceAllStringFunc(bodyHTML, func(match string) string {
+		src := html.UnescapeString(exportedSrcPattern.FindStringSubmatch(match)[1])
+		if id, ok := attachmentPaths[src]; ok {
+			return fmt.Sprintf(`src="/api/attachments/%s"`, id)
+		}
+		return match
+	})
+
+	attendeesOriginal := strings.Join(entry.Attendees, ", ")
+	if entry.AttendeesOriginal != nil {
+		attendeesOriginal = *entry.AttendeesOriginal
+	}
+	bodyText := entry.BodyText
+	if bodyText == "" {
+		bodyText = deltaToText(entry.BodyDelta)
+	}
+
+	return db.ImportEntryParams{
+		ID:                plan.id,
+		UserID:            imp.userID,
+		Title:             entry.Title,
+		BodyDelta:         []byte(imp.remapIDs(string(entry.BodyDelta))),
+		BodyHtml:          imp.h.sanitizer.Sanitize(imp.remapIDs(bodyHTML)),
+		BodyText:          bodyText,
+		AttendeesOriginal: attendeesOriginal,
+		Type:              normalizeEntryTypeName(entry.Type),
+		DayYear:           entry.Date.Year,
+		DayMonth:          entry.Date.Month,
+		DayDay:            entry.Date.Day,
+		Archived:          entry.Archived,
+		CreatedAt:         importTimestamp(entry.CreatedAt),
+		UpdatedAt:         importTimestamp(entry.UpdatedAt),
+	}
+}
+
+// remapIDs replaces the IDs of imported entries and attachments in s with
+// the IDs they were imported as
+func (imp *importer) remapIDs(s string) string {
+	return uuidPattern.ReplaceAllStringFunc(s, func(id string) string {
+		if mapped, ok := imp.ids[strings.ToLower(id)]; ok {
+			return mapped
+		}
+		return id
+	})
+}
+
+// ensureEntryType creates an entry type the archive uses that the user
+// doesn't have
+func (imp *importer) ensureEntryType(ctx context.Context, q *db.Queries, name string, hasAttendees bool) error {
+	_, err := q.GetEntryTypeByName(ctx, db.GetEntryTypeByNameParams{UserID: imp.userID, Name: name})
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err := validateEntryType(name, "", nil); err != nil {
+		return fmt.Errorf("entry type %q: %w", name, err)
+	}
+	_, err = q.CreateEntryType(ctx, db.CreateEntryTypeParams{
+		UserID:       imp.userID,
+		Name:         name,
+		HasAttendees: hasAttendees,
+	})
+	return err
+}
+
+// postProcess makes thumbnails and extracts text for a restored attachment,
+// as on upload
+func (imp *importer) postProcess(ctx context.Context, store storage.Store, restored restoredAttachment) {
+	attachment, err := imp.h.queries.GetAttachment(ctx, restored.params.ID)
+	if err != nil {
+		log.Printf("Error loading imported attachment %s: %v", restored.params.ID.String(), err)
+		return
+	}
+	if imaging.Supported(attachment.MimeType) {
+		if err := imp.h.createThumbnails(ctx, store, attachment, restored.upload, imp.cfg.Storage); err != nil {
+			log.Printf("Error creating thumbnails for attachment %s: %v", attachment.ID.String(), err)
+		}
+	}
+	if extract.Supported(attachment.MimeType) {
+		if err := imp.h.extractText(ctx, attachment, restored.upload); err != nil {
+			log.Printf("Error extracting text from attachment %s: %v", attachment.ID.String(), err)
+		}
+	}
+}
+
+// syncLinks records the links of the imported entries once they all exist,
+// so links to entries later in the archive resolve
+func (imp *importer) syncLinks(ctx context.Context, plans []importPlan) {
+	for _, plan := range plans {
+		result := &imp.report.Entries[plan.report]
+		if result.Action == "skipped" || result.Action == "failed" {
+			continue
+		}
+		err := imp.h.entries.WithTx(ctx, func(q *db.Queries) error {
+			entry, err := q.GetEntryForImport(ctx, plan.id)
+			if err != nil {
+				return err
+			}
+			return syncEntryLinks(ctx, q, entry)
+		})
+		if err != nil {
+			imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf("%s: failed to record links: %v", plan.file, err))
+		}
+	}
+}
+
+// fail marks an entry as not imported
+func (imp *importer) fail(result *ImportedEntry, err error) {
+	result.Action = "failed"
+	result.Error = err.Error()
+	imp.report.Failed++
+}
+
+// validateExportedEntry checks the fields an entry needs to be stored
+func validateExportedEntry(entry exportedEntry) error {
+	if entry.Date.Year < 1 || entry.Date.Month < 1 || entry.Date.Month > 12 || entry.Date.Day < 1 ||
+		int(entry.Date.Day) > time.Date(int(entry.Date.Year), time.Month(entry.Date.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return fmt.Errorf("invalid date %04d-%02d-%02d", entry.Date.Year, entry.Date.Month, entry.Date.Day)
+	}
+	if !json.Valid(entry.BodyDelta) {
+		return errors.New("body_delta is not valid JSON")
+	}
+	if normalizeEntryTypeName(entry.Type) == "" {
+		return errors.New("entry has no type")
+	}
+	return nil
+}
+
+// verifyChecksum checks a file's hex SHA-256 against the manifest. Version 1
+// archives, which have no manifest, pass.
+func (imp *importer) verifyChecksum(name, sum string) error {
+	if imp.manifest == nil {
+		return nil
+	}
+	want, ok := imp.manifest[name]
+	if !ok {
+		return fmt.Errorf("%s is not in the manifest", name)
+	}
+	if !strings.EqualFold(sum, want) {
+		return fmt.Errorf("checksum mismatch for %s", name)
+	}
+	return nil
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readImportFile reads a file being imported, failing if it is larger than
+// limit
+func readImportFile(fsys fs.FS, name string, limit int64) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return io.ReadAll(io.LimitReader(f, limit))
+}
+
+// importTimestamp converts an exported time, using now if it is missing
+func importTimestamp(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
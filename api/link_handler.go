@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// [[Title]] or [[Title|label]]
+	wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]+))?\]\]`)
+	// A link to another entry: entry:<id>, /entries/<id> or #/entries/<id>
+	entryHrefPattern = regexp.MustCompile(`^(?:entry:|(?:[^"]*/)?entries/)([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+	hrefPattern      = regexp.MustCompile(`href="([^"]*)"`)
+)
+
+// entryLinkRef is a link found in an entry body, either by title or by ID
+type entryLinkRef struct {
+	title   string
+	entryID uuid.UUID
+}
+
+// GetBacklinks lists the entries that link to an entry
+func (h *Handler) GetBacklinks(c *gin.Context) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	entry, err := h.queries.GetEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	if err != nil || entry.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	entries, err := h.queries.ListBacklinks(c.Request.Context(), db.ListBacklinksParams{
+		TargetEntryID: entry.ID,
+		UserID:        userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list backlinks"})
+		return
+	}
+
+	response, err := h.entriesToResponse(c.Request.Context(), entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// syncEntryLinks replaces the stored outgoing links of an entry, moves
+// [[Title]] links elsewhere off it if it no longer has their title, and
+// resolves dangling ones that point at its title
+func syncEntryLinks(ctx context.Context, q *db.Queries, entry db.Entry) error {
+	if err := q.DeleteEntryLinks(ctx, entry.ID); err != nil {
+		return fmt.Errorf("failed to clear entry links: %w", err)
+	}
+
+	for _, ref := range parseEntryLinks(entry) {
+		params := db.CreateEntryLinkParams{
+			UserID:        entry.UserID,
+			SourceEntryID: entry.ID,
+			TargetTitle:   ref.title,
+		}
+
+		if ref.title != "" {
+			// Unresolved titles are kept and resolved once an entry takes that title
+			targetID, err := q.ResolveEntryTitle(ctx, db.ResolveEntryTitleParams{
+				UserID:        entry.UserID,
+				Title:         ref.title,
+				SourceEntryID: entry.ID,
+			})
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("failed to resolve link %q: %w", ref.title, err)
+			}
+			params.TargetEntryID = targetID
+		} else {
+			target, err := q.GetEntry(ctx, pgtype.UUID{Bytes: ref.entryID, Valid: true})
+			if err != nil || target.UserID != entry.UserID || target.ID == entry.ID {
+				continue
+			}
+			params.TargetEntryID = target.ID
+		}
+
+		if err := q.CreateEntryLink(ctx, params); err != nil {
+			return fmt.Errorf("failed to save entry link: %w", err)
+		}
+	}
+
+	if err := q.RebindTitleLinks(ctx, entry.ID); err != nil {
+		return fmt.Errorf("failed to rebind links to entry: %w", err)
+	}
+	if strings.TrimSpace(entry.Title) == "" {
+		return nil
+	}
+	return q.ResolveDanglingLinks(ctx, db.ResolveDanglingLinksParams{
+		UserID:        entry.UserID,
+		TargetEntryID: entry.ID,
+		Title:         strings.TrimSpace(entry.Title),
+	})
+}
+
+// parseEntryLinks finds [[Title]] links in the entry's text and links to
+// other entries' URLs in its delta and HTML
+func parseEntryLinks(entry db.Entry) []entryLinkRef {
+	var refs []entryLinkRef
+	seen := make(map[string]bool)
+	add := func(ref entryLinkRef) {
+		key := strings.ToLower(ref.title)
+		if ref.title == "" {
+			key = ref.entryID.String()
+		}
+		if !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	text := deltaToText(entry.BodyDelta)
+	if text == "" {
+		text = entry.BodyText
+	}
+	for _, m := range wikiLinkPattern.FindAllStringSubmatch(text, -1) {
+		if title := strings.TrimSpace(m[1]); title != "" {
+			add(entryLinkRef{title: title})
+		}
+	}
+
+	var hrefs []string
+	if _, ops, err := decodeDeltaOps(entry.BodyDelta); err == nil {
+		for _, op := range ops {
+			var attrs struct {
+				Link string `json:"link"`
+			}
+			if raw, ok := op["attributes"]; ok && json.Unmarshal(raw, &attrs) == nil && attrs.Link != "" {
+				hrefs = append(hrefs, attrs.Link)
+			}
+		}
+	}
+	for _, m := range hrefPattern.FindAllStringSubmatch(entry.BodyHtml, -1) {
+		hrefs = append(hrefs, m[1])
+	}
+	for _, href := range hrefs {
+		if id, ok := entryIDFromHref(href); ok {
+			add(entryLinkRef{entryID: id})
+		}
+	}
+
+	return refs
+}
+
+// entryIDFromHref extracts the entry ID from an in-app entry link
+func entryIDFromHref(href string) (uuid.UUID, bool) {
+	m := entryHrefPattern.FindStringSubmatch(href)
+	if m == nil {
+		return uuid.UUID{}, false
+	}
+	id, err := uuid.Parse(m[1])
+	return id, err == nil
+}
@@ -0,0 +1,559 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PersonRequest represents a partial update of a person profile
+type PersonRequest struct {
+	Email   *string   `json:"email,omitempty"`
+	Role    *string   `json:"role,omitempty"`
+	Notes   *string   `json:"notes,omitempty"`
+	Aliases *[]string `json:"aliases,omitempty"`
+}
+
+// PersonResponse represents a person built from the attendees table
+type PersonResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Notes     string    `json:"notes"`
+	Aliases   []string  `json:"aliases"`
+	UseCount  int32     `json:"use_count"`
+	LastUsed  time.Time `json:"last_used"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PersonDetailResponse is a person with the entries they attended
+type PersonDetailResponse struct {
+	PersonResponse
+	MeetingCount       int             `json:"meeting_count"`
+	FirstMeeting       string          `json:"first_meeting,omitempty"`
+	LastMeeting        string          `json:"last_meeting,omitempty"`
+	AverageDaysBetween float64         `json:"average_days_between,omitempty"`
+	Frequency          []MonthCount    `json:"frequency"`
+	Entries            []EntryResponse `json:"entries"`
+}
+
+// MonthCount is the number of meetings in a month (YYYY-MM)
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// ListPeople lists everyone who has attended an entry
+func (h *Handler) ListPeople(c *gin.Context) {
+	people, err := h.queries.ListPeople(c.Request.Context(), h.getDefaultUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list people"})
+		return
+	}
+
+	response := make([]PersonResponse, len(people))
+	for i, person := range people {
+		response[i] = personToResponse(person)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPerson returns a person's profile, every entry they attended (matched by
+// name or alias), their monthly meeting frequency and last meeting date
+func (h *Handler) GetPerson(c *gin.Context) {
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	person, err := h.queries.GetPerson(c.Request.Context(), db.GetPersonParams{
+		ID:     pgtype.UUID{Bytes: personID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+		return
+	}
+
+	entries, err := h.queries.ListEntriesForPerson(c.Request.Context(), db.ListEntriesForPersonParams{
+		UserID: userID,
+		Names:  personNames(person),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list entries"})
+		return
+	}
+
+	entryResponses, err := h.entriesToResponse(c.Request.Context(), entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := PersonDetailResponse{
+		PersonResponse: personToResponse(person),
+		MeetingCount:   len(entries),
+		Frequency:      []MonthCount{},
+		Entries:        entryResponses,
+	}
+
+	// Entries are newest first
+	if len(entries) > 0 {
+		last := entryDate(entries[0])
+		first := entryDate(entries[len(entries)-1])
+		response.LastMeeting = last.Format("2006-01-02")
+		response.FirstMeeting = first.Format("2006-01-02")
+		if len(entries) > 1 {
+			response.AverageDaysBetween = last.Sub(first).Hours() / 24 / float64(len(entries)-1)
+		}
+		response.Frequency = monthlyFrequency(entries, h.userToday(c.Request.Context(), userID))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdatePerson updates a person's email, role, notes and aliases
+func (h *Handler) UpdatePerson(c *gin.Context) {
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		return
+	}
+
+	var req PersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetPerson(c.Request.Context(), db.GetPersonParams{
+		ID:     pgtype.UUID{Bytes: personID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+		return
+	}
+
+	params := db.UpdatePersonParams{
+		ID:      existing.ID,
+		UserID:  userID,
+		Email:   existing.Email,
+		Role:    existing.Role,
+		Notes:   existing.Notes,
+		Aliases: existing.Aliases,
+	}
+	if req.Email != nil {
+		params.Email = strings.TrimSpace(*req.Email)
+		if params.Email != "" {
+			if _, err := mail.ParseAddress(params.Email); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email address"})
+				return
+			}
+		}
+	}
+	if req.Role != nil {
+		params.Role = strings.TrimSpace(*req.Role)
+	}
+	if req.Notes != nil {
+		params.Notes = *req.Notes
+	}
+	if req.Aliases != nil {
+		params.Aliases = normalizeAliases(existing.Name, *req.Aliases)
+	}
+
+	person, err := h.queries.UpdatePerson(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, personToResponse(person))
+}
+
+// personNames returns the lowercased name and aliases a person is matched by
+func personNames(person db.Attendee) []string {
+	names := []string{strings.ToLower(person.Name)}
+	for _, alias := range person.Aliases {
+		names = append(names, strings.ToLower(alias))
+	}
+	return names
+}
+
+// normalizeAliases trims and dedupes aliases, dropping the person's own name
+func normalizeAliases(name string, aliases []string) []string {
+	seen := map[string]bool{strings.ToLower(name): true}
+	normalized := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if alias == "" || seen[strings.ToLower(alias)] {
+			continue
+		}
+		seen[strings.ToLower(alias)] = true
+		normalized = append(normalized, alias)
+	}
+	return normalized
+}
+
+// monthlyFrequency counts entries per month from the first entry's month
+// through the current month, including months without meetings
+func monthlyFrequency(entries []db.Entry, today time.Time) []MonthCount {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[fmt.Sprintf("%04d-%02d", entry.DayYear, entry.DayMonth)]++
+	}
+
+	first := entries[len(entries)-1]
+	month := time.Date(int(first.DayYear), time.Month(first.DayMonth), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if last := entryDate(entries[0]); last.After(end) {
+		end = time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var frequency []MonthCount
+	for ; !month.After(end); month = month.AddDate(0, 1, 0) {
+		key := month.Format("2006-01")
+		frequency = append(frequency, MonthCount{Month: key, Count: counts[key]})
+	}
+	return frequency
+}
+
+func entryDate(entry db.Entry) time.Time {
+	return time.Date(int(entry.DayYear), time.Month(entry.DayMonth), int(entry.DayDay), 0, 0, 0, 0, time.UTC)
+}
+
+func personToResponse(person db.Attendee) PersonResponse {
+	aliases := person.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return PersonResponse{
+		ID:        person.ID.String(),
+		Name:      person.Name,
+		Email:     person.Email,
+		Role:      person.Role,
+		Notes:     person.Notes,
+		Aliases:   aliases,
+		UseCount:  person.UseCount,
+		LastUsed:  person.LastUsed.Time,
+		CreatedAt: person.CreatedAt.Time,
+		UpdatedAt: person.UpdatedAt.Time,
+	}
+}
+
+// MergePeopleRequest lists the people to fold into the target person
+type MergePeopleRequest struct {
+	SourceIDs []string `json:"source_ids" binding:"required"`
+}
+
+// RenamePersonRequest represents a rename of a person
+type RenamePersonRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergePeople merges other people into this one. Entries naming any source are
+// rewritten to the target, use counts are combined, source names become
+// aliases and the sources are deleted, all in one transaction.
+func (h *Handler) MergePeople(c *gin.Context) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		return
+	}
+
+	var req MergePeopleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sourceIDs := make([]pgtype.UUID, 0, len(req.SourceIDs))
+	seen := make(map[uuid.UUID]bool, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID: " + id})
+			return
+		}
+		// Merging the target into itself would delete it
+		if parsed == targetID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot merge a person into themselves"})
+			return
+		}
+		// A repeated source is already deleted the second time round
+		if seen[parsed] {
+			continue
+		}
+		seen[parsed] = true
+		sourceIDs = append(sourceIDs, pgtype.UUID{Bytes: parsed, Valid: true})
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	var merged db.Attendee
+	var notFound bool
+	err = h.entries.WithTx(c.Request.Context(), func(q *db.Queries) error {
+		target, err := q.GetPerson(c.Request.Context(), db.GetPersonParams{
+			ID:     pgtype.UUID{Bytes: targetID, Valid: true},
+			UserID: userID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			notFound = true
+		}
+		if err != nil {
+			return err
+		}
+
+		params := db.MergeAttendeeParams{
+			ID:       target.ID,
+			UserID:   userID,
+			Email:    target.Email,
+			Role:     target.Role,
+			Notes:    target.Notes,
+			Aliases:  target.Aliases,
+			UseCount: target.UseCount,
+			LastUsed: target.LastUsed,
+		}
+
+		for _, sourceID := range sourceIDs {
+			source, err := q.GetPerson(c.Request.Context(), db.GetPersonParams{ID: sourceID, UserID: userID})
+			if errors.Is(err, pgx.ErrNoRows) {
+				notFound = true
+			}
+			if err != nil {
+				return err
+			}
+
+			if _, err := rewriteEntryAttendees(c.Request.Context(), q, userID, personNames(source), target.Name); err != nil {
+				return err
+			}
+
+			params.Aliases = normalizeAliases(target.Name, append(append(params.Aliases, source.Name), source.Aliases...))
+			if source.LastUsed.Time.After(params.LastUsed.Time) {
+				params.LastUsed = source.LastUsed
+			}
+			if params.Email == "" {
+				params.Email = source.Email
+			}
+			if params.Role == "" {
+				params.Role = source.Role
+			}
+			if source.Notes != "" && source.Notes != params.Notes {
+				params.Notes = strings.TrimSpace(params.Notes + "\n\n" + source.Notes)
+			}
+
+			if err := q.DeleteAttendee(c.Request.Context(), db.DeleteAttendeeParams{ID: source.ID, UserID: userID}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.MergeAttendee(c.Request.Context(), params); err != nil {
+			return err
+		}
+
+		// Entries naming several of the merged people count once
+		if err := q.RecountAttendees(c.Request.Context(), db.RecountAttendeesParams{
+			UserID: userID,
+			Names:  []string{strings.ToLower(target.Name)},
+		}); err != nil {
+			return err
+		}
+		merged, err = q.GetPerson(c.Request.Context(), db.GetPersonParams{ID: target.ID, UserID: userID})
+		return err
+	})
+	if notFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, personToResponse(merged))
+}
+
+// RenamePerson renames a person, rewriting every entry they attended. The old
+// name becomes an alias so future entries using it are normalized.
+func (h *Handler) RenamePerson(c *gin.Context) {
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid person ID"})
+		return
+	}
+
+	var req RenamePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" || strings.Contains(name, ",") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be non-empty and cannot contain commas"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	person, err := h.queries.GetPerson(c.Request.Context(), db.GetPersonParams{
+		ID:     pgtype.UUID{Bytes: personID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "person not found"})
+		return
+	}
+
+	existing, err := h.queries.GetAttendeeByName(c.Request.Context(), db.GetAttendeeByNameParams{UserID: userID, Name: name})
+	if err == nil && existing.ID != person.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%q already exists; merge instead", existing.Name), "id": existing.ID.String()})
+		return
+	}
+
+	var renamed db.Attendee
+	err = h.entries.WithTx(c.Request.Context(), func(q *db.Queries) error {
+		if _, err := rewriteEntryAttendees(c.Request.Context(), q, userID, personNames(person), name); err != nil {
+			return err
+		}
+		renamed, err = q.RenameAttendee(c.Request.Context(), db.RenameAttendeeParams{
+			ID:      person.ID,
+			UserID:  userID,
+			Name:    name,
+			Aliases: normalizeAliases(name, append(person.Aliases, person.Name)),
+		})
+		return err
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%q already exists; merge instead", name)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, personToResponse(renamed))
+}
+
+// rewriteEntryAttendees replaces any of names (lowercased) with to in the
+// attendees of every entry, archived ones included, marking rewritten entries
+// for re-embedding and re-deriving their task owners. It returns the number
+// of entries changed.
+func rewriteEntryAttendees(ctx context.Context, q *db.Queries, userID pgtype.UUID, names []string, to string) (int, error) {
+	entries, err := q.ListEntriesForAttendeeRewrite(ctx, db.ListEntriesForAttendeeRewriteParams{UserID: userID, Names: names})
+	if err != nil {
+		return 0, err
+	}
+
+	from := make(map[string]bool, len(names))
+	for _, name := range names {
+		from[name] = true
+	}
+	rename := func(name string) string {
+		if from[strings.ToLower(name)] {
+			return to
+		}
+		return name
+	}
+
+	for _, entry := range entries {
+		entry.AttendeesOriginal = mapAttendeesOriginal(entry.AttendeesOriginal, rename)
+		entry.Attendees = mapAttendees(entry.Attendees, rename)
+		err := q.UpdateEntryAttendees(ctx, db.UpdateEntryAttendeesParams{
+			ID:                entry.ID,
+			AttendeesOriginal: entry.AttendeesOriginal,
+			Attendees:         entry.Attendees,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrite attendees of entry %s: %w", entry.ID, err)
+		}
+		if err := syncEntryTasks(ctx, q, entry); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// resolveAttendees normalizes an attendees string and maps each name to the
+// canonical name of the person it matches, by name (any case) or alias
+func resolveAttendees(ctx context.Context, q *db.Queries, userID pgtype.UUID, original string) []string {
+	attendees := normalizeAttendees(original)
+	if len(attendees) == 0 {
+		return attendees
+	}
+
+	people, err := q.ListAttendeeNames(ctx, userID)
+	if err != nil {
+		log.Printf("Error loading attendee aliases: %v", err)
+		return attendees
+	}
+
+	canonical := make(map[string]string, len(people))
+	for _, person := range people {
+		for _, alias := range person.Aliases {
+			canonical[strings.ToLower(alias)] = person.Name
+		}
+	}
+	// Exact names win over aliases
+	for _, person := range people {
+		canonical[strings.ToLower(person.Name)] = person.Name
+	}
+
+	return mapAttendees(attendees, func(name string) string {
+		if resolved, ok := canonical[strings.ToLower(name)]; ok {
+			return resolved
+		}
+		return name
+	})
+}
+
+// mapAttendees renames attendees, dropping case-insensitive duplicates
+func mapAttendees(attendees []string, rename func(string) string) []string {
+	seen := make(map[string]bool, len(attendees))
+	mapped := make([]string, 0, len(attendees))
+	for _, name := range attendees {
+		name = rename(name)
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		mapped = append(mapped, name)
+	}
+	return mapped
+}
+
+// mapAttendeesOriginal applies rename to a comma-separated attendees string,
+// leaving it untouched if no name changes
+func mapAttendeesOriginal(original string, rename func(string) string) string {
+	parts := normalizeAttendees(original)
+	changed := false
+	for _, part := range parts {
+		if rename(part) != part {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return original
+	}
+	return strings.Join(mapAttendees(parts, rename), ", ")
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReorderEntriesRequest lists entry IDs in their new display order
+type ReorderEntriesRequest struct {
+	EntryIDs []string `json:"entry_ids" binding:"required"`
+}
+
+// ListPinnedEntries lists pinned entries in the user's order
+func (h *Handler) ListPinnedEntries(c *gin.Context) {
+	entries, err := h.queries.ListPinnedEntries(c.Request.Context(), h.getDefaultUserID(c))
+	h.respondWithEntries(c, entries, err)
+}
+
+// ListFavoriteEntries lists favourite entries in the user's order
+func (h *Handler) ListFavoriteEntries(c *gin.Context) {
+	entries, err := h.queries.ListFavoriteEntries(c.Request.Context(), h.getDefaultUserID(c))
+	h.respondWithEntries(c, entries, err)
+}
+
+// PinEntry pins an entry to the end of the pinned list
+func (h *Handler) PinEntry(c *gin.Context) {
+	h.setEntryFlag(c, func(ctx context.Context, entry db.Entry) error {
+		return h.queries.SetEntryPinned(ctx, db.SetEntryPinnedParams{EntryID: entry.ID, UserID: entry.UserID, Pinned: true})
+	})
+}
+
+// UnpinEntry removes an entry from the pinned list
+func (h *Handler) UnpinEntry(c *gin.Context) {
+	h.setEntryFlag(c, func(ctx context.Context, entry db.Entry) error {
+		return h.queries.SetEntryPinned(ctx, db.SetEntryPinnedParams{EntryID: entry.ID, UserID: entry.UserID, Pinned: false})
+	})
+}
+
+// FavoriteEntry adds an entry to the end of the favourites list
+func (h *Handler) FavoriteEntry(c *gin.Context) {
+	h.setEntryFlag(c, func(ctx context.Context, entry db.Entry) error {
+		return h.queries.SetEntryFavorite(ctx, db.SetEntryFavoriteParams{EntryID: entry.ID, UserID: entry.UserID, Favorite: true})
+	})
+}
+
+// UnfavoriteEntry removes an entry from the favourites list
+func (h *Handler) UnfavoriteEntry(c *gin.Context) {
+	h.setEntryFlag(c, func(ctx context.Context, entry db.Entry) error {
+		return h.queries.SetEntryFavorite(ctx, db.SetEntryFavoriteParams{EntryID: entry.ID, UserID: entry.UserID, Favorite: false})
+	})
+}
+
+// ReorderPinnedEntries sets the order of pinned entries
+func (h *Handler) ReorderPinnedEntries(c *gin.Context) {
+	h.reorderEntries(c, func(ctx context.Context, q *db.Queries, userID pgtype.UUID) ([]db.Entry, error) {
+		return q.ListPinnedEntries(ctx, userID)
+	}, func(ctx context.Context, q *db.Queries, entryID, userID pgtype.UUID, position int32) error {
+		return q.SetPinnedPosition(ctx, db.SetPinnedPositionParams{EntryID: entryID, UserID: userID, PinnedPosition: position})
+	}, h.ListPinnedEntries)
+}
+
+// ReorderFavoriteEntries sets the order of favourite entries
+func (h *Handler) ReorderFavoriteEntries(c *gin.Context) {
+	h.reorderEntries(c, func(ctx context.Context, q *db.Queries, userID pgtype.UUID) ([]db.Entry, error) {
+		return q.ListFavoriteEntries(ctx, userID)
+	}, func(ctx context.Context, q *db.Queries, entryID, userID pgtype.UUID, position int32) error {
+		return q.SetFavoritePosition(ctx, db.SetFavoritePositionParams{EntryID: entryID, UserID: userID, FavoritePosition: position})
+	}, h.ListFavoriteEntries)
+}
+
+func (h *Handler) setEntryFlag(c *gin.Context, set func(ctx context.Context, entry db.Entry) error) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.queries.GetEntry(c.Request.Context(), pgtype.UUID{Bytes: entryID, Valid: true})
+	if err != nil || entry.UserID != h.getDefaultUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	if err := set(c.Request.Context(), entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.entriesToResponse(c.Request.Context(), []db.Entry{entry})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response[0])
+}
+
+// reorderEntries puts the listed entries first, in request order, followed
+// by the rest of the list in their current order, then responds with the
+// reordered list. The whole list is renumbered in one transaction, so
+// positions stay distinct and a failure leaves the old order.
+func (h *Handler) reorderEntries(c *gin.Context, current func(ctx context.Context, q *db.Queries, userID pgtype.UUID) ([]db.Entry, error), setPosition func(ctx context.Context, q *db.Queries, entryID, userID pgtype.UUID, position int32) error, list gin.HandlerFunc) {
+	var req ReorderEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entryIDs := make([]pgtype.UUID, len(req.EntryIDs))
+	for i, id := range req.EntryIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID: " + id})
+			return
+		}
+		entryIDs[i] = pgtype.UUID{Bytes: parsed, Valid: true}
+	}
+
+	ctx := c.Request.Context()
+	userID := h.getDefaultUserID(c)
+	err := h.entries.WithTx(ctx, func(q *db.Queries) error {
+		entries, err := current(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+
+		listed := make(map[pgtype.UUID]bool, len(entries))
+		for _, entry := range entries {
+			listed[entry.ID] = true
+		}
+		order := make([]pgtype.UUID, 0, len(entries))
+		for _, id := range entryIDs {
+			if listed[id] {
+				order = append(order, id)
+				delete(listed, id)
+			}
+		}
+		for _, entry := range entries {
+			if listed[entry.ID] {
+				order = append(order, entry.ID)
+			}
+		}
+
+		for i, id := range order {
+			if err := setPosition(ctx, q, id, userID, int32(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list(c)
+}
+
+func (h *Handler) respondWithEntries(c *gin.Context, entries []db.Entry, err error) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list entries"})
+		return
+	}
+
+	response, err := h.entriesToResponse(c.Request.Context(), entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// loadEntryFlags returns the pinned/favourite flags of the given entries
+func (h *Handler) loadEntryFlags(ctx context.Context, entryIDs []pgtype.UUID) (map[pgtype.UUID]db.ListFlagsForEntriesRow, error) {
+	flagsByEntry := make(map[pgtype.UUID]db.ListFlagsForEntriesRow, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return flagsByEntry, nil
+	}
+
+	rows, err := h.queries.ListFlagsForEntries(ctx, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		flagsByEntry[row.EntryID] = row
+	}
+	return flagsByEntry, nil
+}
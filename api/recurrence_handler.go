@@ -0,0 +1,288 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RecurrenceRequest represents a create or partial update of a recurring series
+type RecurrenceRequest struct {
+	TemplateID *string `json:"template_id,omitempty"`
+	Rrule      *string `json:"rrule,omitempty"`     // e.g. FREQ=WEEKLY;BYDAY=MO,WE,FR
+	StartsOn   *string `json:"starts_on,omitempty"` // YYYY-MM-DD, defaults to today
+	EndsOn     *string `json:"ends_on,omitempty"`   // YYYY-MM-DD, "" clears
+	Paused     *bool   `json:"paused,omitempty"`
+}
+
+// RecurrenceResponse represents a recurring series
+type RecurrenceResponse struct {
+	ID             string    `json:"id"`
+	TemplateID     string    `json:"template_id"`
+	TemplateName   string    `json:"template_name,omitempty"`
+	Rrule          string    `json:"rrule"`
+	StartsOn       string    `json:"starts_on"`
+	EndsOn         string    `json:"ends_on,omitempty"`
+	Paused         bool      `json:"paused"`
+	NextOccurrence string    `json:"next_occurrence,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ListRecurrences lists the current user's recurring series
+func (h *Handler) ListRecurrences(c *gin.Context) {
+	userID := h.getDefaultUserID(c)
+
+	series, err := h.queries.ListRecurrences(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list recurrences"})
+		return
+	}
+
+	today := h.userToday(c.Request.Context(), userID)
+	response := make([]RecurrenceResponse, len(series))
+	for i, row := range series {
+		response[i] = recurrenceToResponse(db.Recurrence{
+			ID:         row.ID,
+			UserID:     row.UserID,
+			TemplateID: row.TemplateID,
+			Rrule:      row.Rrule,
+			StartsOn:   row.StartsOn,
+			EndsOn:     row.EndsOn,
+			Paused:     row.Paused,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			ResumedAt:  row.ResumedAt,
+		}, today)
+		response[i].TemplateName = row.TemplateName
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateRecurrence attaches a recurrence rule to a template
+func (h *Handler) CreateRecurrence(c *gin.Context) {
+	var req RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.TemplateID == nil || req.Rrule == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template_id and rrule are required"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	templateID, err := uuid.Parse(*req.TemplateID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return
+	}
+	template, err := h.queries.GetTemplate(c.Request.Context(), db.GetTemplateParams{
+		ID:     pgtype.UUID{Bytes: templateID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	if _, err := scheduler.ParseRule(*req.Rrule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	today := h.userToday(c.Request.Context(), userID)
+	startsOn := pgtype.Date{Time: time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	if req.StartsOn != nil {
+		if startsOn, err = parseDate(*req.StartsOn); err != nil || !startsOn.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "starts_on must be YYYY-MM-DD"})
+			return
+		}
+	}
+	var endsOn pgtype.Date
+	if req.EndsOn != nil {
+		if endsOn, err = parseDate(*req.EndsOn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if endsBeforeStart(startsOn, endsOn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must not be before starts_on"})
+		return
+	}
+
+	recurrence, err := h.queries.CreateRecurrence(c.Request.Context(), db.CreateRecurrenceParams{
+		UserID:     userID,
+		TemplateID: template.ID,
+		Rrule:      *req.Rrule,
+		StartsOn:   startsOn,
+		EndsOn:     endsOn,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := recurrenceToResponse(recurrence, today)
+	response.TemplateName = template.Name
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateRecurrence edits a series' rule, dates or paused state
+func (h *Handler) UpdateRecurrence(c *gin.Context) {
+	var req RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.updateRecurrence(c, req)
+}
+
+// PauseRecurrence stops a series from creating new entries
+func (h *Handler) PauseRecurrence(c *gin.Context) {
+	paused := true
+	h.updateRecurrence(c, RecurrenceRequest{Paused: &paused})
+}
+
+// ResumeRecurrence resumes a paused series
+func (h *Handler) ResumeRecurrence(c *gin.Context) {
+	paused := false
+	h.updateRecurrence(c, RecurrenceRequest{Paused: &paused})
+}
+
+func (h *Handler) updateRecurrence(c *gin.Context, req RecurrenceRequest) {
+	recurrenceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recurrence ID"})
+		return
+	}
+
+	if req.TemplateID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template_id cannot be changed; create a new series instead"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetRecurrence(c.Request.Context(), db.GetRecurrenceParams{
+		ID:     pgtype.UUID{Bytes: recurrenceID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurrence not found"})
+		return
+	}
+
+	params := db.UpdateRecurrenceParams{
+		ID:       existing.ID,
+		UserID:   userID,
+		Rrule:    existing.Rrule,
+		StartsOn: existing.StartsOn,
+		EndsOn:   existing.EndsOn,
+		Paused:   existing.Paused,
+	}
+	if req.Rrule != nil {
+		if _, err := scheduler.ParseRule(*req.Rrule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		params.Rrule = *req.Rrule
+	}
+	if req.StartsOn != nil {
+		if params.StartsOn, err = parseDate(*req.StartsOn); err != nil || !params.StartsOn.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "starts_on must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if req.EndsOn != nil {
+		if params.EndsOn, err = parseDate(*req.EndsOn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must be YYYY-MM-DD"})
+			return
+		}
+	}
+	// Either date may be the one that changed, so the pair is checked after both
+	if endsBeforeStart(params.StartsOn, params.EndsOn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_on must not be before starts_on"})
+		return
+	}
+	if req.Paused != nil {
+		params.Paused = *req.Paused
+	}
+
+	recurrence, err := h.queries.UpdateRecurrence(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, recurrenceToResponse(recurrence, h.userToday(c.Request.Context(), userID)))
+}
+
+// DeleteRecurrence deletes a series. Entries it already created are kept.
+func (h *Handler) DeleteRecurrence(c *gin.Context) {
+	recurrenceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recurrence ID"})
+		return
+	}
+
+	err = h.queries.DeleteRecurrence(c.Request.Context(), db.DeleteRecurrenceParams{
+		ID:     pgtype.UUID{Bytes: recurrenceID, Valid: true},
+		UserID: h.getDefaultUserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// endsBeforeStart reports whether a series ends before it starts, and so
+// would never create an entry
+func endsBeforeStart(startsOn, endsOn pgtype.Date) bool {
+	return startsOn.Valid && endsOn.Valid && endsOn.Time.Before(startsOn.Time)
+}
+
+// parseDate parses a YYYY-MM-DD date; the empty string yields a NULL date
+func parseDate(s string) (pgtype.Date, error) {
+	if s == "" {
+		return pgtype.Date{}, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+	return pgtype.Date{Time: t, Valid: true}, nil
+}
+
+func recurrenceToResponse(recurrence db.Recurrence, today time.Time) RecurrenceResponse {
+	response := RecurrenceResponse{
+		ID:         recurrence.ID.String(),
+		TemplateID: recurrence.TemplateID.String(),
+		Rrule:      recurrence.Rrule,
+		StartsOn:   recurrence.StartsOn.Time.Format("2006-01-02"),
+		Paused:     recurrence.Paused,
+		CreatedAt:  recurrence.CreatedAt.Time,
+		UpdatedAt:  recurrence.UpdatedAt.Time,
+	}
+	if recurrence.EndsOn.Valid {
+		response.EndsOn = recurrence.EndsOn.Time.Format("2006-01-02")
+	}
+
+	if rule, err := scheduler.ParseRule(recurrence.Rrule); err == nil && !recurrence.Paused {
+		next, ok := rule.Next(recurrence.StartsOn.Time, today)
+		if ok && (!recurrence.EndsOn.Valid || !next.After(recurrence.EndsOn.Time)) {
+			response.NextOccurrence = next.Format("2006-01-02")
+		}
+	}
+	return response
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TagResponse represents a tag together with the number of entries using it
+type TagResponse struct {
+	Name       string `json:"name"`
+	EntryCount int32  `json:"entry_count"`
+}
+
+// ListTags lists all tags in use with their entry counts
+func (h *Handler) ListTags(c *gin.Context) {
+	userID := h.getDefaultUserID(c)
+
+	results, err := h.queries.ListTagsWithCounts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	tags := make([]TagResponse, len(results))
+	for i, result := range results {
+		tags[i] = TagResponse{Name: result.Name, EntryCount: result.EntryCount}
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// SearchTags provides autocomplete suggestions for tag names
+func (h *Handler) SearchTags(c *gin.Context) {
+	query := strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "#")
+	userID := h.getDefaultUserID(c)
+
+	var suggestions []string
+
+	if query == "" {
+		// No query - return recent tags
+		results, err := h.queries.GetRecentTags(c.Request.Context(), db.GetRecentTagsParams{
+			UserID: userID,
+			Limit:  10,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tags"})
+			return
+		}
+		suggestions = make([]string, len(results))
+		for i, result := range results {
+			suggestions[i] = result.Name
+		}
+	} else {
+		// Search by prefix
+		results, err := h.queries.SearchTags(c.Request.Context(), db.SearchTagsParams{
+			UserID: userID,
+			Limit:  10,
+			Query:  pgtype.Text{String: strings.ToLower(query), Valid: true},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search tags"})
+			return
+		}
+		suggestions = make([]string, len(results))
+		for i, result := range results {
+			suggestions[i] = result.Name
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// normalizeTags trims, lowercases and de-duplicates tag names, dropping a
+// leading '#' so "#Project" and "project" are the same tag
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized
+}
+
+// setEntryTags replaces the tags on an entry with the given (normalized) names
+func setEntryTags(ctx context.Context, q *db.Queries, userID, entryID pgtype.UUID, tags []string) error {
+	if err := q.DeleteEntryTags(ctx, entryID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	for _, name := range tags {
+		tag, err := q.GetOrCreateTag(ctx, db.GetOrCreateTagParams{
+			UserID: userID,
+			Name:   name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record tag %s: %w", name, err)
+		}
+		if err := q.AddEntryTag(ctx, db.AddEntryTagParams{
+			EntryID: entryID,
+			TagID:   tag.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to tag entry with %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// loadEntryTags returns the tag names for each of the given entries, keyed by entry ID
+func (h *Handler) loadEntryTags(ctx context.Context, entryIDs []pgtype.UUID) (map[pgtype.UUID][]string, error) {
+	tagsByEntry := make(map[pgtype.UUID][]string, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return tagsByEntry, nil
+	}
+
+	rows, err := h.queries.ListTagsForEntries(ctx, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		tagsByEntry[row.EntryID] = append(tagsByEntry[row.EntryID], row.Name)
+	}
+	return tagsByEntry, nil
+}
+
+// entriesToResponse converts entries to responses, including their tags and
+// pinned/favourite flags
+func (h *Handler) entriesToResponse(ctx context.Context, entries []db.Entry) ([]EntryResponse, error) {
+	entryIDs := make([]pgtype.UUID, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.ID
+	}
+
+	tagsByEntry, err := h.loadEntryTags(ctx, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	flagsByEntry, err := h.loadEntryFlags(ctx, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]EntryResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = entryToResponse(entry)
+		if tags, ok := tagsByEntry[entry.ID]; ok {
+			response[i].Tags = tags
+		}
+		response[i].Pinned = flagsByEntry[entry.ID].Pinned
+		response[i].Favorite = flagsByEntry[entry.ID].Favorite
+	}
+	return response, nil
+}
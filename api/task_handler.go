@@ -0,0 +1,541 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxTasks caps how many tasks GET /tasks returns
+const maxTasks = 500
+
+var (
+	// A plain-text action item: "TODO: ...", "DONE ...", "[ ] ..." or "[x] ...",
+	// optionally behind a "-" or "*" bullet
+	todoLinePattern = regexp.MustCompile(`^(\s*(?:[-*•]\s+)?)(TODO|DONE|\[ \]|\[[xX]\])(?::\s*|\s+)(\S.*)$`)
+	dueDatePattern  = regexp.MustCompile(`(?i)(?:\bdue:?|\bby|📅)\s*(\d{4}-\d{2}-\d{2})\b`)
+	mentionPattern  = regexp.MustCompile(`@([\p{L}\p{N}._-]+)`)
+)
+
+// TaskRequest represents a task toggle
+type TaskRequest struct {
+	Done *bool `json:"done"`
+}
+
+// TaskResponse represents an action item and the entry it came from
+type TaskResponse struct {
+	ID          string     `json:"id"`
+	EntryID     string     `json:"entry_id"`
+	EntryTitle  string     `json:"entry_title"`
+	EntryDate   string     `json:"entry_date"`
+	Text        string     `json:"text"`
+	Done        bool       `json:"done"`
+	DueDate     string     `json:"due_date,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ListTasks lists action items across entries. ?status=open (default), done
+// or all; ?owner= filters by attendee; ?due_before=YYYY-MM-DD limits by due date.
+func (h *Handler) ListTasks(c *gin.Context) {
+	params := db.ListTasksParams{
+		UserID:   h.getDefaultUserID(c),
+		RowLimit: maxTasks,
+	}
+
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		params.Done = pgtype.Bool{Bool: false, Valid: true}
+	case "done":
+		params.Done = pgtype.Bool{Bool: true, Valid: true}
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, done or all"})
+		return
+	}
+
+	if owner := strings.TrimSpace(c.Query("owner")); owner != "" {
+		params.Owner = pgtype.Text{String: owner, Valid: true}
+	}
+	if dueBefore := c.Query("due_before"); dueBefore != "" {
+		date, err := parseDate(dueBefore)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_before must be YYYY-MM-DD"})
+			return
+		}
+		params.DueBefore = date
+	}
+
+	tasks, err := h.queries.ListTasks(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tasks"})
+		return
+	}
+
+	response := make([]TaskResponse, len(tasks))
+	for i, row := range tasks {
+		response[i] = taskToResponse(db.Task{
+			ID:          row.ID,
+			EntryID:     row.EntryID,
+			Text:        row.Text,
+			Done:        row.Done,
+			DueDate:     row.DueDate,
+			Owner:       row.Owner,
+			CompletedAt: row.CompletedAt,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		}, row.EntryTitle, row.DayYear, row.DayMonth, row.DayDay)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateTask checks or unchecks a task by rewriting its line in the source
+// entry's delta, so the entry and the task list never disagree
+func (h *Handler) UpdateTask(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		return
+	}
+
+	var req TaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Done == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "done is required"})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	task, err := h.queries.GetTask(c.Request.Context(), db.GetTaskParams{
+		ID:     pgtype.UUID{Bytes: taskID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	entry, err := h.queries.GetEntry(c.Request.Context(), task.EntryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+		return
+	}
+
+	if task.Done != *req.Done {
+		// The line is toggled against the locked entry so concurrent edits
+		// to the body aren't lost
+		var conflict error
+		entry, err = h.entries.EditEntry(c.Request.Context(), entry.ID, func(entry db.Entry) (EntryPatch, error) {
+			bodyDelta, bodyHTML, err := h.toggleTaskInBody(entry, task, *req.Done)
+			if err != nil {
+				conflict = err
+				return EntryPatch{}, err
+			}
+			bodyText := deltaToText(bodyDelta)
+			return EntryPatch{BodyDelta: &bodyDelta, BodyHTML: &bodyHTML, BodyText: &bodyText}, nil
+		})
+		if conflict != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict.Error()})
+			return
+		}
+		if errors.Is(err, ErrEntryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		task, err = h.queries.GetTask(c.Request.Context(), db.GetTaskParams{ID: task.ID, UserID: userID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, taskToResponse(task, entry.Title, entry.DayYear, entry.DayMonth, entry.DayDay))
+}
+
+// syncEntryTasks replaces the stored tasks of an entry with those parsed from
+// its delta. Tasks are keyed by their order in the entry, so IDs survive edits
+// that don't add or remove action items above them.
+func syncEntryTasks(ctx context.Context, q *db.Queries, entry db.Entry) error {
+	tasks := extractTasks(entry.BodyDelta, entry.Attendees)
+
+	for i, task := range tasks {
+		_, err := q.UpsertTask(ctx, db.UpsertTaskParams{
+			UserID:   entry.UserID,
+			EntryID:  entry.ID,
+			Position: int32(i),
+			Text:     task.text,
+			Done:     task.done,
+			DueDate:  task.dueDate,
+			Owner:    task.owner,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save task: %w", err)
+		}
+	}
+
+	return q.DeleteTasksFromPosition(ctx, db.DeleteTasksFromPositionParams{
+		EntryID:  entry.ID,
+		Position: int32(len(tasks)),
+	})
+}
+
+// toggleTaskInBody returns the entry's delta and HTML with the task's line
+// checked or unchecked
+func (h *Handler) toggleTaskInBody(entry db.Entry, task db.Task, done bool) (json.RawMessage, string, error) {
+	doc, ops, err := decodeDeltaOps(entry.BodyDelta)
+	if err != nil {
+		return nil, "", fmt.Errorf("entry body is not a valid delta")
+	}
+
+	lines := splitDeltaLines(ops)
+	tasks := extractTasksFromLines(lines, entry.Attendees)
+	if int(task.Position) >= len(tasks) || tasks[task.Position].text != task.Text {
+		return nil, "", fmt.Errorf("entry has changed since the task list was loaded; reload and try again")
+	}
+	parsed := tasks[task.Position]
+	line := lines[parsed.line]
+
+	bodyHTML := entry.BodyHtml
+	if parsed.marker == "" {
+		// Quill checklist item: flip the list attribute of the line's newline
+		value := "unchecked"
+		if done {
+			value = "checked"
+		}
+		ops = setLineAttribute(ops, line, "list", value)
+
+		// Flip the matching data-list attribute in the stored HTML
+		checklistIndex := 0
+		for _, l := range lines[:parsed.line] {
+			if isChecklistLine(l) {
+				checklistIndex++
+			}
+		}
+		bodyHTML = replaceNth(bodyHTML, checklistItemPattern, checklistIndex, `data-list="`+value+`"`)
+	} else {
+		// Plain-text marker: TODO <-> DONE, [ ] <-> [x]. The line's text may
+		// repeat or be split by formatting in the HTML, so the HTML is rendered
+		// again from the patched delta below rather than patched.
+		marker := toggledMarker(parsed.marker, done)
+		if ops, err = replaceLineText(ops, line, parsed.markerOffset, parsed.marker, marker); err != nil {
+			return nil, "", err
+		}
+	}
+
+	rawOps, err := json.Marshal(ops)
+	if err != nil {
+		return nil, "", err
+	}
+	doc["ops"] = rawOps
+	bodyDelta, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Render the delta if the stored HTML wasn't, or couldn't be, patched
+	if bodyHTML == entry.BodyHtml {
+		bodyHTML = h.deltaToHTML(bodyDelta)
+	}
+	return bodyDelta, bodyHTML, nil
+}
+
+var checklistItemPattern = regexp.MustCompile(`data-list="(?:checked|unchecked)"`)
+
+// deltaLine is one line of a Quill delta. Quill stores line formats such as
+// lists and headers on the newline that ends the line.
+type deltaLine struct {
+	text     string
+	attrs    map[string]interface{}
+	startOp  int // op and byte offset of the line's first character
+	startOff int
+	endOp    int // op and byte offset of the terminating newline
+	endOff   int
+}
+
+// parsedTask is an action item found in a delta
+type parsedTask struct {
+	line         int
+	text         string
+	done         bool
+	marker       string // "" for checklist items
+	markerOffset int    // byte offset of the marker within the line
+	dueDate      pgtype.Date
+	owner        pgtype.Text
+}
+
+func decodeDeltaOps(delta []byte) (map[string]json.RawMessage, []map[string]json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(delta, &doc); err != nil {
+		return nil, nil, err
+	}
+	var ops []map[string]json.RawMessage
+	if err := json.Unmarshal(doc["ops"], &ops); err != nil {
+		return nil, nil, err
+	}
+	return doc, ops, nil
+}
+
+// splitDeltaLines splits delta ops into lines. Embeds contribute no text.
+func splitDeltaLines(ops []map[string]json.RawMessage) []deltaLine {
+	var lines []deltaLine
+	var text strings.Builder
+	startOp, startOff, started := 0, 0, false
+
+	for i, op := range ops {
+		var insert string
+		if err := json.Unmarshal(op["insert"], &insert); err != nil {
+			if !started {
+				startOp, startOff, started = i, 0, true
+			}
+			continue // embed
+		}
+
+		offset := 0
+		for offset < len(insert) {
+			if !started {
+				startOp, startOff, started = i, offset, true
+			}
+			newline := strings.IndexByte(insert[offset:], '\n')
+			if newline < 0 {
+				text.WriteString(insert[offset:])
+				break
+			}
+			text.WriteString(insert[offset : offset+newline])
+
+			var attrs map[string]interface{}
+			if raw, ok := op["attributes"]; ok {
+				_ = json.Unmarshal(raw, &attrs)
+			}
+			lines = append(lines, deltaLine{
+				text:     text.String(),
+				attrs:    attrs,
+				startOp:  startOp,
+				startOff: startOff,
+				endOp:    i,
+				endOff:   offset + newline,
+			})
+			text.Reset()
+			started = false
+			offset += newline + 1
+		}
+	}
+	return lines
+}
+
+func isChecklistLine(line deltaLine) bool {
+	list, _ := line.attrs["list"].(string)
+	return list == "checked" || list == "unchecked"
+}
+
+// extractTasks parses the action items out of a Quill delta
+func extractTasks(delta []byte, attendees []string) []parsedTask {
+	_, ops, err := decodeDeltaOps(delta)
+	if err != nil {
+		return nil
+	}
+	return extractTasksFromLines(splitDeltaLines(ops), attendees)
+}
+
+func extractTasksFromLines(lines []deltaLine, attendees []string) []parsedTask {
+	var tasks []parsedTask
+	for i, line := range lines {
+		task := parsedTask{line: i}
+
+		if isChecklistLine(line) {
+			task.text = strings.TrimSpace(line.text)
+			task.done = line.attrs["list"] == "checked"
+		} else if m := todoLinePattern.FindStringSubmatchIndex(line.text); m != nil {
+			task.marker = line.text[m[4]:m[5]]
+			task.markerOffset = m[4]
+			task.text = strings.TrimSpace(line.text[m[6]:m[7]])
+			task.done = task.marker == "DONE" || strings.EqualFold(task.marker, "[x]")
+		} else {
+			continue
+		}
+		if task.text == "" {
+			continue
+		}
+
+		if m := dueDatePattern.FindStringSubmatch(task.text); m != nil {
+			if due, err := parseDate(m[1]); err == nil {
+				task.dueDate = due
+			}
+		}
+		if owner := taskOwner(task.text, attendees); owner != "" {
+			task.owner = pgtype.Text{String: owner, Valid: true}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// taskOwner returns the attendee a task is assigned to, either by an
+// @mention ("@alice", "@alice.smith") or a leading "Alice:"
+func taskOwner(text string, attendees []string) string {
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if attendee := matchAttendee(m[1], attendees); attendee != "" {
+			return attendee
+		}
+	}
+	if prefix, _, ok := strings.Cut(text, ":"); ok {
+		return matchAttendee(strings.TrimSpace(prefix), attendees)
+	}
+	return ""
+}
+
+// matchAttendee finds the attendee called name, by full name, first name or
+// the name with spaces replaced by dots or removed
+func matchAttendee(name string, attendees []string) string {
+	name = strings.ToLower(name)
+	if name == "" {
+		return ""
+	}
+	for _, attendee := range attendees {
+		full := strings.ToLower(attendee)
+		first, _, _ := strings.Cut(full, " ")
+		if name == full || name == first ||
+			name == strings.ReplaceAll(full, " ", ".") ||
+			name == strings.ReplaceAll(full, " ", "") {
+			return attendee
+		}
+	}
+	return ""
+}
+
+func toggledMarker(marker string, done bool) string {
+	if strings.HasPrefix(marker, "[") {
+		if done {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+	if done {
+		return "DONE"
+	}
+	return "TODO"
+}
+
+// setLineAttribute sets a line format on the newline ending line, splitting
+// the op if it carries other text or newlines
+func setLineAttribute(ops []map[string]json.RawMessage, line deltaLine, key, value string) []map[string]json.RawMessage {
+	op := ops[line.endOp]
+	var insert string
+	_ = json.Unmarshal(op["insert"], &insert)
+
+	var attrs map[string]interface{}
+	if raw, ok := op["attributes"]; ok {
+		_ = json.Unmarshal(raw, &attrs)
+	}
+
+	withInsert := func(s string, attrs map[string]interface{}) map[string]json.RawMessage {
+		split := make(map[string]json.RawMessage, len(op))
+		for k, v := range op {
+			split[k] = v
+		}
+		split["insert"], _ = json.Marshal(s)
+		if len(attrs) > 0 {
+			split["attributes"], _ = json.Marshal(attrs)
+		} else {
+			delete(split, "attributes")
+		}
+		return split
+	}
+
+	newAttrs := make(map[string]interface{}, len(attrs)+1)
+	for k, v := range attrs {
+		newAttrs[k] = v
+	}
+	newAttrs[key] = value
+
+	var replacement []map[string]json.RawMessage
+	if before := insert[:line.endOff]; before != "" {
+		replacement = append(replacement, withInsert(before, attrs))
+	}
+	replacement = append(replacement, withInsert("\n", newAttrs))
+	if after := insert[line.endOff+1:]; after != "" {
+		replacement = append(replacement, withInsert(after, attrs))
+	}
+
+	result := make([]map[string]json.RawMessage, 0, len(ops)+len(replacement)-1)
+	result = append(result, ops[:line.endOp]...)
+	result = append(result, replacement...)
+	return append(result, ops[line.endOp+1:]...)
+}
+
+// replaceLineText replaces from with to at a byte offset within a line. The
+// text being replaced must sit in a single op.
+func replaceLineText(ops []map[string]json.RawMessage, line deltaLine, offset int, from, to string) ([]map[string]json.RawMessage, error) {
+	op := ops[line.startOp]
+	var insert string
+	if err := json.Unmarshal(op["insert"], &insert); err != nil {
+		return nil, fmt.Errorf("task line starts with an embed")
+	}
+
+	start := line.startOff + offset
+	if start+len(from) > len(insert) || insert[start:start+len(from)] != from {
+		return nil, fmt.Errorf("task marker is split across formatting; edit the entry instead")
+	}
+
+	rendered, err := json.Marshal(insert[:start] + to + insert[start+len(from):])
+	if err != nil {
+		return nil, err
+	}
+	op["insert"] = rendered
+	return ops, nil
+}
+
+// replaceNth replaces the n-th (0-based) match of pattern in s
+func replaceNth(s string, pattern *regexp.Regexp, n int, replacement string) string {
+	matches := pattern.FindAllStringIndex(s, n+1)
+	if len(matches) <= n {
+		return s
+	}
+	m := matches[n]
+	return s[:m[0]] + replacement + s[m[1]:]
+}
+
+func taskToResponse(task db.Task, entryTitle string, year, month, day int32) TaskResponse {
+	response := TaskResponse{
+		ID:         task.ID.String(),
+		EntryID:    task.EntryID.String(),
+		EntryTitle: entryTitle,
+		EntryDate:  fmt.Sprintf("%04d-%02d-%02d", year, month, day),
+		Text:       task.Text,
+		Done:       task.Done,
+		CreatedAt:  task.CreatedAt.Time,
+		UpdatedAt:  task.UpdatedAt.Time,
+	}
+	if task.DueDate.Valid {
+		response.DueDate = task.DueDate.Time.Format("2006-01-02")
+	}
+	if task.Owner.Valid {
+		response.Owner = task.Owner.String
+	}
+	if task.CompletedAt.Valid {
+		completedAt := task.CompletedAt.Time
+		response.CompletedAt = &completedAt
+	}
+	return response
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TemplateRequest represents a create or partial update of an entry template
+type TemplateRequest struct {
+	Name             *string          `json:"name,omitempty"`
+	TitlePattern     *string          `json:"title_pattern,omitempty"`
+	BodyDelta        *json.RawMessage `json:"body_delta,omitempty"`
+	DefaultType      *string          `json:"default_type,omitempty"`
+	DefaultAttendees *string          `json:"default_attendees,omitempty"`
+}
+
+// TemplateResponse represents a stored entry template
+type TemplateResponse struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	TitlePattern     string          `json:"title_pattern"`
+	BodyDelta        json.RawMessage `json:"body_delta"`
+	DefaultType      string          `json:"default_type"`
+	DefaultAttendees string          `json:"default_attendees"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// ListTemplates lists the current user's templates
+func (h *Handler) ListTemplates(c *gin.Context) {
+	userID := h.getDefaultUserID(c)
+
+	templates, err := h.queries.ListTemplates(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list templates"})
+		return
+	}
+
+	response := make([]TemplateResponse, len(templates))
+	for i, template := range templates {
+		response[i] = templateToResponse(template)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTemplate returns a single template
+func (h *Handler) GetTemplate(c *gin.Context) {
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return
+	}
+
+	template, err := h.queries.GetTemplate(c.Request.Context(), db.GetTemplateParams{
+		ID:     pgtype.UUID{Bytes: templateID, Valid: true},
+		UserID: h.getDefaultUserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templateToResponse(template))
+}
+
+// CreateTemplate creates a new entry template
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	params := db.CreateTemplateParams{
+		UserID:    userID,
+		BodyDelta: []byte(emptyDelta),
+	}
+	if req.Name != nil {
+		params.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TitlePattern != nil {
+		params.TitlePattern = *req.TitlePattern
+	}
+	if req.BodyDelta != nil {
+		params.BodyDelta = *req.BodyDelta
+	}
+	if req.DefaultAttendees != nil {
+		params.DefaultAttendees = *req.DefaultAttendees
+	}
+	if req.DefaultType != nil && strings.TrimSpace(*req.DefaultType) != "" {
+		entryType, err := h.lookupEntryType(c.Request.Context(), userID, *req.DefaultType)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		params.DefaultType = pgtype.Text{String: entryType.Name, Valid: true}
+	}
+
+	if err := validateTemplate(params.Name, params.BodyDelta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.queries.CreateTemplate(c.Request.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a template with that name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, templateToResponse(template))
+}
+
+// UpdateTemplate updates an entry template
+func (h *Handler) UpdateTemplate(c *gin.Context) {
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return
+	}
+
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetTemplate(c.Request.Context(), db.GetTemplateParams{
+		ID:     pgtype.UUID{Bytes: templateID, Valid: true},
+		UserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	params := db.UpdateTemplateParams{
+		ID:               existing.ID,
+		UserID:           userID,
+		Name:             existing.Name,
+		TitlePattern:     existing.TitlePattern,
+		BodyDelta:        existing.BodyDelta,
+		DefaultType:      existing.DefaultType,
+		DefaultAttendees: existing.DefaultAttendees,
+	}
+	if req.Name != nil {
+		params.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TitlePattern != nil {
+		params.TitlePattern = *req.TitlePattern
+	}
+	if req.BodyDelta != nil {
+		params.BodyDelta = *req.BodyDelta
+	}
+	if req.DefaultAttendees != nil {
+		params.DefaultAttendees = *req.DefaultAttendees
+	}
+	if req.DefaultType != nil {
+		if strings.TrimSpace(*req.DefaultType) == "" {
+			params.DefaultType = pgtype.Text{}
+		} else {
+			entryType, err := h.lookupEntryType(c.Request.Context(), userID, *req.DefaultType)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			params.DefaultType = pgtype.Text{String: entryType.Name, Valid: true}
+		}
+	}
+
+	if err := validateTemplate(params.Name, params.BodyDelta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.queries.UpdateTemplate(c.Request.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a template with that name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templateToResponse(template))
+}
+
+// DeleteTemplate deletes an entry template. Entries created from it are kept.
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return
+	}
+
+	err = h.queries.DeleteTemplate(c.Request.Context(), db.DeleteTemplateParams{
+		ID:     pgtype.UUID{Bytes: templateID, Valid: true},
+		UserID: h.getDefaultUserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// applyTemplate fills the empty fields of a create request from a template,
+// rendering date placeholders for the entry's date
+func (h *Handler) applyTemplate(req *CreateEntryRequest, template db.Template, date time.Time) error {
+	if req.Title == "" {
+		req.Title = renderPlaceholders(template.TitlePattern, date)
+	}
+	if req.Type == "" && template.DefaultType.Valid {
+		req.Type = template.DefaultType.String
+	}
+	if req.AttendeesOriginal == "" {
+		req.AttendeesOriginal = template.DefaultAttendees
+	}
+	if len(req.BodyDelta) == 0 || string(req.BodyDelta) == "null" {
+		bodyDelta, err := renderDeltaPlaceholders(template.BodyDelta, date)
+		if err != nil {
+			return fmt.Errorf("failed to render template body: %w", err)
+		}
+		req.BodyDelta = bodyDelta
+		req.BodyHTML = h.deltaToHTML(bodyDelta)
+		req.BodyText = deltaToText(bodyDelta)
+	}
+	return nil
+}
+
+// WithTx runs fn in one entry service transaction. The recurrence scheduler
+// uses it to claim an occurrence and create its entry together.
+func (h *Handler) WithTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	return h.entries.WithTx(ctx, fn)
+}
+
+// CreateEntryFromTemplate creates an entry on the given civil date from a
+// template, within the transaction of q. It is used by the recurrence
+// scheduler.
+func (h *Handler) CreateEntryFromTemplate(ctx context.Context, q *db.Queries, userID pgtype.UUID, template db.Template, date time.Time) (db.Entry, error) {
+	var req CreateEntryRequest
+	if err := h.applyTemplate(&req, template, date); err != nil {
+		return db.Entry{}, err
+	}
+
+	// Fall back to the user's first entry type if the template has none
+	var entryType db.EntryType
+	var err error
+	if req.Type != "" {
+		entryType, err = h.lookupEntryType(ctx, userID, req.Type)
+	} else {
+		var types []db.EntryType
+		types, err = q.ListEntryTypes(ctx, userID)
+		if err == nil && len(types) == 0 {
+			err = fmt.Errorf("user has no entry types")
+		}
+		if err == nil {
+			entryType = types[0]
+		}
+	}
+	if err != nil {
+		return db.Entry{}, err
+	}
+
+	return createEntry(ctx, q, db.CreateEntryParams{
+		UserID:            userID,
+		Title:             req.Title,
+		BodyDelta:         req.BodyDelta,
+		BodyHtml:          h.sanitizer.Sanitize(req.BodyHTML),
+		BodyText:          req.BodyText,
+		AttendeesOriginal: req.AttendeesOriginal,
+		Type:              entryType.Name,
+		DayYear:           int32(date.Year()),
+		DayMonth:          int32(date.Month()),
+		DayDay:            int32(date.Day()),
+	}, nil)
+}
+
+// renderPlaceholders replaces date placeholders in s:
+// {{date}} 2006-01-02, {{weekday}} Monday, {{day}} 2, {{month}} January,
+// {{year}} 2006 and {{week}} (ISO week number)
+func renderPlaceholders(s string, date time.Time) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	_, week := date.ISOWeek()
+	replacer := strings.NewReplacer(
+		"{{date}}", date.Format("2006-01-02"),
+		"{{weekday}}", date.Weekday().String(),
+		"{{day}}", strconv.Itoa(date.Day()),
+		"{{month}}", date.Month().String(),
+		"{{year}}", strconv.Itoa(date.Year()),
+		"{{week}}", strconv.Itoa(week),
+	)
+	return replacer.Replace(s)
+}
+
+// renderDeltaPlaceholders renders placeholders in every text insert of a
+// Quill delta, leaving embeds and attributes untouched
+func renderDeltaPlaceholders(delta []byte, date time.Time) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(delta, &doc); err != nil {
+		return nil, err
+	}
+
+	var ops []map[string]json.RawMessage
+	if err := json.Unmarshal(doc["ops"], &ops); err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		var text string
+		if err := json.Unmarshal(op["insert"], &text); err != nil {
+			continue // embed (image, formula, ...)
+		}
+		rendered, err := json.Marshal(renderPlaceholders(text, date))
+		if err != nil {
+			return nil, err
+		}
+		op["insert"] = rendered
+	}
+
+	renderedOps, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	doc["ops"] = renderedOps
+	return json.Marshal(doc)
+}
+
+// deltaToText extracts the plain text of a Quill delta, as Quill's getText() would
+func deltaToText(delta []byte) string {
+	var deltaOps struct {
+		Ops []struct {
+			Insert json.RawMessage `json:"insert"`
+		} `json:"ops"`
+	}
+	if err := json.Unmarshal(delta, &deltaOps); err != nil {
+		return ""
+	}
+
+	var text strings.Builder
+	for _, op := range deltaOps.Ops {
+		var s string
+		if err := json.Unmarshal(op.Insert, &s); err == nil {
+			text.WriteString(s)
+		}
+	}
+	return text.String()
+}
+
+func validateTemplate(name string, bodyDelta []byte) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	var delta struct {
+		Ops []json.RawMessage `json:"ops"`
+	}
+	if err := json.Unmarshal(bodyDelta, &delta); err != nil || delta.Ops == nil {
+		return fmt.Errorf("body_delta must be a Quill delta with an ops array")
+	}
+	return nil
+}
+
+func templateToResponse(template db.Template) TemplateResponse {
+	return TemplateResponse{
+		ID:               template.ID.String(),
+		Name:             template.Name,
+		TitlePattern:     template.TitlePattern,
+		BodyDelta:        template.BodyDelta,
+		DefaultType:      template.DefaultType.String,
+		DefaultAttendees: template.DefaultAttendees,
+		CreatedAt:        template.CreatedAt.Time,
+		UpdatedAt:        template.UpdatedAt.Time,
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UpdateProfileRequest represents a partial update of the current user's profile
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+}
+
+// ProfileResponse represents the current user's profile settings
+type ProfileResponse struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Timezone    string    `json:"timezone"`
+	Today       string    `json:"today"` // YYYY-MM-DD in the user's timezone
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GetProfile returns the current user's profile settings
+func (h *Handler) GetProfile(c *gin.Context) {
+	userID := h.getDefaultUserID(c)
+
+	user, err := h.queries.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.userToResponse(c.Request.Context(), user))
+}
+
+// UpdateProfile updates the current user's display name and/or timezone
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := h.getDefaultUserID(c)
+
+	existing, err := h.queries.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	displayName := existing.DisplayName
+	timezone := existing.Timezone
+
+	if req.DisplayName != nil {
+		trimmed := strings.TrimSpace(*req.DisplayName)
+		displayName = pgtype.Text{String: trimmed, Valid: trimmed != ""}
+	}
+	if req.Timezone != nil {
+		if !isValidTimezone(*req.Timezone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timezone must be a valid IANA timezone name, e.g. Europe/London"})
+			return
+		}
+		timezone = *req.Timezone
+	}
+
+	user, err := h.queries.UpdateUser(c.Request.Context(), db.UpdateUserParams{
+		ID:          userID,
+		DisplayName: displayName,
+		Timezone:    timezone,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.userToResponse(c.Request.Context(), user))
+}
+
+// userToResponse reports today's date as the rest of the API sees it, falling
+// back to the server's default timezone if the user's can't be loaded
+func (h *Handler) userToResponse(ctx context.Context, user db.User) ProfileResponse {
+	loc := h.userLocation(ctx, user.ID)
+
+	return ProfileResponse{
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		DisplayName: user.DisplayName.String,
+		Timezone:    user.Timezone,
+		Today:       time.Now().In(loc).Format("2006-01-02"),
+		CreatedAt:   user.CreatedAt.Time,
+	}
+}
+
+// isValidTimezone reports whether name is an IANA timezone name. The empty
+// string and "Local" are rejected because LoadLocation maps them to the
+// server's own zone rather than a real user setting.
+func isValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
@@ -0,0 +1,22 @@
+// Package cleanup finds attachments no longer referenced from their entry's
+// body and, after a grace period, reports or removes them.
+package cleanup
+
+import (
+	"regexp"
+	"strings"
+)
+
+// attachmentURL matches the attachment URLs the editor embeds for images and
+// file links. JSON may escape the slash.
+var attachmentURL = regexp.MustCompile(`attachments\\?/([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+
+// References returns the IDs of the attachments a Quill delta refers to,
+// lowercased, whether as an embedded image or a link
+func References(bodyDelta []byte) map[string]bool {
+	refs := map[string]bool{}
+	for _, m := range attachmentURL.FindAllSubmatch(bodyDelta, -1) {
+		refs[strings.ToLower(string(m[1]))] = true
+	}
+	return refs
+}
@@ -0,0 +1,260 @@
+package cleanup
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// scanPageSize is how many attachments the scan reads at a time
+const scanPageSize = 100
+
+// AttachmentRemover deletes an orphaned attachment along with its stored
+// contents, unless its entry refers to it again, reporting whether it did
+type AttachmentRemover interface {
+	RemoveOrphanedAttachment(ctx context.Context, id pgtype.UUID, cfg config.StorageConfig) (bool, error)
+}
+
+// Cleaner periodically scans entry bodies for attachment references. An
+// attachment that was once referenced and no longer is gets marked orphaned;
+// one still orphaned after the grace period is logged, or deleted in
+// "delete" mode. Attachments that are referenced again are unmarked, and
+// ones never embedded, such as API uploads, are left alone. Archived
+// entries are not scanned. The scan is skipped in "off" mode.
+//
+// It also deletes stored contents left unused when removing them failed
+// after their last attachment or thumbnail was deleted.
+type Cleaner struct {
+	dbpool   *pgxpool.Pool
+	queries  *db.Queries
+	remover  AttachmentRemover
+	cfg      config.AttachmentsConfig
+	interval time.Duration
+	runMu    sync.Mutex // held for a run, so runs don't overlap
+	mu       sync.Mutex
+	running  bool
+	stopCh   chan struct{}
+}
+
+func New(dbpool *pgxpool.Pool, queries *db.Queries, remover AttachmentRemover, cfg config.AttachmentsConfig, interval time.Duration) *Cleaner {
+	return &Cleaner{
+		dbpool:   dbpool,
+		queries:  queries,
+		remover:  remover,
+		cfg:      cfg,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (s *Cleaner) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	log.Printf("Attachment cleanup started (mode %s, grace %dh)", s.cfg.OrphanMode, s.cfg.OrphanGraceHours)
+
+	// Initial run
+	go s.clean(ctx)
+
+	// Periodic runs
+	ticker := time.NewTicker(s.interval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				s.clean(ctx)
+			case <-s.stopCh:
+				ticker.Stop()
+				return
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (s *Cleaner) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopCh)
+	s.running = false
+	log.Println("Attachment cleanup stopped")
+}
+
+func (s *Cleaner) clean(ctx context.Context) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.purgeUnused(ctx)
+	if s.cfg.OrphanMode != "off" {
+		s.scanOrphans(ctx)
+	}
+}
+
+// purgeUnused deletes contents whose last use is gone
+func (s *Cleaner) purgeUnused(ctx context.Context) {
+	blobs, err := s.queries.ListUnusedBlobs(ctx)
+	if err != nil {
+		log.Printf("Error fetching unused blobs: %v", err)
+		return
+	}
+
+	purged := 0
+	for _, blob := range blobs {
+		if err := storage.Purge(ctx, s.dbpool, blob.StorageBackend, blob.StorageKey, s.cfg.Storage); err != nil {
+			log.Printf("Error deleting unused contents %s from %s: %v", blob.StorageKey, blob.StorageBackend, err)
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		log.Printf("Deleted %d unused stored contents", purged)
+	}
+}
+
+// scanOrphans reads the attachments a page at a time, so memory use doesn't
+// grow with the number of entry bodies
+func (s *Cleaner) scanOrphans(ctx context.Context) {
+	cutoff := time.Now().Add(-time.Duration(s.cfg.OrphanGraceHours) * time.Hour)
+	var orphaned, restored, removed int
+
+	params := db.ListAttachmentsForCleanupParams{PageSize: scanPageSize}
+	for !s.stopped(ctx) {
+		attachments, err := s.queries.ListAttachmentsForCleanup(ctx, params)
+		if err != nil {
+			log.Printf("Error fetching attachments for cleanup: %v", err)
+			return
+		}
+		if len(attachments) == 0 {
+			break
+		}
+
+		o, r, expired := s.scanPage(ctx, attachments, cutoff)
+		orphaned += o
+		restored += r
+		removed += s.removeExpired(ctx, expired)
+
+		if len(attachments) < scanPageSize {
+			break
+		}
+		last := attachments[len(attachments)-1]
+		params.AfterID = last.ID
+		params.AfterEntryID = last.EntryID
+	}
+
+	if orphaned > 0 || restored > 0 {
+		log.Printf("Attachment cleanup: %d newly orphaned, %d referenced again", orphaned, restored)
+	}
+	if removed > 0 {
+		log.Printf("Removed %d orphaned attachments", removed)
+	}
+}
+
+// scanPage updates the reference marks of a page of attachments. It returns
+// how many were newly orphaned and unmarked, and those orphaned for longer
+// than the grace period.
+func (s *Cleaner) scanPage(ctx context.Context, attachments []db.ListAttachmentsForCleanupRow, cutoff time.Time) (int, int, []db.ListAttachmentsForCleanupRow) {
+	var orphaned, restored []pgtype.UUID
+	referenced := map[pgtype.UUID][]pgtype.UUID{} // by entry
+	var expired []db.ListAttachmentsForCleanupRow
+
+	// Rows come grouped by entry, so each body is scanned once a page
+	var refs map[string]bool
+	var entryID pgtype.UUID
+	for _, attachment := range attachments {
+		if refs == nil || attachment.EntryID != entryID {
+			refs = References(attachment.BodyDelta)
+			entryID = attachment.EntryID
+		}
+
+		switch {
+		case refs[attachment.ID.String()]:
+			if !attachment.ReferencedAt.Valid {
+				referenced[attachment.EntryID] = append(referenced[attachment.EntryID], attachment.ID)
+			}
+			if attachment.OrphanedAt.Valid {
+				restored = append(restored, attachment.ID)
+			}
+		case !attachment.ReferencedAt.Valid:
+			// Never embedded, so nothing removed it
+		case !attachment.OrphanedAt.Valid:
+			orphaned = append(orphaned, attachment.ID)
+		case attachment.OrphanedAt.Time.Before(cutoff):
+			expired = append(expired, attachment)
+		}
+	}
+
+	for entryID, ids := range referenced {
+		if err := s.queries.MarkAttachmentsReferenced(ctx, db.MarkAttachmentsReferencedParams{
+			EntryID: entryID,
+			Ids:     ids,
+		}); err != nil {
+			log.Printf("Error marking referenced attachments: %v", err)
+		}
+	}
+	if len(orphaned) > 0 {
+		if err := s.queries.MarkAttachmentsOrphaned(ctx, orphaned); err != nil {
+			log.Printf("Error marking orphaned attachments: %v", err)
+		}
+	}
+	if len(restored) > 0 {
+		if err := s.queries.ClearAttachmentsOrphaned(ctx, restored); err != nil {
+			log.Printf("Error unmarking referenced attachments: %v", err)
+		}
+	}
+	return len(orphaned), len(restored), expired
+}
+
+// removeExpired logs the attachments orphaned past the grace period or, in
+// "delete" mode, removes them, returning how many were removed
+func (s *Cleaner) removeExpired(ctx context.Context, expired []db.ListAttachmentsForCleanupRow) int {
+	removed := 0
+	for _, orphan := range expired {
+		age := time.Since(orphan.OrphanedAt.Time).Round(time.Hour)
+		if s.cfg.OrphanMode != "delete" {
+			log.Printf("Orphaned attachment %s (%s) on entry %s, unreferenced for %s", orphan.ID.String(), orphan.Filename, orphan.EntryID.String(), age)
+			continue
+		}
+
+		ok, err := s.remover.RemoveOrphanedAttachment(ctx, orphan.ID, s.cfg.Storage)
+		if err != nil {
+			log.Printf("Error removing orphaned attachment %s: %v", orphan.ID.String(), err)
+			continue
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed
+}
+
+// stopped reports whether the cleaner has been stopped or its context
+// cancelled, so a run can end early
+func (s *Cleaner) stopped(ctx context.Context) bool {
+	select {
+	case <-s.stopCh:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+// Command extract-attachments extracts the text of attachments uploaded
+// before text extraction existed, so they show up in search and Chat. The
+// vector service embeds the text on its next run. It is safe to rerun.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/chrisbakker/journal/config"
+	"github.com/chrisbakker/journal/extract"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	attachments, err := queries.ListAttachmentsNeedingText(ctx, extract.MimeTypes)
+	if err != nil {
+		log.Fatalf("Failed to list attachments: %v", err)
+	}
+	log.Printf("📄 Extracting text from %d attachments", len(attachments))
+
+	done := 0
+	for _, attachment := range attachments {
+		if err := extractText(ctx, queries, cfg.Attachments.Storage, attachment); err != nil {
+			log.Printf("❌ %s (%s): %v", attachment.ID.String(), attachment.Filename, err)
+			continue
+		}
+		done++
+	}
+
+	log.Printf("✅ Extracted text from %d of %d attachments", done, len(attachments))
+}
+
+// extractText stores an attachment's text. As on upload, files with no
+// readable text are stored with empty text; storage errors are retried on
+// the next run.
+func extractText(ctx context.Context, queries *db.Queries, cfg config.StorageConfig, attachment db.Attachment) error {
+	store, err := storage.ForBackend(attachment.StorageBackend, cfg, queries)
+	if err != nil {
+		return err
+	}
+	content, err := store.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	text, err := extract.Text(content, attachment.MimeType)
+	if err != nil {
+		log.Printf("⚠️  No text in %s (%s): %v", attachment.ID.String(), attachment.Filename, err)
+	}
+	return queries.CreateAttachmentText(ctx, db.CreateAttachmentTextParams{
+		AttachmentID: attachment.ID,
+		Content:      text,
+	})
+}
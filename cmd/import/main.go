@@ -0,0 +1,107 @@
+// Command import imports entries from an export archive, a folder or zip of
+// Markdown files (an Obsidian vault, say) or a Day One JSON export:
+//
+//	go run ./cmd/import -format markdown ~/notes
+//	go run ./cmd/import -format dayone -dry-run ~/Downloads/Journal.zip
+//
+// The options are those of POST /api/import, and the report it prints is
+// the same. Importing Markdown or Day One again skips entries with an ID in
+// their front matter or export, and duplicates the rest.
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/chrisbakker/journal/api"
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+
+	format := flag.String("format", "markdown", "what to import: json (an export archive), markdown or dayone")
+	email := flag.String("user", "", "email of the user to import for (default: the app's user)")
+	dryRun := flag.Bool("dry-run", false, "report what would happen without changing anything")
+	ids := flag.String("ids", "preserve", "preserve entry and attachment IDs where free, or remap them all")
+	conflict := flag.String("conflict", "skip", "for entries that already exist: skip, overwrite or duplicate")
+	entryType := flag.String("type", "notes", "entry type of Markdown and Day One entries that don't name one")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: import [flags] <folder or zip>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (*ids != "preserve" && *ids != "remap") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src := flag.Arg(0)
+	info, err := os.Stat(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(src)
+	} else {
+		archive, err := zip.OpenReader(src)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", src, err)
+		}
+		defer archive.Close()
+		fsys = archive
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	userID := pgtype.UUID{Bytes: uuid.MustParse(api.DefaultUserID), Valid: true}
+	if *email != "" {
+		user, err := queries.GetUserByEmail(ctx, *email)
+		if err != nil {
+			log.Fatalf("Failed to find user %s: %v", *email, err)
+		}
+		userID = user.ID
+	}
+
+	handler := api.NewHandler(pool, queries, cfg.App.DefaultTimezone, nil, nil)
+	report, err := handler.Import(ctx, userID, fsys, api.ImportOptions{
+		Format:   *format,
+		DryRun:   *dryRun,
+		Remap:    *ids == "remap",
+		Conflict: *conflict,
+		Type:     *entryType,
+	}, cfg.Attachments)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(out))
+
+	verb := "Imported"
+	if *dryRun {
+		verb = "Dry run: would import"
+	}
+	log.Printf("✅ %s %d entries (%d overwritten, %d skipped, %d failed) with %d attachments",
+		verb, report.Created+report.Overwritten, report.Overwritten, report.Skipped, report.Failed, report.Attachments)
+}
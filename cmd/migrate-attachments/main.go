@@ -0,0 +1,130 @@
+// Command migrate-attachments moves attachment contents between storage
+// backends:
+//
+//	go run ./cmd/migrate-attachments -from db -to fs
+//
+// Backend settings come from the usual configuration. Each attachment and
+// thumbnail is copied, then repointed; contents are removed from the source
+// once nothing there still uses them. It is safe to rerun after a failure.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/chrisbakker/journal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+
+	from := flag.String("from", "db", "backend to move attachments out of (db, fs or s3)")
+	to := flag.String("to", cfg.Attachments.Storage.Backend, "backend to move attachments into (db, fs or s3)")
+	flag.Parse()
+
+	if *from == *to {
+		log.Fatalf("Source and destination are both %q", *from)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	src, err := storage.ForBackend(*from, cfg.Attachments.Storage, queries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dst, err := storage.ForBackend(*to, cfg.Attachments.Storage, queries)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	attachments, err := queries.ListAttachmentsInBackend(ctx, src.Name())
+	if err != nil {
+		log.Fatalf("Failed to list attachments: %v", err)
+	}
+	log.Printf("📦 Moving %d attachments from %s to %s", len(attachments), src.Name(), dst.Name())
+
+	moved := 0
+	for _, attachment := range attachments {
+		err := move(ctx, pool, src, dst, attachment.StorageKey, attachment.SizeBytes, cfg.Attachments.Storage, func() error {
+			return queries.SetAttachmentBackend(ctx, db.SetAttachmentBackendParams{
+				ID:             attachment.ID,
+				StorageBackend: dst.Name(),
+			})
+		})
+		if err != nil {
+			log.Printf("❌ %s (%s): %v", attachment.ID.String(), attachment.Filename, err)
+			continue
+		}
+		moved++
+	}
+	log.Printf("✅ Moved %d of %d attachments", moved, len(attachments))
+
+	thumbnails, err := queries.ListThumbnailsInBackend(ctx, src.Name())
+	if err != nil {
+		log.Fatalf("Failed to list thumbnails: %v", err)
+	}
+
+	moved = 0
+	for _, thumbnail := range thumbnails {
+		err := move(ctx, pool, src, dst, thumbnail.StorageKey, thumbnail.SizeBytes, cfg.Attachments.Storage, func() error {
+			return queries.SetThumbnailBackend(ctx, db.SetThumbnailBackendParams{
+				AttachmentID:   thumbnail.AttachmentID,
+				Width:          thumbnail.Width,
+				StorageBackend: dst.Name(),
+			})
+		})
+		if err != nil {
+			log.Printf("❌ thumbnail %s@%d: %v", thumbnail.AttachmentID.String(), thumbnail.Width, err)
+			continue
+		}
+		moved++
+	}
+	log.Printf("✅ Moved %d of %d thumbnails", moved, len(thumbnails))
+}
+
+// move copies the content under key from src to dst, runs repoint to record
+// the new backend, and deletes the source copy once nothing uses it
+func move(ctx context.Context, pool *pgxpool.Pool, src, dst storage.Store, key string, size int64, cfg config.StorageConfig, repoint func() error) error {
+	queries := db.New(pool)
+	if err := storage.Acquire(ctx, queries, dst.Name(), key, size); err != nil {
+		return err
+	}
+	if err := copyAndRepoint(ctx, src, dst, key, size, repoint); err != nil {
+		release(ctx, pool, dst.Name(), key, cfg)
+		return err
+	}
+	return release(ctx, pool, src.Name(), key, cfg)
+}
+
+func copyAndRepoint(ctx context.Context, src, dst storage.Store, key string, size int64, repoint func() error) error {
+	content, err := src.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = dst.Put(ctx, key, content, size)
+	content.Close()
+	if err != nil {
+		return err
+	}
+	return repoint()
+}
+
+// release drops one use of the content under key in a backend, deleting it
+// if that was the last
+func release(ctx context.Context, pool *pgxpool.Pool, backend, key string, cfg config.StorageConfig) error {
+	last, err := storage.Release(ctx, db.New(pool), backend, key)
+	if err != nil || !last {
+		return err
+	}
+	return storage.Purge(ctx, pool, backend, key, cfg)
+}
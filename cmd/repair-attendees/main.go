@@ -0,0 +1,45 @@
+// Command repair-attendees rebuilds attendee use counts from entry membership.
+// Counts written before they were derived from entries can drift (edits and
+// deletes never decremented them); this brings them back in line.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	queries := db.New(pool).WithTx(tx)
+
+	created, err := queries.BackfillAttendees(ctx)
+	if err != nil {
+		log.Fatalf("Failed to backfill attendees: %v", err)
+	}
+	recounted, err := queries.RecountAllAttendees(ctx)
+	if err != nil {
+		log.Fatalf("Failed to recount attendees: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Fatalf("Failed to commit: %v", err)
+	}
+
+	log.Printf("✅ Created %d missing attendees, recounted %d", created, recounted)
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/chrisbakker/journal/api"
+	"github.com/chrisbakker/journal/cleanup"
 	"github.com/chrisbakker/journal/config"
 	db "github.com/chrisbakker/journal/generated"
 	"github.com/chrisbakker/journal/ollama"
+	"github.com/chrisbakker/journal/scheduler"
 	"github.com/chrisbakker/journal/vectorservice"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,6 +30,8 @@ type AppResources struct {
 	queries      *db.Queries
 	ollamaClient *ollama.Client
 	vectorSvc    *vectorservice.VectorService
+	scheduler    *scheduler.Scheduler
+	cleaner      *cleanup.Cleaner
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
@@ -84,6 +89,16 @@ func (app *AppResources) Reload() error {
 		app.vectorSvc.Stop()
 	}
 
+	// Stop existing recurrence scheduler
+	if app.scheduler != nil {
+		app.scheduler.Stop()
+	}
+
+	// Stop existing attachment cleanup
+	if app.cleaner != nil {
+		app.cleaner.Stop()
+	}
+
 	// Cancel existing context
 	if app.cancel != nil {
 		app.cancel()
@@ -149,12 +164,35 @@ func (app *AppResources) Reload() error {
 		log.Println("✅ Restarted background vector update service")
 	}
 
+	// Restart recurrence scheduler
+	recurrenceScheduler := scheduler.New(
+		queries,
+		api.NewHandler(dbpool, queries, newCfg.App.DefaultTimezone, vectorSvc, ollamaClient),
+		newCfg.App.DefaultTimezone,
+		5*time.Minute,
+	)
+	recurrenceScheduler.Start(ctx)
+	log.Println("✅ Restarted recurrence scheduler")
+
+	// Restart attachment cleanup
+	cleaner := cleanup.New(
+		dbpool,
+		queries,
+		api.NewHandler(dbpool, queries, newCfg.App.DefaultTimezone, vectorSvc, ollamaClient),
+		newCfg.Attachments,
+		time.Hour,
+	)
+	cleaner.Start(ctx)
+	log.Println("✅ Restarted attachment cleanup")
+
 	// Update all resources
 	app.config = newCfg
 	app.dbpool = dbpool
 	app.queries = queries
 	app.ollamaClient = ollamaClient
 	app.vectorSvc = vectorSvc
+	app.scheduler = recurrenceScheduler
+	app.cleaner = cleaner
 	app.ctx = ctx
 	app.cancel = cancel
 
@@ -169,6 +207,12 @@ func (app *AppResources) getQueries() *db.Queries {
 	return app.queries
 }
 
+func (app *AppResources) getDBPool() *pgxpool.Pool {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	return app.dbpool
+}
+
 func (app *AppResources) getConfig() *config.Config {
 	app.mu.RLock()
 	defer app.mu.RUnlock()
@@ -226,6 +270,8 @@ func runServer() {
 	var queries *db.Queries
 	var ollamaClient *ollama.Client
 	var vectorSvc *vectorservice.VectorService
+	var recurrenceScheduler *scheduler.Scheduler
+	var cleaner *cleanup.Cleaner
 
 	if validationResult.Valid {
 		dbpool, err = pgxpool.New(ctx, cfg.Database.URL)
@@ -269,6 +315,27 @@ func runServer() {
 					vectorSvc.Start(ctx)
 					log.Println("Started background vector update service")
 				}
+
+				// Start recurrence scheduler
+				recurrenceScheduler = scheduler.New(
+					queries,
+					api.NewHandler(dbpool, queries, cfg.App.DefaultTimezone, vectorSvc, ollamaClient),
+					cfg.App.DefaultTimezone,
+					5*time.Minute,
+				)
+				recurrenceScheduler.Start(ctx)
+				log.Println("Started recurrence scheduler")
+
+				// Start attachment cleanup
+				cleaner = cleanup.New(
+					dbpool,
+					queries,
+					api.NewHandler(dbpool, queries, cfg.App.DefaultTimezone, vectorSvc, ollamaClient),
+					cfg.Attachments,
+					time.Hour,
+				)
+				cleaner.Start(ctx)
+				log.Println("Started attachment cleanup")
 			}
 		}
 	} // Store resources in app
@@ -277,6 +344,8 @@ func runServer() {
 	app.queries = queries
 	app.ollamaClient = ollamaClient
 	app.vectorSvc = vectorSvc
+	app.scheduler = recurrenceScheduler
+	app.cleaner = cleaner
 	app.ctx = ctx
 	app.cancel = cancel
 
@@ -331,37 +400,118 @@ func runServer() {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.ListEntriesForDay(c)
 		})
 		apiGroup.POST("/entries", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.CreateEntry(c)
 		})
 		apiGroup.PATCH("/entries/:id", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.UpdateEntry(c)
 		})
 		apiGroup.DELETE("/entries/:id", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.DeleteEntry(c)
 		})
+		apiGroup.GET("/entries/:id/backlinks", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetBacklinks(c)
+		})
+
+		// Pinned and favourite entries
+		apiGroup.GET("/pinned", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListPinnedEntries(c)
+		})
+		apiGroup.PUT("/pinned/order", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ReorderPinnedEntries(c)
+		})
+		apiGroup.POST("/entries/:id/pin", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.PinEntry(c)
+		})
+		apiGroup.POST("/entries/:id/unpin", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UnpinEntry(c)
+		})
+		apiGroup.GET("/favorites", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListFavoriteEntries(c)
+		})
+		apiGroup.PUT("/favorites/order", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ReorderFavoriteEntries(c)
+		})
+		apiGroup.POST("/entries/:id/favorite", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.FavoriteEntry(c)
+		})
+		apiGroup.POST("/entries/:id/unfavorite", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UnfavoriteEntry(c)
+		})
+
+		// Profile settings
+		apiGroup.GET("/me", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetProfile(c)
+		})
+		apiGroup.PATCH("/me", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdateProfile(c)
+		})
 
 		// Search
 		apiGroup.GET("/search", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.SearchEntries(c)
 		})
 
@@ -370,16 +520,196 @@ func runServer() {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.SearchAttendees(c)
 		})
 
+		// Entry types
+		apiGroup.GET("/entry-types", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListEntryTypes(c)
+		})
+		apiGroup.POST("/entry-types", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.CreateEntryType(c)
+		})
+		apiGroup.PATCH("/entry-types/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdateEntryType(c)
+		})
+		apiGroup.DELETE("/entry-types/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.DeleteEntryType(c)
+		})
+
+		// Templates
+		apiGroup.GET("/templates", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListTemplates(c)
+		})
+		apiGroup.POST("/templates", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.CreateTemplate(c)
+		})
+		apiGroup.GET("/templates/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetTemplate(c)
+		})
+		apiGroup.PATCH("/templates/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdateTemplate(c)
+		})
+		apiGroup.DELETE("/templates/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.DeleteTemplate(c)
+		})
+
+		// Recurring series
+		apiGroup.GET("/recurrences", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListRecurrences(c)
+		})
+		apiGroup.POST("/recurrences", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.CreateRecurrence(c)
+		})
+		apiGroup.PATCH("/recurrences/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdateRecurrence(c)
+		})
+		apiGroup.POST("/recurrences/:id/pause", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.PauseRecurrence(c)
+		})
+		apiGroup.POST("/recurrences/:id/resume", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ResumeRecurrence(c)
+		})
+		apiGroup.DELETE("/recurrences/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.DeleteRecurrence(c)
+		})
+
+		// People
+		apiGroup.GET("/people", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListPeople(c)
+		})
+		apiGroup.GET("/people/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetPerson(c)
+		})
+		apiGroup.PATCH("/people/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdatePerson(c)
+		})
+		apiGroup.POST("/people/:id/rename", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.RenamePerson(c)
+		})
+		apiGroup.POST("/people/:id/merge", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.MergePeople(c)
+		})
+
+		// Tags
+		apiGroup.GET("/tags", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListTags(c)
+		})
+		apiGroup.GET("/tags/search", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.SearchTags(c)
+		})
+
+		// Tasks
+		apiGroup.GET("/tasks", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListTasks(c)
+		})
+		apiGroup.PATCH("/tasks/:id", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UpdateTask(c)
+		})
+
 		// Chat (Phase 3 - RAG)
 		apiGroup.POST("/chat", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.Chat(c)
 		})
 
@@ -388,22 +718,43 @@ func runServer() {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
-			handler.UploadAttachment(c)
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.UploadAttachment(c, app.getConfig().Attachments)
+		})
+		apiGroup.GET("/entries/:id/attachments", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListAttachments(c)
+		})
+		apiGroup.GET("/attachments/orphans", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ListOrphanedAttachments(c)
 		})
 		apiGroup.GET("/attachments/:id", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
-			handler.GetAttachment(c)
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetAttachment(c, app.getConfig().Attachments)
+		})
+		apiGroup.GET("/attachments/:id/thumbnail", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.GetAttachmentThumbnail(c, app.getConfig().Attachments)
 		})
 		apiGroup.DELETE("/attachments/:id", func(c *gin.Context) {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
-			handler.DeleteAttachment(c)
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.DeleteAttachment(c, app.getConfig().Attachments)
 		})
 
 		// Calendar
@@ -411,7 +762,7 @@ func runServer() {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
 			handler.GetDaysWithEntries(c)
 		})
 
@@ -420,8 +771,17 @@ func runServer() {
 			if !requireResources(c) {
 				return
 			}
-			handler := api.NewHandler(app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
-			handler.ExportEntries(c)
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ExportEntries(c, app.getConfig().Attachments)
+		})
+
+		// Import
+		apiGroup.POST("/import", func(c *gin.Context) {
+			if !requireResources(c) {
+				return
+			}
+			handler := api.NewHandler(app.getDBPool(), app.getQueries(), app.getConfig().App.DefaultTimezone, app.getVectorService(), app.getOllamaClient())
+			handler.ImportEntries(c, app.getConfig().Attachments)
 		})
 	}
 
@@ -0,0 +1,46 @@
+// Command sync-derived re-derives the tasks and links of every entry from its
+// body. Entries saved before tasks or links were derived have none until they
+// are edited, so they are missing from /api/tasks, backlinks and export link
+// rewriting; this fills them in. Each entry is synced in its own transaction,
+// so it is safe to rerun and to run while the server is up.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/chrisbakker/journal/api"
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	ids, err := queries.ListEntryIDs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list entries: %v", err)
+	}
+	log.Printf("🔄 Syncing %d entries", len(ids))
+
+	entries := api.NewEntryService(pool, queries)
+	synced := 0
+	for _, id := range ids {
+		if err := entries.SyncDerived(ctx, id); err != nil {
+			log.Printf("❌ %s: %v", id.String(), err)
+			continue
+		}
+		synced++
+	}
+
+	log.Printf("✅ Synced %d of %d entries", synced, len(ids))
+}
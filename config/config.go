@@ -13,12 +13,13 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	SPA      SPAConfig
-	App      AppConfig
-	LLM      LLMConfig
-	CORS     CORSConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	SPA         SPAConfig
+	App         AppConfig
+	LLM         LLMConfig
+	CORS        CORSConfig
+	Attachments AttachmentsConfig
 }
 
 type ServerConfig struct {
@@ -55,6 +56,42 @@ type CORSConfig struct {
 	MaxAge           time.Duration
 }
 
+// AttachmentsConfig limits what can be uploaded. QuotaMB is the total size of
+// a user's attachments; a negative quota means unlimited. AllowedTypes are
+// MIME types, optionally with a "type/*" wildcard, checked against the sniffed
+// content type. StripGPS removes GPS location from the EXIF metadata of
+// uploaded JPEGs. OrphanMode is what the cleanup job does with attachments
+// no longer referenced from their entry's body for OrphanGraceHours: "off",
+// "report" (log them) or "delete".
+type AttachmentsConfig struct {
+	MaxUploadMB      int
+	QuotaMB          int
+	AllowedTypes     []string
+	StripGPS         bool
+	OrphanMode       string
+	OrphanGraceHours int
+	Storage          StorageConfig
+}
+
+// StorageConfig selects where attachment contents are kept: "db" (the
+// attachment_blobs table), "fs" (a content-addressed directory under Dir) or
+// "s3" (any S3-compatible object store, addressed path-style)
+type StorageConfig struct {
+	Backend     string
+	Dir         string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// DefaultAllowedAttachmentTypes are the MIME types accepted when none are configured
+var DefaultAllowedAttachmentTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"application/pdf", "text/plain", "text/markdown", "text/csv",
+}
+
 // Load loads configuration with the following priority:
 // 1. Environment variables (highest priority)
 // 2. Config file (user config dir or --config flag)
@@ -85,6 +122,9 @@ func Load() *Config {
 	if envDB := os.Getenv("DATABASE_URL"); envDB != "" {
 		cfg.Database.URL = envDB
 	}
+	if envTZ := os.Getenv("DEFAULT_TIMEZONE"); envTZ != "" {
+		cfg.App.DefaultTimezone = envTZ
+	}
 	if envOllama := os.Getenv("OLLAMA_BASE_URL"); envOllama != "" {
 		cfg.LLM.OllamaBaseURL = envOllama
 	}
@@ -102,6 +142,22 @@ func Load() *Config {
 	if envCORS := os.Getenv("CORS_ORIGINS"); envCORS != "" {
 		cfg.CORS.AllowedOrigins = parseCORSOrigins(envCORS)
 	}
+	cfg.Attachments.MaxUploadMB = getEnvInt("ATTACHMENT_MAX_UPLOAD_MB", cfg.Attachments.MaxUploadMB)
+	cfg.Attachments.QuotaMB = getEnvInt("ATTACHMENT_QUOTA_MB", cfg.Attachments.QuotaMB)
+	if envTypes := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); envTypes != "" {
+		cfg.Attachments.AllowedTypes = parseCORSOrigins(envTypes)
+	}
+	cfg.Attachments.StripGPS = getEnvBool("ATTACHMENT_STRIP_GPS", cfg.Attachments.StripGPS)
+	cfg.Attachments.OrphanMode = getEnv("ATTACHMENT_ORPHAN_MODE", cfg.Attachments.OrphanMode)
+	cfg.Attachments.OrphanGraceHours = getEnvInt("ATTACHMENT_ORPHAN_GRACE_HOURS", cfg.Attachments.OrphanGraceHours)
+	storage := &cfg.Attachments.Storage
+	storage.Backend = getEnv("STORAGE_BACKEND", storage.Backend)
+	storage.Dir = getEnv("STORAGE_DIR", storage.Dir)
+	storage.S3Endpoint = getEnv("S3_ENDPOINT", storage.S3Endpoint)
+	storage.S3Region = getEnv("S3_REGION", storage.S3Region)
+	storage.S3Bucket = getEnv("S3_BUCKET", storage.S3Bucket)
+	storage.S3AccessKey = getEnv("S3_ACCESS_KEY_ID", storage.S3AccessKey)
+	storage.S3SecretKey = getEnv("S3_SECRET_ACCESS_KEY", storage.S3SecretKey)
 
 	return cfg
 }
@@ -136,6 +192,19 @@ func getDefaultConfig() *Config {
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
 		},
+		Attachments: AttachmentsConfig{
+			MaxUploadMB:      25,
+			QuotaMB:          1024,
+			AllowedTypes:     DefaultAllowedAttachmentTypes,
+			StripGPS:         true,
+			OrphanMode:       "report",
+			OrphanGraceHours: 168,
+			Storage: StorageConfig{
+				Backend:  "db",
+				Dir:      "data/attachments",
+				S3Region: "us-east-1",
+			},
+		},
 	}
 }
 
@@ -335,6 +404,23 @@ func loadEnvFormat(data []byte) (*Config, error) {
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
 		},
+		Attachments: AttachmentsConfig{
+			MaxUploadMB:      getIntFromMap(envMap, "ATTACHMENT_MAX_UPLOAD_MB", 25),
+			QuotaMB:          getIntFromMap(envMap, "ATTACHMENT_QUOTA_MB", 1024),
+			AllowedTypes:     parseCORSOrigins(getFromMap(envMap, "ATTACHMENT_ALLOWED_TYPES", strings.Join(DefaultAllowedAttachmentTypes, ","))),
+			StripGPS:         getBoolFromMap(envMap, "ATTACHMENT_STRIP_GPS", true),
+			OrphanMode:       getFromMap(envMap, "ATTACHMENT_ORPHAN_MODE", "report"),
+			OrphanGraceHours: getIntFromMap(envMap, "ATTACHMENT_ORPHAN_GRACE_HOURS", 168),
+			Storage: StorageConfig{
+				Backend:     getFromMap(envMap, "STORAGE_BACKEND", "db"),
+				Dir:         getFromMap(envMap, "STORAGE_DIR", "data/attachments"),
+				S3Endpoint:  getFromMap(envMap, "S3_ENDPOINT", ""),
+				S3Region:    getFromMap(envMap, "S3_REGION", "us-east-1"),
+				S3Bucket:    getFromMap(envMap, "S3_BUCKET", ""),
+				S3AccessKey: getFromMap(envMap, "S3_ACCESS_KEY_ID", ""),
+				S3SecretKey: getFromMap(envMap, "S3_SECRET_ACCESS_KEY", ""),
+			},
+		},
 	}
 
 	return cfg, nil
@@ -406,6 +492,30 @@ func applyDefaults(cfg *Config) {
 		cfg.CORS.MaxAge = 12 * time.Hour
 	}
 	cfg.CORS.AllowCredentials = true
+	if cfg.Attachments.MaxUploadMB == 0 {
+		cfg.Attachments.MaxUploadMB = 25
+	}
+	if cfg.Attachments.QuotaMB == 0 {
+		cfg.Attachments.QuotaMB = 1024
+	}
+	if len(cfg.Attachments.AllowedTypes) == 0 {
+		cfg.Attachments.AllowedTypes = DefaultAllowedAttachmentTypes
+	}
+	if cfg.Attachments.OrphanMode == "" {
+		cfg.Attachments.OrphanMode = "report"
+	}
+	if cfg.Attachments.OrphanGraceHours == 0 {
+		cfg.Attachments.OrphanGraceHours = 168
+	}
+	if cfg.Attachments.Storage.Backend == "" {
+		cfg.Attachments.Storage.Backend = "db"
+	}
+	if cfg.Attachments.Storage.Dir == "" {
+		cfg.Attachments.Storage.Dir = "data/attachments"
+	}
+	if cfg.Attachments.Storage.S3Region == "" {
+		cfg.Attachments.Storage.S3Region = "us-east-1"
+	}
 }
 
 // SaveConfigFile writes the configuration to a file
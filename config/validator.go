@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // ValidationError represents a configuration validation error
@@ -34,6 +35,44 @@ func (c *Config) Validate() *ValidationResult {
 		}
 	}
 
+	// Validate default timezone (used for users without their own setting)
+	if _, err := time.LoadLocation(c.App.DefaultTimezone); err != nil || c.App.DefaultTimezone == "" {
+		result.addError("DEFAULT_TIMEZONE", "Default timezone must be a valid IANA timezone name")
+	}
+
+	if c.Attachments.MaxUploadMB <= 0 {
+		result.addError("ATTACHMENT_MAX_UPLOAD_MB", "Maximum upload size must be positive")
+	}
+
+	switch c.Attachments.OrphanMode {
+	case "off", "report", "delete":
+	default:
+		result.addError("ATTACHMENT_ORPHAN_MODE", "Orphan mode must be off, report or delete")
+	}
+	if c.Attachments.OrphanGraceHours <= 0 {
+		result.addError("ATTACHMENT_ORPHAN_GRACE_HOURS", "Orphan grace period must be positive")
+	}
+
+	switch storage := c.Attachments.Storage; storage.Backend {
+	case "db":
+	case "fs":
+		if storage.Dir == "" {
+			result.addError("STORAGE_DIR", "Storage directory is required for the fs backend")
+		}
+	case "s3":
+		if u, err := url.Parse(storage.S3Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			result.addError("S3_ENDPOINT", "S3 endpoint must be a URL such as http://localhost:9000")
+		}
+		if storage.S3Bucket == "" {
+			result.addError("S3_BUCKET", "S3 bucket is required for the s3 backend")
+		}
+		if storage.S3AccessKey == "" || storage.S3SecretKey == "" {
+			result.addError("S3_ACCESS_KEY_ID", "S3 credentials are required for the s3 backend")
+		}
+	default:
+		result.addError("STORAGE_BACKEND", "Storage backend must be db, fs or s3")
+	}
+
 	// Validate Ollama URL if vector search is enabled
 	if c.LLM.EnableVectorSearch {
 		if c.LLM.OllamaBaseURL == "" {
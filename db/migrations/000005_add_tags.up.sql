@@ -0,0 +1,21 @@
+-- Create tags table to store unique, user-defined tag names
+CREATE TABLE tags (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name       TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, name)
+);
+
+-- Create join table between entries and tags
+CREATE TABLE entry_tags (
+  entry_id UUID NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+  tag_id   UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (entry_id, tag_id)
+);
+
+-- Create indexes for autocomplete and tag-based browsing
+CREATE INDEX idx_tags_user_name ON tags(user_id, name);
+CREATE INDEX idx_tags_last_used ON tags(user_id, last_used DESC);
+CREATE INDEX idx_entry_tags_tag ON entry_tags(tag_id);
@@ -0,0 +1,10 @@
+-- Restore the fixed type list; custom types fall back to 'other'
+ALTER TABLE entries DROP CONSTRAINT IF EXISTS entries_type_fkey;
+UPDATE entries SET type = 'other' WHERE type NOT IN ('meeting','notes','other');
+ALTER TABLE entries
+  ADD CONSTRAINT entries_type_check CHECK (type IN ('meeting','notes','other'));
+
+-- Drop entry types
+DROP TRIGGER IF EXISTS users_seed_entry_types ON users;
+DROP FUNCTION IF EXISTS seed_default_entry_types();
+DROP TABLE IF EXISTS entry_types;
@@ -0,0 +1,49 @@
+-- Create per-user entry types to replace the fixed CHECK constraint
+CREATE TABLE entry_types (
+  id                     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id                UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name                   TEXT NOT NULL,
+  color                  TEXT NOT NULL DEFAULT '',
+  icon                   TEXT NOT NULL DEFAULT '',
+  default_template_delta JSONB,
+  has_attendees          BOOLEAN NOT NULL DEFAULT FALSE,
+  position               INT NOT NULL DEFAULT 0,
+  created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, name)
+);
+
+CREATE INDEX idx_entry_types_user_position ON entry_types(user_id, position);
+
+-- Seed the built-in types for every user
+CREATE FUNCTION seed_default_entry_types() RETURNS TRIGGER AS $$
+BEGIN
+  INSERT INTO entry_types (user_id, name, color, icon, has_attendees, position)
+  VALUES
+    (NEW.id, 'meeting', '#3b82f6', 'users', TRUE, 0),
+    (NEW.id, 'notes', '#10b981', 'note', FALSE, 1),
+    (NEW.id, 'other', '#6b7280', 'dots', FALSE, 2)
+  ON CONFLICT (user_id, name) DO NOTHING;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_seed_entry_types
+  AFTER INSERT ON users
+  FOR EACH ROW EXECUTE FUNCTION seed_default_entry_types();
+
+INSERT INTO entry_types (user_id, name, color, icon, has_attendees, position)
+SELECT u.id, t.name, t.color, t.icon, t.has_attendees, t.position
+FROM users u
+CROSS JOIN (VALUES
+  ('meeting', '#3b82f6', 'users', TRUE, 0),
+  ('notes', '#10b981', 'note', FALSE, 1),
+  ('other', '#6b7280', 'dots', FALSE, 2)
+) AS t(name, color, icon, has_attendees, position);
+
+-- Entries now reference the user's entry types by name; renames cascade
+ALTER TABLE entries DROP CONSTRAINT IF EXISTS entries_type_check;
+ALTER TABLE entries
+  ADD CONSTRAINT entries_type_fkey
+  FOREIGN KEY (user_id, type) REFERENCES entry_types(user_id, name)
+  ON UPDATE CASCADE;
@@ -0,0 +1,17 @@
+-- Create entry templates (e.g. standups, 1:1s)
+CREATE TABLE templates (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name              TEXT NOT NULL,
+  title_pattern     TEXT NOT NULL DEFAULT '',
+  body_delta        JSONB NOT NULL DEFAULT '{"ops":[{"insert":"\n"}]}',
+  default_type      TEXT,
+  default_attendees TEXT NOT NULL DEFAULT '',
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, name),
+  FOREIGN KEY (user_id, default_type) REFERENCES entry_types(user_id, name)
+    ON UPDATE CASCADE
+);
+
+CREATE INDEX idx_templates_user_name ON templates(user_id, name);
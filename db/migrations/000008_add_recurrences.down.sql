@@ -0,0 +1,3 @@
+-- Drop recurrence tables
+DROP TABLE IF EXISTS recurrence_occurrences;
+DROP TABLE IF EXISTS recurrences;
@@ -0,0 +1,25 @@
+-- Create recurrence rules that materialize entries from a template
+CREATE TABLE recurrences (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  template_id UUID NOT NULL REFERENCES templates(id) ON DELETE CASCADE,
+  rrule       TEXT NOT NULL,
+  starts_on   DATE NOT NULL,
+  ends_on     DATE,
+  paused      BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Track which occurrences have been materialized so each day is created once,
+-- even if the user later deletes the generated entry
+CREATE TABLE recurrence_occurrences (
+  recurrence_id UUID NOT NULL REFERENCES recurrences(id) ON DELETE CASCADE,
+  occurs_on     DATE NOT NULL,
+  entry_id      UUID REFERENCES entries(id) ON DELETE SET NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (recurrence_id, occurs_on)
+);
+
+CREATE INDEX idx_recurrences_user ON recurrences(user_id, created_at);
+CREATE INDEX idx_recurrences_active ON recurrences(paused) WHERE paused = false;
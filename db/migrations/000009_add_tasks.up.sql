@@ -0,0 +1,18 @@
+-- Action items parsed from entry bodies (Quill checklists and TODO lines)
+CREATE TABLE tasks (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  entry_id     UUID NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+  position     INT NOT NULL,
+  text         TEXT NOT NULL,
+  done         BOOLEAN NOT NULL DEFAULT FALSE,
+  due_date     DATE,
+  owner        TEXT,
+  completed_at TIMESTAMPTZ,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (entry_id, position)
+);
+
+CREATE INDEX idx_tasks_user_open ON tasks(user_id, due_date) WHERE done = false;
+CREATE INDEX idx_tasks_user_owner ON tasks(user_id, owner);
@@ -0,0 +1,2 @@
+-- Drop entry links
+DROP TABLE IF EXISTS entry_links;
@@ -0,0 +1,13 @@
+-- Links between entries: [[Title]] wiki links and links to an entry's URL
+CREATE TABLE entry_links (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  source_entry_id UUID NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+  target_entry_id UUID REFERENCES entries(id) ON DELETE SET NULL,
+  target_title    TEXT NOT NULL DEFAULT '', -- '' for links by ID
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_entry_links_source ON entry_links(source_entry_id);
+CREATE INDEX idx_entry_links_target ON entry_links(target_entry_id);
+CREATE INDEX idx_entry_links_dangling ON entry_links(user_id, lower(target_title)) WHERE target_entry_id IS NULL;
@@ -0,0 +1,2 @@
+-- Drop entry flags
+DROP TABLE IF EXISTS entry_flags;
@@ -0,0 +1,14 @@
+-- Pinned and favourite entries, each with a user-defined order
+CREATE TABLE entry_flags (
+  entry_id          UUID PRIMARY KEY REFERENCES entries(id) ON DELETE CASCADE,
+  user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  pinned            BOOLEAN NOT NULL DEFAULT FALSE,
+  pinned_position   INT NOT NULL DEFAULT 0,
+  favorite          BOOLEAN NOT NULL DEFAULT FALSE,
+  favorite_position INT NOT NULL DEFAULT 0,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_entry_flags_pinned ON entry_flags(user_id, pinned_position) WHERE pinned;
+CREATE INDEX idx_entry_flags_favorite ON entry_flags(user_id, favorite_position) WHERE favorite;
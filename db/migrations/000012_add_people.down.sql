@@ -0,0 +1,7 @@
+-- Drop person profile columns
+ALTER TABLE attendees
+  DROP COLUMN IF EXISTS email,
+  DROP COLUMN IF EXISTS role,
+  DROP COLUMN IF EXISTS notes,
+  DROP COLUMN IF EXISTS aliases,
+  DROP COLUMN IF EXISTS updated_at;
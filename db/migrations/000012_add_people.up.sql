@@ -0,0 +1,7 @@
+-- Turn attendees into person profiles
+ALTER TABLE attendees
+  ADD COLUMN email      TEXT NOT NULL DEFAULT '',
+  ADD COLUMN role       TEXT NOT NULL DEFAULT '',
+  ADD COLUMN notes      TEXT NOT NULL DEFAULT '',
+  ADD COLUMN aliases    TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,21 @@
+-- Move attachment contents back into the attachments table. Attachments in
+-- other backends must be moved to the database backend first
+-- (cmd/migrate-attachments), or they are dropped here.
+ALTER TABLE attachments ADD COLUMN data BYTEA;
+
+UPDATE attachments a
+SET data = b.data
+FROM attachment_blobs b
+WHERE a.storage_backend = 'db' AND b.key = a.storage_key;
+
+DELETE FROM attachments WHERE data IS NULL;
+
+ALTER TABLE attachments ALTER COLUMN data SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_attachments_storage_key;
+
+ALTER TABLE attachments
+  DROP COLUMN IF EXISTS storage_backend,
+  DROP COLUMN IF EXISTS storage_key;
+
+DROP TABLE IF EXISTS attachment_blobs;
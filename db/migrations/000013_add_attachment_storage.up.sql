@@ -0,0 +1,24 @@
+-- Attachment contents move out of the attachments table into a storage
+-- backend, addressed by the SHA-256 of the content. The database backend
+-- keeps them in attachment_blobs.
+CREATE TABLE attachment_blobs (
+  key        TEXT PRIMARY KEY,
+  data       BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE attachments
+  ADD COLUMN storage_backend TEXT NOT NULL DEFAULT 'db',
+  ADD COLUMN storage_key     TEXT NOT NULL DEFAULT '';
+
+UPDATE attachments SET storage_key = encode(sha256(data), 'hex');
+
+INSERT INTO attachment_blobs (key, data)
+SELECT DISTINCT ON (storage_key) storage_key, data
+FROM attachments
+ORDER BY storage_key
+ON CONFLICT (key) DO NOTHING;
+
+ALTER TABLE attachments DROP COLUMN data;
+
+CREATE INDEX idx_attachments_storage_key ON attachments(storage_backend, storage_key);
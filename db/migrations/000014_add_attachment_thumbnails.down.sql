@@ -0,0 +1,2 @@
+-- Drop attachment thumbnails
+DROP TABLE IF EXISTS attachment_thumbnails;
@@ -0,0 +1,14 @@
+-- Downscaled copies of image attachments, stored like attachment contents
+CREATE TABLE attachment_thumbnails (
+  attachment_id   UUID NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+  width           INT NOT NULL,
+  height          INT NOT NULL,
+  mime_type       TEXT NOT NULL,
+  size_bytes      BIGINT NOT NULL,
+  storage_backend TEXT NOT NULL,
+  storage_key     TEXT NOT NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (attachment_id, width)
+);
+
+CREATE INDEX idx_attachment_thumbnails_storage_key ON attachment_thumbnails(storage_backend, storage_key);
@@ -0,0 +1,2 @@
+-- Drop extracted attachment text
+DROP TABLE IF EXISTS attachment_texts;
@@ -0,0 +1,11 @@
+-- Text extracted from attachments, for keyword search and Chat retrieval.
+-- A row with empty content records an attachment with no extractable text.
+CREATE TABLE attachment_texts (
+  attachment_id      UUID PRIMARY KEY REFERENCES attachments(id) ON DELETE CASCADE,
+  content            TEXT NOT NULL,
+  embedding_vector   vector(768),
+  vectors_updated_at TIMESTAMPTZ,
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON attachment_texts USING ivfflat (embedding_vector vector_cosine_ops) WITH (lists = 100);
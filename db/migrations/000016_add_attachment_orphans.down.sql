@@ -0,0 +1,3 @@
+-- Remove attachment orphan tracking
+DROP INDEX IF EXISTS idx_attachments_orphaned_at;
+ALTER TABLE attachments DROP COLUMN IF EXISTS orphaned_at;
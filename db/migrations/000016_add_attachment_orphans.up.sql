@@ -0,0 +1,5 @@
+-- When the cleanup job first found an attachment unreferenced by its entry's
+-- body; cleared if the reference comes back
+ALTER TABLE attachments ADD COLUMN orphaned_at TIMESTAMPTZ;
+
+CREATE INDEX idx_attachments_orphaned_at ON attachments(orphaned_at) WHERE orphaned_at IS NOT NULL;
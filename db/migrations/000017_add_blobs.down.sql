@@ -0,0 +1,4 @@
+ALTER TABLE attachment_thumbnails DROP CONSTRAINT IF EXISTS attachment_thumbnails_blob_fkey;
+ALTER TABLE attachments DROP CONSTRAINT IF EXISTS attachments_blob_fkey;
+
+DROP TABLE IF EXISTS blobs;
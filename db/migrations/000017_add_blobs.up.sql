@@ -0,0 +1,31 @@
+-- Every stored object, in whichever backend, with the number of attachments
+-- and thumbnails using it. Identical files share one blob; its contents are
+-- deleted when the last use is released.
+CREATE TABLE blobs (
+  storage_backend TEXT NOT NULL,
+  storage_key     TEXT NOT NULL,
+  size_bytes      BIGINT NOT NULL,
+  ref_count       INT NOT NULL DEFAULT 0 CHECK (ref_count >= 0),
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (storage_backend, storage_key)
+);
+
+CREATE INDEX idx_blobs_unused ON blobs(storage_backend, storage_key) WHERE ref_count = 0;
+
+INSERT INTO blobs (storage_backend, storage_key, size_bytes, ref_count)
+SELECT storage_backend, storage_key, MAX(size_bytes), COUNT(*)
+FROM (
+  SELECT storage_backend, storage_key, size_bytes FROM attachments
+  UNION ALL
+  SELECT storage_backend, storage_key, size_bytes FROM attachment_thumbnails
+) uses
+GROUP BY storage_backend, storage_key;
+
+ALTER TABLE attachments
+  ADD CONSTRAINT attachments_blob_fkey
+  FOREIGN KEY (storage_backend, storage_key) REFERENCES blobs(storage_backend, storage_key);
+
+ALTER TABLE attachment_thumbnails
+  ADD CONSTRAINT attachment_thumbnails_blob_fkey
+  FOREIGN KEY (storage_backend, storage_key) REFERENCES blobs(storage_backend, storage_key);
+
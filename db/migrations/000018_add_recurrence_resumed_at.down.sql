@@ -0,0 +1,2 @@
+-- Remove recurrence resume tracking
+ALTER TABLE recurrences DROP COLUMN IF EXISTS resumed_at;
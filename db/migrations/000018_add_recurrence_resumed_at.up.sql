@@ -0,0 +1,3 @@
+-- When a paused series was last resumed; the scheduler never materializes
+-- days before it, so pausing skips those days rather than deferring them
+ALTER TABLE recurrences ADD COLUMN resumed_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Remove attachment reference tracking
+ALTER TABLE attachments DROP COLUMN IF EXISTS referenced_at;
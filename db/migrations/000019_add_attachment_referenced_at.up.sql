@@ -0,0 +1,13 @@
+-- When an attachment was first found referenced from its entry's body. Only
+-- attachments that once were can be orphaned: files never embedded, such as
+-- API uploads and imported media, are left alone.
+ALTER TABLE attachments ADD COLUMN referenced_at TIMESTAMPTZ;
+
+UPDATE attachments a
+SET referenced_at = NOW()
+FROM entries e
+WHERE e.id = a.entry_id AND e.body_delta::text ILIKE '%attachments%' || a.id::text || '%';
+
+-- Existing orphans can't be told apart from files never embedded, so they
+-- start over
+UPDATE attachments SET orphaned_at = NULL WHERE referenced_at IS NULL;
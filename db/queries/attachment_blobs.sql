@@ -0,0 +1,12 @@
+-- name: PutAttachmentBlob :exec
+INSERT INTO attachment_blobs (key, data)
+VALUES ($1, $2)
+ON CONFLICT (key) DO NOTHING;
+
+-- name: GetAttachmentBlob :one
+SELECT data FROM attachment_blobs
+WHERE key = $1;
+
+-- name: DeleteAttachmentBlob :exec
+DELETE FROM attachment_blobs
+WHERE key = $1;
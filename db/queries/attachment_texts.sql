@@ -0,0 +1,16 @@
+-- name: CreateAttachmentText :exec
+-- Replacing the text clears its embedding so it is recomputed
+INSERT INTO attachment_texts (attachment_id, content)
+VALUES ($1, $2)
+ON CONFLICT (attachment_id) DO UPDATE
+SET content = EXCLUDED.content,
+    embedding_vector = NULL,
+    vectors_updated_at = NULL;
+
+-- name: ListAttachmentsNeedingText :many
+SELECT a.* FROM attachments a
+WHERE a.mime_type = ANY(sqlc.arg(mime_types)::text[])
+  AND NOT EXISTS (
+    SELECT 1 FROM attachment_texts t WHERE t.attachment_id = a.id
+  )
+ORDER BY a.created_at ASC;
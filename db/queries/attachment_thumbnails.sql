@@ -0,0 +1,28 @@
+-- name: CreateAttachmentThumbnail :execrows
+INSERT INTO attachment_thumbnails (
+  attachment_id,
+  width,
+  height,
+  mime_type,
+  size_bytes,
+  storage_backend,
+  storage_key
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (attachment_id, width) DO NOTHING;
+
+-- name: ListAttachmentThumbnails :many
+SELECT * FROM attachment_thumbnails
+WHERE attachment_id = $1
+ORDER BY width ASC;
+
+-- name: ListThumbnailsInBackend :many
+SELECT * FROM attachment_thumbnails
+WHERE storage_backend = $1
+ORDER BY created_at ASC;
+
+-- name: SetThumbnailBackend :exec
+UPDATE attachment_thumbnails
+SET storage_backend = $3
+WHERE attachment_id = $1 AND width = $2;
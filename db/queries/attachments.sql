@@ -7,6 +7,16 @@ SELECT * FROM attachments
 WHERE entry_id = $1
 ORDER BY created_at ASC;
 
+-- name: ListAttachmentsForEntries :many
+SELECT * FROM attachments
+WHERE entry_id = ANY(sqlc.arg(entry_ids)::uuid[])
+ORDER BY entry_id, created_at ASC;
+
+-- name: ListOrphanedAttachments :many
+SELECT * FROM attachments
+WHERE user_id = $1 AND orphaned_at IS NOT NULL
+ORDER BY orphaned_at ASC;
+
 -- name: CreateAttachment :one
 INSERT INTO attachments (
   user_id,
@@ -14,12 +24,74 @@ INSERT INTO attachments (
   filename,
   mime_type,
   size_bytes,
-  data
+  storage_backend,
+  storage_key
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: ImportAttachment :one
+-- Restores an attachment from an export with its ID and upload time
+INSERT INTO attachments (
+  id,
+  user_id,
+  entry_id,
+  filename,
+  mime_type,
+  size_bytes,
+  storage_backend,
+  storage_key,
+  created_at
 ) VALUES (
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
 )
 RETURNING *;
 
 -- name: DeleteAttachment :exec
 DELETE FROM attachments
 WHERE id = $1;
+
+-- name: GetAttachmentUsage :one
+SELECT COALESCE(SUM(size_bytes), 0)::bigint AS total_bytes
+FROM attachments
+WHERE user_id = $1;
+
+-- name: ListAttachmentsInBackend :many
+SELECT * FROM attachments
+WHERE storage_backend = $1
+ORDER BY created_at ASC;
+
+-- name: SetAttachmentBackend :exec
+UPDATE attachments
+SET storage_backend = $2
+WHERE id = $1;
+
+-- name: ListAttachmentsForCleanup :many
+-- Pages through the attachments of entries that aren't archived, each with
+-- the body of its entry, for the reference scan. The first page has a null
+-- after_id; each later one starts after the last attachment of the page before.
+SELECT a.id, a.user_id, a.entry_id, a.filename, a.orphaned_at, a.referenced_at, e.body_delta
+FROM attachments a
+JOIN entries e ON e.id = a.entry_id
+WHERE e.archived = false
+  AND (sqlc.narg(after_id)::uuid IS NULL
+       OR (a.entry_id, a.id) > (sqlc.arg(after_entry_id)::uuid, sqlc.narg(after_id)::uuid))
+ORDER BY a.entry_id, a.id
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkAttachmentsReferenced :exec
+UPDATE attachments
+SET referenced_at = NOW()
+WHERE entry_id = sqlc.arg(entry_id) AND id = ANY(sqlc.arg(ids)::uuid[]) AND referenced_at IS NULL;
+
+-- name: MarkAttachmentsOrphaned :exec
+-- Only attachments that were once referenced can be orphaned
+UPDATE attachments
+SET orphaned_at = NOW()
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND orphaned_at IS NULL AND referenced_at IS NOT NULL;
+
+-- name: ClearAttachmentsOrphaned :exec
+UPDATE attachments
+SET orphaned_at = NULL
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND orphaned_at IS NOT NULL;
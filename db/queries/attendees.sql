@@ -1,10 +1,10 @@
 -- name: GetOrCreateAttendee :one
+-- use_count is maintained by RecountAttendees
 INSERT INTO attendees (user_id, name, last_used, use_count)
-VALUES ($1, $2, NOW(), 1)
+VALUES ($1, $2, NOW(), 0)
 ON CONFLICT (user_id, name) 
 DO UPDATE SET 
-  last_used = NOW(),
-  use_count = attendees.use_count + 1
+  last_used = NOW()
 RETURNING *;
 
 -- name: SearchAttendees :many
@@ -28,3 +28,122 @@ FROM attendees
 WHERE user_id = $1
 ORDER BY use_count DESC, last_used DESC
 LIMIT $2;
+
+-- name: ListPeople :many
+SELECT * FROM attendees
+WHERE user_id = $1
+ORDER BY use_count DESC, name ASC;
+
+-- name: GetPerson :one
+SELECT * FROM attendees
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: UpdatePerson :one
+UPDATE attendees
+SET email = $3,
+    role = $4,
+    notes = $5,
+    aliases = $6,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: ListEntriesForAttendeeRewrite :many
+-- Every entry naming any of names, archived ones too, locked so it can be
+-- rewritten when people are renamed or merged
+SELECT * FROM entries
+WHERE user_id = $1
+  AND EXISTS (
+    SELECT 1 FROM unnest(attendees) AS a(name)
+    WHERE lower(a.name) = ANY(sqlc.arg(names)::text[])
+  )
+ORDER BY id
+FOR UPDATE;
+
+-- name: ListEntriesForPerson :many
+-- Matches the person's name and aliases case-insensitively
+SELECT * FROM entries
+WHERE user_id = $1
+  AND archived = false
+  AND EXISTS (
+    SELECT 1 FROM unnest(attendees) AS a(name)
+    WHERE lower(a.name) = ANY(sqlc.arg(names)::text[])
+  )
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC;
+
+-- name: GetAttendeeByName :one
+SELECT * FROM attendees
+WHERE user_id = $1 AND lower(name) = lower(sqlc.arg(name)::text)
+LIMIT 1;
+
+-- name: ListAttendeeNames :many
+SELECT name, aliases FROM attendees
+WHERE user_id = $1;
+
+-- name: MergeAttendee :one
+UPDATE attendees
+SET email = $3,
+    role = $4,
+    notes = $5,
+    aliases = $6,
+    use_count = $7,
+    last_used = $8,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RenameAttendee :one
+UPDATE attendees
+SET name = $3,
+    aliases = $4,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteAttendee :exec
+DELETE FROM attendees
+WHERE id = $1 AND user_id = $2;
+
+-- name: RecountAttendees :exec
+-- Derives use_count from the non-archived entries naming the attendee or an alias
+UPDATE attendees a
+SET use_count = (
+  SELECT COUNT(*) FROM entries e
+  WHERE e.user_id = a.user_id
+    AND e.archived = false
+    AND EXISTS (
+      SELECT 1 FROM unnest(e.attendees) AS n(name)
+      WHERE lower(n.name) = lower(a.name)
+         OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias)
+    )
+)
+WHERE a.user_id = $1
+  AND lower(a.name) = ANY(sqlc.arg(names)::text[]);
+
+-- name: BackfillAttendees :execrows
+-- Creates attendee rows for names used in entries but missing from the table
+INSERT INTO attendees (user_id, name, last_used, use_count)
+SELECT DISTINCT ON (e.user_id, lower(n.name)) e.user_id, n.name, e.updated_at, 0
+FROM entries e, unnest(e.attendees) AS n(name)
+WHERE e.archived = false
+  AND NOT EXISTS (
+    SELECT 1 FROM attendees a
+    WHERE a.user_id = e.user_id
+      AND (lower(a.name) = lower(n.name)
+           OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias))
+  )
+ORDER BY e.user_id, lower(n.name), e.updated_at DESC
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- name: RecountAllAttendees :execrows
+UPDATE attendees a
+SET use_count = (
+  SELECT COUNT(*) FROM entries e
+  WHERE e.user_id = a.user_id
+    AND e.archived = false
+    AND EXISTS (
+      SELECT 1 FROM unnest(e.attendees) AS n(name)
+      WHERE lower(n.name) = lower(a.name)
+         OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias)
+    )
+);
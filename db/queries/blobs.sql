@@ -0,0 +1,30 @@
+-- name: AcquireBlob :one
+-- Records one more use of stored contents, creating the blob on first use
+INSERT INTO blobs (storage_backend, storage_key, size_bytes, ref_count)
+VALUES ($1, $2, $3, 1)
+ON CONFLICT (storage_backend, storage_key) DO UPDATE
+SET ref_count = blobs.ref_count + 1
+RETURNING ref_count;
+
+-- name: DeleteBlob :exec
+DELETE FROM blobs
+WHERE storage_backend = $1 AND storage_key = $2;
+
+-- name: ListUnusedBlobs :many
+SELECT * FROM blobs
+WHERE ref_count = 0
+ORDER BY created_at ASC;
+
+-- name: LockUnusedBlob :one
+-- Holds an unused blob until its contents are deleted, so a concurrent
+-- upload of the same file waits rather than reusing contents being removed
+SELECT * FROM blobs
+WHERE storage_backend = $1 AND storage_key = $2 AND ref_count = 0
+FOR UPDATE;
+
+-- name: ReleaseBlob :one
+-- Records one less use of stored contents, returning the uses left
+UPDATE blobs
+SET ref_count = ref_count - 1
+WHERE storage_backend = $1 AND storage_key = $2 AND ref_count > 0
+RETURNING ref_count;
@@ -2,6 +2,18 @@
 SELECT * FROM entries
 WHERE id = $1 AND archived = false LIMIT 1;
 
+-- name: GetEntryForUpdate :one
+-- Locks the entry row until the end of the transaction
+SELECT * FROM entries
+WHERE id = $1 AND archived = false LIMIT 1
+FOR UPDATE;
+
+-- name: GetEntryForImport :one
+-- Includes archived entries, and locks the row until the end of the transaction
+SELECT * FROM entries
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
 -- name: ListEntriesForDay :many
 SELECT * FROM entries
 WHERE user_id = $1
@@ -11,6 +23,82 @@ WHERE user_id = $1
   AND archived = false
 ORDER BY created_at ASC;
 
+-- name: ListEntryIDs :many
+-- Every live entry, oldest first, for backfills
+SELECT id FROM entries
+WHERE archived = false
+ORDER BY created_at, id;
+
+-- name: ListEntriesForExport :many
+-- Pages through a user's entries, newest first, archived ones too when
+-- include_archived is set. The first page has a null after_id; each later one
+-- starts after the last entry of the page before. Null dates and empty lists
+-- don't filter: types and attendees match any given, tags must all be present.
+SELECT * FROM entries
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(after_id)::uuid IS NULL
+       OR (day_year, day_month, day_day, created_at, id) <
+          (sqlc.arg(after_year)::int, sqlc.arg(after_month)::int, sqlc.arg(after_day)::int,
+           sqlc.arg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+  AND (sqlc.narg(from_date)::date IS NULL
+       OR make_date(day_year, day_month, day_day) >= sqlc.narg(from_date)::date)
+  AND (sqlc.narg(to_date)::date IS NULL
+       OR make_date(day_year, day_month, day_day) <= sqlc.narg(to_date)::date)
+  AND (cardinality(sqlc.arg(types)::text[]) = 0 OR type = ANY(sqlc.arg(types)::text[]))
+  AND (
+    cardinality(sqlc.arg(attendee_names)::text[]) = 0
+    OR EXISTS (
+      SELECT 1 FROM unnest(attendees) AS a(name)
+      WHERE lower(a.name) = ANY(sqlc.arg(attendee_names)::text[])
+    )
+  )
+  AND (
+    cardinality(sqlc.arg(tags)::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = sqlc.arg(user_id)
+        AND t.name = ANY(sqlc.arg(tags)::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality(sqlc.arg(tags)::text[])
+    )
+  )
+  AND (sqlc.arg(include_archived)::bool OR archived = false)
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: FilterEntriesForExport :many
+-- Which of the given entries an export with the same filters includes
+SELECT id FROM entries
+WHERE user_id = sqlc.arg(user_id)
+  AND id = ANY(sqlc.arg(entry_ids)::uuid[])
+  AND (sqlc.narg(from_date)::date IS NULL
+       OR make_date(day_year, day_month, day_day) >= sqlc.narg(from_date)::date)
+  AND (sqlc.narg(to_date)::date IS NULL
+       OR make_date(day_year, day_month, day_day) <= sqlc.narg(to_date)::date)
+  AND (cardinality(sqlc.arg(types)::text[]) = 0 OR type = ANY(sqlc.arg(types)::text[]))
+  AND (
+    cardinality(sqlc.arg(attendee_names)::text[]) = 0
+    OR EXISTS (
+      SELECT 1 FROM unnest(attendees) AS a(name)
+      WHERE lower(a.name) = ANY(sqlc.arg(attendee_names)::text[])
+    )
+  )
+  AND (
+    cardinality(sqlc.arg(tags)::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = sqlc.arg(user_id)
+        AND t.name = ANY(sqlc.arg(tags)::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality(sqlc.arg(tags)::text[])
+    )
+  )
+  AND (sqlc.arg(include_archived)::bool OR archived = false);
+
 -- name: CreateEntry :one
 INSERT INTO entries (
   user_id,
@@ -29,6 +117,29 @@ INSERT INTO entries (
 )
 RETURNING *;
 
+-- name: ImportEntry :one
+-- Restores an entry from an export with its ID, archived state and timestamps
+INSERT INTO entries (
+  id,
+  user_id,
+  title,
+  body_delta,
+  body_html,
+  body_text,
+  attendees_original,
+  attendees,
+  type,
+  day_year,
+  day_month,
+  day_day,
+  archived,
+  created_at,
+  updated_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+RETURNING *;
+
 -- name: UpdateEntry :one
 UPDATE entries
 SET title = $2,
@@ -42,6 +153,26 @@ SET title = $2,
 WHERE id = $1 AND archived = false
 RETURNING *;
 
+-- name: RestoreEntry :one
+-- Overwrites an entry with its exported state; it is re-embedded afterwards
+UPDATE entries
+SET title = $2,
+    body_delta = $3,
+    body_html = $4,
+    body_text = $5,
+    attendees_original = $6,
+    attendees = $7,
+    type = $8,
+    day_year = $9,
+    day_month = $10,
+    day_day = $11,
+    archived = $12,
+    created_at = $13,
+    updated_at = $14,
+    vectors_updated_at = NULL
+WHERE id = $1
+RETURNING *;
+
 -- name: SoftDeleteEntry :exec
 UPDATE entries
 SET archived = true,
@@ -57,7 +188,11 @@ WHERE user_id = $1
   AND archived = false
 ORDER BY day_year, day_month, day_day;
 
--- name: ListAllEntries :many
-SELECT * FROM entries
-WHERE archived = false
-ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at ASC;
+-- name: UpdateEntryAttendees :exec
+-- Clears vectors_updated_at so the entry is re-embedded
+UPDATE entries
+SET attendees_original = $2,
+    attendees = $3,
+    updated_at = NOW(),
+    vectors_updated_at = NULL
+WHERE id = $1;
@@ -0,0 +1,56 @@
+-- name: SetEntryPinned :exec
+-- Newly pinned entries go to the end of the list
+INSERT INTO entry_flags (entry_id, user_id, pinned, pinned_position)
+VALUES ($1, $2, $3, (
+  SELECT COALESCE(MAX(pinned_position) + 1, 0) FROM entry_flags WHERE user_id = $2 AND pinned
+))
+ON CONFLICT (entry_id)
+DO UPDATE SET
+  pinned = EXCLUDED.pinned,
+  pinned_position = CASE WHEN entry_flags.pinned THEN entry_flags.pinned_position ELSE EXCLUDED.pinned_position END,
+  updated_at = NOW();
+
+-- name: SetEntryFavorite :exec
+-- Newly favourited entries go to the end of the list
+INSERT INTO entry_flags (entry_id, user_id, favorite, favorite_position)
+VALUES ($1, $2, $3, (
+  SELECT COALESCE(MAX(favorite_position) + 1, 0) FROM entry_flags WHERE user_id = $2 AND favorite
+))
+ON CONFLICT (entry_id)
+DO UPDATE SET
+  favorite = EXCLUDED.favorite,
+  favorite_position = CASE WHEN entry_flags.favorite THEN entry_flags.favorite_position ELSE EXCLUDED.favorite_position END,
+  updated_at = NOW();
+
+-- name: SetPinnedPosition :exec
+UPDATE entry_flags
+SET pinned_position = $3,
+    updated_at = NOW()
+WHERE entry_id = $1 AND user_id = $2 AND pinned;
+
+-- name: SetFavoritePosition :exec
+UPDATE entry_flags
+SET favorite_position = $3,
+    updated_at = NOW()
+WHERE entry_id = $1 AND user_id = $2 AND favorite;
+
+-- name: ListPinnedEntries :many
+SELECT e.* FROM entries e
+JOIN entry_flags f ON f.entry_id = e.id
+WHERE f.user_id = $1
+  AND f.pinned
+  AND e.archived = false
+ORDER BY f.pinned_position ASC, e.created_at ASC;
+
+-- name: ListFavoriteEntries :many
+SELECT e.* FROM entries e
+JOIN entry_flags f ON f.entry_id = e.id
+WHERE f.user_id = $1
+  AND f.favorite
+  AND e.archived = false
+ORDER BY f.favorite_position ASC, e.created_at ASC;
+
+-- name: ListFlagsForEntries :many
+SELECT entry_id, pinned, favorite
+FROM entry_flags
+WHERE entry_id = ANY(sqlc.arg(entry_ids)::uuid[]);
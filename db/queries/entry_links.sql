@@ -0,0 +1,70 @@
+-- name: DeleteEntryLinks :exec
+DELETE FROM entry_links
+WHERE source_entry_id = $1;
+
+-- name: CreateEntryLink :exec
+INSERT INTO entry_links (user_id, source_entry_id, target_entry_id, target_title)
+VALUES ($1, $2, $3, $4);
+
+-- name: ResolveEntryTitle :one
+SELECT id FROM entries
+WHERE user_id = $1
+  AND lower(title) = lower(sqlc.arg(title)::text)
+  AND id <> sqlc.arg(source_entry_id)
+  AND archived = false
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC
+LIMIT 1;
+
+-- name: ResolveDanglingLinks :exec
+UPDATE entry_links
+SET target_entry_id = sqlc.arg(target_entry_id)
+WHERE user_id = $1
+  AND target_entry_id IS NULL
+  AND target_title <> ''
+  AND lower(target_title) = lower(sqlc.arg(title)::text)
+  AND source_entry_id <> sqlc.arg(target_entry_id);
+
+-- name: RebindTitleLinks :exec
+-- Moves [[Title]] links off an entry that no longer has that title or is
+-- archived, onto the newest other entry with the title, or leaves them
+-- unresolved
+UPDATE entry_links l
+SET target_entry_id = (
+  SELECT e.id FROM entries e
+  WHERE e.user_id = l.user_id
+    AND lower(e.title) = lower(l.target_title)
+    AND e.id <> l.source_entry_id
+    AND e.archived = false
+  ORDER BY e.day_year DESC, e.day_month DESC, e.day_day DESC, e.created_at DESC
+  LIMIT 1
+)
+WHERE l.target_entry_id = sqlc.arg(target_entry_id)
+  AND l.target_title <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM entries t
+    WHERE t.id = sqlc.arg(target_entry_id)
+      AND t.archived = false
+      AND lower(t.title) = lower(l.target_title)
+  );
+
+-- name: ListBacklinks :many
+SELECT * FROM entries
+WHERE id IN (
+  SELECT source_entry_id FROM entry_links
+  WHERE target_entry_id = $1
+)
+  AND user_id = $2
+  AND archived = false
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC;
+
+-- name: ListLinksForEntries :many
+-- The target's title and day are null when it is unresolved
+SELECT l.source_entry_id, l.target_entry_id, l.target_title,
+       t.title AS target_entry_title,
+       t.day_year AS target_day_year,
+       t.day_month AS target_day_month,
+       t.day_day AS target_day_day
+FROM entry_links l
+LEFT JOIN entries t ON t.id = l.target_entry_id
+WHERE l.source_entry_id = ANY(sqlc.arg(entry_ids)::uuid[])
+ORDER BY l.created_at;
@@ -0,0 +1,46 @@
+-- name: ListEntryTypes :many
+SELECT * FROM entry_types
+WHERE user_id = $1
+ORDER BY position ASC, name ASC;
+
+-- name: GetEntryType :one
+SELECT * FROM entry_types
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: GetEntryTypeByName :one
+SELECT * FROM entry_types
+WHERE user_id = $1 AND name = $2 LIMIT 1;
+
+-- name: CreateEntryType :one
+INSERT INTO entry_types (
+  user_id,
+  name,
+  color,
+  icon,
+  default_template_delta,
+  has_attendees,
+  position
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: UpdateEntryType :one
+UPDATE entry_types
+SET name = $3,
+    color = $4,
+    icon = $5,
+    default_template_delta = $6,
+    has_attendees = $7,
+    position = $8,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteEntryType :exec
+DELETE FROM entry_types
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountEntriesWithType :one
+SELECT COUNT(*) FROM entries
+WHERE user_id = $1 AND type = $2;
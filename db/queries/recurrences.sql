@@ -0,0 +1,59 @@
+-- name: ListRecurrences :many
+SELECT r.*, t.name AS template_name
+FROM recurrences r
+JOIN templates t ON t.id = r.template_id
+WHERE r.user_id = $1
+ORDER BY r.created_at ASC;
+
+-- name: GetRecurrence :one
+SELECT * FROM recurrences
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: CreateRecurrence :one
+INSERT INTO recurrences (
+  user_id,
+  template_id,
+  rrule,
+  starts_on,
+  ends_on
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: UpdateRecurrence :one
+-- Stamps resumed_at when the series goes from paused to running
+UPDATE recurrences
+SET rrule = $3,
+    starts_on = $4,
+    ends_on = $5,
+    paused = $6,
+    resumed_at = CASE WHEN paused AND NOT $6 THEN NOW() ELSE resumed_at END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteRecurrence :exec
+DELETE FROM recurrences
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListActiveRecurrences :many
+SELECT r.id, r.user_id, r.template_id, r.rrule, r.starts_on, r.ends_on,
+       r.resumed_at, u.timezone,
+       (SELECT MAX(o.occurs_on) FROM recurrence_occurrences o WHERE o.recurrence_id = r.id)::date AS last_occurrence
+FROM recurrences r
+JOIN users u ON u.id = r.user_id
+WHERE r.paused = false
+  AND (r.ends_on IS NULL OR r.ends_on >= CURRENT_DATE - 1)
+ORDER BY r.created_at ASC;
+
+-- name: ClaimOccurrence :one
+INSERT INTO recurrence_occurrences (recurrence_id, occurs_on)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+RETURNING recurrence_id;
+
+-- name: SetOccurrenceEntry :exec
+UPDATE recurrence_occurrences
+SET entry_id = $3
+WHERE recurrence_id = $1 AND occurs_on = $2;
@@ -6,6 +6,25 @@ WHERE user_id = $1
     title ILIKE '%' || $2 || '%'
     OR body_html ILIKE '%' || $2 || '%'
     OR $2 = ANY(attendees)
+    OR id IN (
+      SELECT a.entry_id
+      FROM attachments a
+      JOIN attachment_texts at ON at.attachment_id = a.id
+      WHERE a.user_id = $1
+        AND at.content ILIKE '%' || $2 || '%'
+    )
+  )
+  AND (
+    cardinality(sqlc.arg(tags)::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = $1
+        AND t.name = ANY(sqlc.arg(tags)::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality(sqlc.arg(tags)::text[])
+    )
   )
 ORDER BY created_at DESC
 LIMIT 100;
@@ -0,0 +1,50 @@
+-- name: GetOrCreateTag :one
+INSERT INTO tags (user_id, name, last_used)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, name)
+DO UPDATE SET
+  last_used = NOW()
+RETURNING *;
+
+-- name: AddEntryTag :exec
+INSERT INTO entry_tags (entry_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteEntryTags :exec
+DELETE FROM entry_tags
+WHERE entry_id = $1;
+
+-- name: ListTagsForEntries :many
+SELECT et.entry_id, t.name
+FROM entry_tags et
+JOIN tags t ON t.id = et.tag_id
+WHERE et.entry_id = ANY(sqlc.arg(entry_ids)::uuid[])
+ORDER BY t.name;
+
+-- name: SearchTags :many
+SELECT t.name, t.last_used, COUNT(e.id)::int AS entry_count
+FROM tags t
+LEFT JOIN entry_tags et ON et.tag_id = t.id
+LEFT JOIN entries e ON e.id = et.entry_id AND e.archived = false
+WHERE t.user_id = $1
+  AND t.name ILIKE sqlc.arg(query) || '%'
+GROUP BY t.id
+ORDER BY entry_count DESC, t.last_used DESC
+LIMIT $2;
+
+-- name: GetRecentTags :many
+SELECT name, last_used
+FROM tags
+WHERE user_id = $1
+ORDER BY last_used DESC
+LIMIT $2;
+
+-- name: ListTagsWithCounts :many
+SELECT t.name, COUNT(e.id)::int AS entry_count
+FROM tags t
+JOIN entry_tags et ON et.tag_id = t.id
+JOIN entries e ON e.id = et.entry_id AND e.archived = false
+WHERE t.user_id = $1
+GROUP BY t.id
+ORDER BY entry_count DESC, t.name ASC;
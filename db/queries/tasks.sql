@@ -0,0 +1,35 @@
+-- name: UpsertTask :one
+INSERT INTO tasks (user_id, entry_id, position, text, done, due_date, owner, completed_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $5::boolean THEN NOW() END)
+ON CONFLICT (entry_id, position)
+DO UPDATE SET
+  text = EXCLUDED.text,
+  done = EXCLUDED.done,
+  due_date = EXCLUDED.due_date,
+  owner = EXCLUDED.owner,
+  completed_at = CASE
+    WHEN NOT EXCLUDED.done THEN NULL
+    WHEN tasks.done THEN tasks.completed_at
+    ELSE NOW()
+  END,
+  updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteTasksFromPosition :exec
+DELETE FROM tasks
+WHERE entry_id = $1 AND position >= $2;
+
+-- name: GetTask :one
+SELECT * FROM tasks
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: ListTasks :many
+SELECT t.*, e.title AS entry_title, e.day_year, e.day_month, e.day_day
+FROM tasks t
+JOIN entries e ON e.id = t.entry_id AND e.archived = false
+WHERE t.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(done)::boolean IS NULL OR t.done = sqlc.narg(done)::boolean)
+  AND (sqlc.narg(owner)::text IS NULL OR lower(t.owner) = lower(sqlc.narg(owner)::text))
+  AND (sqlc.narg(due_before)::date IS NULL OR t.due_date <= sqlc.narg(due_before)::date)
+ORDER BY t.due_date ASC NULLS LAST, e.day_year DESC, e.day_month DESC, e.day_day DESC, t.position ASC
+LIMIT sqlc.arg(row_limit);
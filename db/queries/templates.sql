@@ -0,0 +1,36 @@
+-- name: ListTemplates :many
+SELECT * FROM templates
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: GetTemplate :one
+SELECT * FROM templates
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: CreateTemplate :one
+INSERT INTO templates (
+  user_id,
+  name,
+  title_pattern,
+  body_delta,
+  default_type,
+  default_attendees
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: UpdateTemplate :one
+UPDATE templates
+SET name = $3,
+    title_pattern = $4,
+    body_delta = $5,
+    default_type = $6,
+    default_attendees = $7,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTemplate :exec
+DELETE FROM templates
+WHERE id = $1 AND user_id = $2;
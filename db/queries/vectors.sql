@@ -24,3 +24,33 @@ WHERE user_id = $1
   AND embedding_vector IS NOT NULL
 ORDER BY embedding_vector <-> $2::vector
 LIMIT $3;
+
+-- name: GetAttachmentsNeedingVectors :many
+SELECT t.attachment_id, a.filename, t.content
+FROM attachment_texts t
+JOIN attachments a ON a.id = t.attachment_id
+WHERE a.user_id = $1
+  AND t.content <> ''
+  AND t.embedding_vector IS NULL
+ORDER BY t.created_at DESC
+LIMIT $2;
+
+-- name: UpdateAttachmentVector :exec
+UPDATE attachment_texts
+SET embedding_vector = $2,
+    vectors_updated_at = CURRENT_TIMESTAMP
+WHERE attachment_id = $1;
+
+-- name: SearchSimilarAttachments :many
+SELECT a.id, a.entry_id, a.filename, a.mime_type,
+       left(t.content, 2000)::text AS excerpt,
+       e.title AS entry_title, e.day_year, e.day_month, e.day_day,
+       (t.embedding_vector <-> $2::vector) AS distance
+FROM attachment_texts t
+JOIN attachments a ON a.id = t.attachment_id
+JOIN entries e ON e.id = a.entry_id
+WHERE a.user_id = $1
+  AND e.archived = false
+  AND t.embedding_vector IS NOT NULL
+ORDER BY t.embedding_vector <-> $2::vector
+LIMIT $3;
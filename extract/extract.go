@@ -0,0 +1,107 @@
+// Package extract pulls the plain text out of attachments so they can be
+// found by keyword search and embedded for Chat retrieval. It handles PDFs
+// and text formats using only the standard library.
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTextBytes caps the text kept for one attachment
+const MaxTextBytes = 1 << 20
+
+// ErrEncrypted is returned for password-protected PDFs
+var ErrEncrypted = errors.New("pdf is encrypted")
+
+// MimeTypes are the types text can be extracted from
+var MimeTypes = []string{"application/pdf", "text/plain", "text/markdown", "text/csv"}
+
+// Supported reports whether text can be extracted from a MIME type
+func Supported(mimeType string) bool {
+	return slices.Contains(MimeTypes, mimeType)
+}
+
+// Text returns the text of a file of the given MIME type, with whitespace
+// tidied and truncated to MaxTextBytes
+func Text(r io.Reader, mimeType string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	switch mimeType {
+	case "application/pdf":
+		text, err = pdfText(data)
+	case "text/plain", "text/markdown":
+		text = string(data)
+	case "text/csv":
+		text = csvText(data)
+	default:
+		return "", fmt.Errorf("cannot extract text from %s", mimeType)
+	}
+	if err != nil {
+		return "", err
+	}
+	return clean(text), nil
+}
+
+// csvText puts each record on a line with its fields tab-separated, falling
+// back to the raw text for malformed files
+func csvText(data []byte) string {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return string(data)
+	}
+	var b strings.Builder
+	for _, record := range records {
+		b.WriteString(strings.Join(record, "\t"))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// clean makes text safe to store in Postgres (valid UTF-8, no NULs), trims
+// trailing space from lines, collapses runs of blank lines and truncates it
+// to MaxTextBytes on a character boundary
+func clean(text string) string {
+	text = strings.ToValidUTF8(text, "�")
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var b strings.Builder
+	blank := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r\f\v")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	text = strings.TrimSpace(b.String())
+
+	if len(text) > MaxTextBytes {
+		cut := MaxTextBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
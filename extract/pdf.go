@@ -0,0 +1,837 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamBytes bounds a decompressed stream, against zip bombs
+const maxStreamBytes = 64 << 20
+
+// maxDepth bounds recursion through references, page trees and form XObjects
+const maxDepth = 32
+
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// PDF object types. Numbers are float64, booleans bool and null nil.
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfDoc is a PDF's objects, found by scanning the file for "n g obj"
+// headers rather than trusting the cross-reference table, so damaged and
+// incrementally updated files still read
+type pdfDoc struct {
+	objects map[int]any
+	fonts   map[any]*pdfFont
+}
+
+// pdfText returns the text of a PDF's pages, in page order
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		return "", errors.New("not a PDF")
+	}
+	doc := &pdfDoc{objects: map[int]any{}, fonts: map[any]*pdfFont{}}
+	doc.scan(data)
+	if doc.encrypted(data) {
+		return "", ErrEncrypted
+	}
+
+	var out textWriter
+	for _, page := range doc.pages() {
+		resources, _ := doc.resolve(page["Resources"]).(pdfDict)
+		var content []byte
+		switch contents := doc.resolve(page["Contents"]).(type) {
+		case *pdfStream:
+			content = doc.decode(contents)
+		case []any:
+			for _, part := range contents {
+				if stream, ok := doc.resolve(part).(*pdfStream); ok {
+					content = append(content, doc.decode(stream)...)
+					content = append(content, '\n')
+				}
+			}
+		}
+		doc.showText(&out, content, resources, 0)
+		out.newline()
+		out.b.WriteByte('\n')
+	}
+	return out.b.String(), nil
+}
+
+// scan parses every indirect object in the file, then those packed into
+// object streams. Later definitions replace earlier ones, as in an
+// incremental update.
+func (d *pdfDoc) scan(data []byte) {
+	next := 0
+	for _, m := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] < next {
+			continue // inside the previous object's stream
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		p := &pdfParser{data: data, pos: m[1]}
+		obj, err := p.object()
+		if err != nil {
+			continue
+		}
+		if dict, ok := obj.(pdfDict); ok {
+			if stream, end, ok := p.stream(dict); ok {
+				obj = stream
+				p.pos = end
+			}
+		}
+		d.objects[num] = obj
+		next = p.pos
+	}
+
+	for _, obj := range d.objects {
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("ObjStm") {
+			d.unpack(stream)
+		}
+	}
+}
+
+// unpack adds the objects in an object stream, keeping any defined directly
+func (d *pdfDoc) unpack(stream *pdfStream) {
+	data := d.decode(stream)
+	n, _ := stream.dict["N"].(float64)
+	first, _ := d.resolve(stream.dict["First"]).(float64)
+	p := &pdfParser{data: data}
+	for i := 0; i < int(n); i++ {
+		num, err1 := p.object()
+		offset, err2 := p.object()
+		numF, ok1 := num.(float64)
+		offsetF, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, ok := d.objects[int(numF)]; ok {
+			continue
+		}
+		at := int(first) + int(offsetF)
+		if at < 0 || at >= len(data) {
+			continue
+		}
+		if obj, err := (&pdfParser{data: data, pos: at}).object(); err == nil {
+			d.objects[int(numF)] = obj
+		}
+	}
+}
+
+// encrypted reports whether a trailer or cross-reference stream names an
+// encryption dictionary
+func (d *pdfDoc) encrypted(data []byte) bool {
+	for _, obj := range d.objects {
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			if _, ok := stream.dict["Encrypt"]; ok {
+				return true
+			}
+		}
+	}
+	for rest := data; ; {
+		i := bytes.Index(rest, []byte("trailer"))
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len("trailer"):]
+		if trailer, err := (&pdfParser{data: rest}).object(); err == nil {
+			if dict, ok := trailer.(pdfDict); ok && dict["Encrypt"] != nil {
+				return true
+			}
+		}
+	}
+}
+
+func (d *pdfDoc) resolve(obj any) any {
+	for i := 0; i < maxDepth; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+// decode returns a stream's decoded data. Only FlateDecode is supported;
+// streams with other filters (mostly images) decode to nothing.
+func (d *pdfDoc) decode(stream *pdfStream) []byte {
+	var filters []any
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := stream.raw
+	for _, f := range filters {
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil
+			}
+			// Keep what inflated before any error in a damaged stream
+			data, _ = io.ReadAll(io.LimitReader(r, maxStreamBytes))
+		default:
+			return nil
+		}
+	}
+	return data
+}
+
+// pages returns the page dictionaries in order, each with its inherited
+// Resources filled in
+func (d *pdfDoc) pages() []pdfDict {
+	var pages []pdfDict
+	visited := map[pdfRef]bool{}
+	var walk func(node any, resources any, depth int)
+	walk = func(node any, resources any, depth int) {
+		if depth > maxDepth {
+			return
+		}
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict, ok := d.resolve(node).(pdfDict)
+		if !ok {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+		switch dict["Type"] {
+		case pdfName("Pages"):
+			kids, _ := d.resolve(dict["Kids"]).([]any)
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+		case pdfName("Page"):
+			page := pdfDict{"Contents": dict["Contents"], "Resources": resources}
+			pages = append(pages, page)
+		}
+	}
+
+	for _, obj := range d.objects {
+		if catalog, ok := obj.(pdfDict); ok && catalog["Type"] == pdfName("Catalog") {
+			walk(catalog["Pages"], nil, 0)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+
+	// No usable page tree: take the pages in object number order
+	var nums []int
+	for num, obj := range d.objects {
+		if dict, ok := obj.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		page := d.objects[num].(pdfDict)
+		resources := page["Resources"]
+		for parent, depth := page["Parent"], 0; resources == nil && parent != nil && depth < maxDepth; depth++ {
+			node, _ := d.resolve(parent).(pdfDict)
+			resources, parent = node["Resources"], node["Parent"]
+		}
+		pages = append(pages, pdfDict{"Contents": page["Contents"], "Resources": resources})
+	}
+	return pages
+}
+
+// showText runs a content stream, writing the strings it shows. Line breaks
+// are inferred from text positioning; glyph widths aren't known, so larger
+// gaps within a line become single spaces.
+func (d *pdfDoc) showText(out *textWriter, content []byte, resources pdfDict, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	fontDict, _ := d.resolve(resources["Font"]).(pdfDict)
+	xobjects, _ := d.resolve(resources["XObject"]).(pdfDict)
+
+	var font *pdfFont
+	var operands []any
+	p := &pdfParser{data: content, content: true}
+	for {
+		obj, err := p.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = d.font(fontDict[name])
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					out.write(font.decode(s))
+				}
+			}
+		case "'", "\"":
+			out.newline()
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					out.write(font.decode(s))
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				array, _ := operands[len(operands)-1].([]any)
+				for _, item := range array {
+					switch v := item.(type) {
+					case pdfString:
+						out.write(font.decode(v))
+					case float64:
+						// Kerning is in thousandths of an em; a big gap is a space
+						if v < -200 {
+							out.space()
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[len(operands)-1].(float64); ty != 0 {
+					out.newline()
+				} else {
+					out.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[len(operands)-1].(float64)
+				if y != out.y {
+					out.newline()
+				} else {
+					out.space()
+				}
+				out.y = y
+			}
+		case "T*":
+			out.newline()
+		case "Do":
+			if len(operands) >= 1 {
+				name, _ := operands[len(operands)-1].(pdfName)
+				if form, ok := d.resolve(xobjects[name]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					formResources, ok := d.resolve(form.dict["Resources"]).(pdfDict)
+					if !ok {
+						formResources = resources
+					}
+					d.showText(out, d.decode(form), formResources, depth+1)
+				}
+			}
+		case "ID":
+			p.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// font returns the font for a reference or dictionary, parsing its
+// ToUnicode CMap once
+func (d *pdfDoc) font(obj any) *pdfFont {
+	key := obj
+	if _, ok := obj.(pdfRef); !ok {
+		key = fmt.Sprintf("%p", obj)
+	}
+	if font, ok := d.fonts[key]; ok {
+		return font
+	}
+
+	font := &pdfFont{codeBytes: 1}
+	if dict, ok := d.resolve(obj).(pdfDict); ok {
+		if dict["Subtype"] == pdfName("Type0") {
+			font.codeBytes = 2
+		}
+		if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			font.parseCMap(d.decode(stream))
+		}
+	}
+	d.fonts[key] = font
+	return font
+}
+
+// pdfFont maps a font's character codes to Unicode
+type pdfFont struct {
+	codeBytes int
+	toUnicode map[uint32]string
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap
+func (f *pdfFont) parseCMap(data []byte) {
+	f.toUnicode = map[uint32]string{}
+	p := &pdfParser{data: data, content: true}
+	var operands []any
+	mode := ""
+	for {
+		obj, err := p.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			if mode == "bfchar" && len(operands) == 2 {
+				src, _ := operands[0].(pdfString)
+				dst, _ := operands[1].(pdfString)
+				f.toUnicode[code(src)] = utf16BE(dst)
+				operands = operands[:0]
+			}
+			if mode == "bfrange" && len(operands) == 3 {
+				f.mapRange(operands)
+				operands = operands[:0]
+			}
+			if mode == "codespacerange" && len(operands) == 2 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					f.codeBytes = len(lo)
+				}
+				operands = operands[:0]
+			}
+			continue
+		}
+		switch op {
+		case "beginbfchar", "beginbfrange", "begincodespacerange":
+			mode = strings.TrimPrefix(string(op), "begin")
+		case "endbfchar", "endbfrange", "endcodespacerange":
+			mode = ""
+		}
+		operands = operands[:0]
+	}
+}
+
+// mapRange adds a bfrange: codes lo..hi map either to consecutive Unicode
+// values from a start string, or to the strings of an array
+func (f *pdfFont) mapRange(operands []any) {
+	lo, _ := operands[0].(pdfString)
+	hi, _ := operands[1].(pdfString)
+	start, end := code(lo), code(hi)
+	if end < start || end-start > 0xFFFF {
+		return
+	}
+	switch dst := operands[2].(type) {
+	case pdfString:
+		units := make([]uint16, 0, len(dst)/2)
+		for i := 0; i+1 < len(dst); i += 2 {
+			units = append(units, uint16(dst[i])<<8|uint16(dst[i+1]))
+		}
+		if len(units) == 0 {
+			return
+		}
+		for c := start; c <= end; c++ {
+			f.toUnicode[c] = string(utf16.Decode(units))
+			units[len(units)-1]++
+		}
+	case []any:
+		for i, item := range dst {
+			if s, ok := item.(pdfString); ok && start+uint32(i) <= end {
+				f.toUnicode[start+uint32(i)] = utf16BE(s)
+			}
+		}
+	}
+}
+
+// decode maps a shown string to text. Without a ToUnicode map, one-byte
+// codes are read as WinAnsi; multi-byte codes can't be interpreted.
+func (f *pdfFont) decode(s pdfString) string {
+	if f == nil {
+		return winAnsi(s)
+	}
+	if f.toUnicode == nil {
+		if f.codeBytes == 1 {
+			return winAnsi(s)
+		}
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i+f.codeBytes <= len(s); i += f.codeBytes {
+		c := code(s[i : i+f.codeBytes])
+		if text, ok := f.toUnicode[c]; ok {
+			b.WriteString(text)
+		} else if f.codeBytes == 1 {
+			b.WriteString(winAnsi(s[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+func code(b []byte) uint32 {
+	var c uint32
+	for _, x := range b {
+		c = c<<8 | uint32(x)
+	}
+	return c
+}
+
+func utf16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiHigh maps the WinAnsi codes 0x80-0x9F that differ from Latin-1
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘',
+	0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜',
+	0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func winAnsi(s []byte) string {
+	runes := make([]rune, 0, len(s))
+	for _, b := range s {
+		if r, ok := winAnsiHigh[b]; ok {
+			runes = append(runes, r)
+		} else if b >= 0x20 || b == '\t' || b == '\n' {
+			runes = append(runes, rune(b))
+		}
+	}
+	return string(runes)
+}
+
+// textWriter collects extracted text, avoiding doubled spaces and newlines
+type textWriter struct {
+	b strings.Builder
+	y float64
+}
+
+func (w *textWriter) write(s string) {
+	w.b.WriteString(s)
+}
+
+func (w *textWriter) last() byte {
+	s := w.b.String()
+	if s == "" {
+		return '\n'
+	}
+	return s[len(s)-1]
+}
+
+func (w *textWriter) space() {
+	if last := w.last(); last != ' ' && last != '\n' {
+		w.b.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newline() {
+	if w.last() != '\n' {
+		w.b.WriteByte('\n')
+	}
+}
+
+// pdfParser reads PDF objects from data. In content streams, operators are
+// returned as keywords and "n g R" is not a reference.
+type pdfParser struct {
+	data    []byte
+	pos     int
+	content bool
+}
+
+var errEOF = errors.New("unexpected end of PDF data")
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *pdfParser) object() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errEOF
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literalString(), nil
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		return p.dict()
+	case c == '<':
+		return p.hexString(), nil
+	case c == '[':
+		p.pos++
+		var array []any
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				return nil, errEOF
+			}
+			if p.data[p.pos] == ']' {
+				p.pos++
+				return array, nil
+			}
+			obj, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, obj)
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		// Stray delimiter: treat it as a keyword so callers can move on
+		p.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfKeyword(word), nil
+	}
+	if !p.content && n >= 0 && n == float64(int(n)) {
+		if ref, ok := p.ref(int(n)); ok {
+			return ref, nil
+		}
+	}
+	return n, nil
+}
+
+// ref reads the "g R" of a reference after its object number, leaving the
+// position unchanged if they aren't there
+func (p *pdfParser) ref(num int) (pdfRef, bool) {
+	save := p.pos
+	// Neither token can start another reference
+	p.content = true
+	defer func() { p.content = false }()
+	gen, err := p.object()
+	if g, ok := gen.(float64); ok && err == nil {
+		if r, err := p.object(); r == pdfKeyword("R") && err == nil {
+			return pdfRef{num: num, gen: int(g)}, true
+		}
+	}
+	p.pos = save
+	return pdfRef{}, false
+}
+
+func (p *pdfParser) dict() (pdfDict, error) {
+	p.pos += 2
+	dict := pdfDict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 >= len(p.data) {
+			return nil, errEOF
+		}
+		if p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return dict, nil
+		}
+		key, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := key.(pdfName); ok {
+			dict[name] = value
+		}
+	}
+}
+
+// stream reads the stream data following a dictionary, preferring a direct
+// /Length and falling back to searching for "endstream"
+func (p *pdfParser) stream(dict pdfDict) (*pdfStream, int, bool) {
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		return nil, 0, false
+	}
+	start := p.pos + len("stream")
+	if bytes.HasPrefix(p.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(p.data) && (p.data[start] == '\n' || p.data[start] == '\r') {
+		start++
+	}
+
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end >= start && end <= len(p.data) {
+			after := bytes.TrimLeft(p.data[end:], " \t\r\n")
+			if bytes.HasPrefix(after, []byte("endstream")) {
+				return &pdfStream{dict: dict, raw: p.data[start:end]}, end, true
+			}
+		}
+	}
+
+	i := bytes.Index(p.data[start:], []byte("endstream"))
+	if i < 0 {
+		return nil, 0, false
+	}
+	end := start + i
+	raw := bytes.TrimSuffix(bytes.TrimSuffix(p.data[start:end], []byte("\n")), []byte("\r"))
+	return &pdfStream{dict: dict, raw: raw}, end + len("endstream"), true
+}
+
+func (p *pdfParser) name() pdfName {
+	p.pos++
+	var b []byte
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if v, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		p.pos++
+	}
+	return pdfName(b)
+}
+
+func (p *pdfParser) literalString() pdfString {
+	p.pos++
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return b
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func (p *pdfParser) hexString() pdfString {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if c := p.data[p.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		b = append(b, byte(v))
+	}
+	return b
+}
+
+// skipInlineImage skips the binary data of an inline image, which runs from
+// after the ID operator to an EI operator
+func (p *pdfParser) skipInlineImage() {
+	for i := p.pos; i+2 < len(p.data); i++ {
+		if p.data[i] == 'E' && p.data[i+1] == 'I' && isSpace(p.data[i-1]) && (i+2 == len(p.data) || isSpace(p.data[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.data)
+}
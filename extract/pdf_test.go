@@ -0,0 +1,308 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// pdfObject is an indirect object for buildPDF: its number and body
+type pdfObject struct {
+	num  int
+	body string
+}
+
+// xref says what cross-reference table buildPDF writes
+type xref int
+
+const (
+	xrefValid   xref = iota // offsets of the objects as written
+	xrefBroken              // every offset wrong
+	xrefMissing             // no table, trailer or startxref at all
+)
+
+// buildPDF writes a PDF of the given objects with a trailer dictionary
+func buildPDF(objects []pdfObject, trailer string, table xref) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := map[int]int{}
+	size := 0
+	for _, obj := range objects {
+		offsets[obj.num] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", obj.num, obj.body)
+		size = max(size, obj.num+1)
+	}
+	if table == xrefMissing {
+		return b.Bytes()
+	}
+
+	start := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < size; num++ {
+		offset := offsets[num]
+		if table == xrefBroken {
+			offset = 999999 - offset
+		}
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", size, trailer, start)
+	return b.Bytes()
+}
+
+// stream is the body of a stream object with the given dictionary entries
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(data))
+	w.Close()
+	return b.String()
+}
+
+// onePage is a catalog, page tree and page using Helvetica as /F1, with the
+// page's content in object 4
+func onePage(content string) []pdfObject {
+	return []pdfObject{
+		{1, "<< /Type /Catalog /Pages 2 0 R >>"},
+		{2, "<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >>"},
+		{3, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R >>"},
+		{4, content},
+		{5, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"},
+	}
+}
+
+const helloContent = "BT /F1 12 Tf 72 700 Td (Hello, world) Tj ET"
+
+func TestPDFText(t *testing.T) {
+	objStmHeader := "1 0 2 50 3 150 "
+	objStmBody := fmt.Sprintf("%-50s%-100s%s",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>")
+
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <0048> <0002> <0069> endbfchar\n" +
+		"1 beginbfrange <0010> <0012> <0061> endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+
+	tests := []struct {
+		name string
+		pdf  []byte
+		want string
+	}{
+		{
+			name: "uncompressed content",
+			pdf:  buildPDF(onePage(stream("", helloContent)), "/Root 1 0 R", xrefValid),
+			want: "Hello, world",
+		},
+		{
+			name: "flate-compressed content",
+			pdf:  buildPDF(onePage(stream("/Filter /FlateDecode", deflate(helloContent))), "/Root 1 0 R", xrefValid),
+			want: "Hello, world",
+		},
+		{
+			name: "filter array",
+			pdf:  buildPDF(onePage(stream("/Filter [/FlateDecode]", deflate(helloContent))), "/Root 1 0 R", xrefValid),
+			want: "Hello, world",
+		},
+		{
+			name: "corrupt flate stream",
+			pdf:  buildPDF(onePage(stream("/Filter /FlateDecode", "not zlib data")), "/Root 1 0 R", xrefValid),
+			want: "",
+		},
+		{
+			name: "unsupported filter",
+			pdf:  buildPDF(onePage(stream("/Filter /DCTDecode", helloContent)), "/Root 1 0 R", xrefValid),
+			want: "",
+		},
+		{
+			name: "lines and kerning",
+			pdf: buildPDF(onePage(stream("",
+				"BT /F1 12 Tf 72 700 Td (First) Tj 0 -14 Td [(Sec) -50 (ond) -300 (line)] TJ T* (Third) Tj ET")),
+				"/Root 1 0 R", xrefValid),
+			want: "First\nSecond line\nThird",
+		},
+		{
+			name: "escapes and WinAnsi",
+			pdf:  buildPDF(onePage(stream("", `BT /F1 12 Tf (caf\351 \(1\) \\ \101) Tj ET`)), "/Root 1 0 R", xrefValid),
+			want: `café (1) \ A`,
+		},
+		{
+			name: "pages in page tree order",
+			pdf: buildPDF([]pdfObject{
+				{1, "<< /Type /Catalog /Pages 2 0 R >>"},
+				{2, "<< /Type /Pages /Kids [5 0 R 3 0 R] /Count 2 >>"},
+				{3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"},
+				{4, stream("", "BT (Second) Tj ET")},
+				{5, "<< /Type /Page /Parent 2 0 R /Contents [6 0 R 7 0 R] >>"},
+				{6, stream("", "BT (First) Tj")},
+				{7, stream("", "( page) Tj ET")},
+			}, "/Root 1 0 R", xrefValid),
+			want: "First page\n\nSecond",
+		},
+		{
+			name: "broken xref table",
+			pdf:  buildPDF(onePage(stream("/Filter /FlateDecode", deflate(helloContent))), "/Root 1 0 R", xrefBroken),
+			want: "Hello, world",
+		},
+		{
+			name: "missing xref table and trailer",
+			pdf:  buildPDF(onePage(stream("", helloContent)), "", xrefMissing),
+			want: "Hello, world",
+		},
+		{
+			name: "truncated file",
+			pdf: func() []byte {
+				pdf := buildPDF(onePage(stream("", helloContent)), "/Root 1 0 R", xrefValid)
+				return pdf[:bytes.Index(pdf, []byte("5 0 obj"))+3]
+			}(),
+			want: "Hello, world",
+		},
+		{
+			name: "no page tree",
+			pdf: buildPDF([]pdfObject{
+				{3, "<< /Type /Page /Contents 4 0 R >>"},
+				{4, stream("", helloContent)},
+			}, "", xrefValid),
+			want: "Hello, world",
+		},
+		{
+			name: "incremental update",
+			pdf: func() []byte {
+				pdf := buildPDF(onePage(stream("", helloContent)), "/Root 1 0 R", xrefValid)
+				update := "4 0 obj\n" + stream("", "BT (Updated) Tj ET") + "\nendobj\n"
+				return append(pdf, update...)
+			}(),
+			want: "Updated",
+		},
+		{
+			name: "object stream",
+			pdf: buildPDF([]pdfObject{
+				{4, stream("", helloContent)},
+				{6, stream(fmt.Sprintf("/Type /ObjStm /N 3 /First %d /Filter /FlateDecode", len(objStmHeader)),
+					deflate(objStmHeader+objStmBody))},
+			}, "/Root 1 0 R", xrefValid),
+			want: "Hello, world",
+		},
+		{
+			name: "ToUnicode CMap",
+			pdf: buildPDF([]pdfObject{
+				{1, "<< /Type /Catalog /Pages 2 0 R >>"},
+				{2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"},
+				{3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>"},
+				{4, stream("", "BT /F1 12 Tf <00010002> Tj ( ) ' <001000110012> Tj ET")},
+				{5, "<< /Type /Font /Subtype /Type0 /BaseFont /Noto /ToUnicode 6 0 R >>"},
+				{6, stream("/Filter /FlateDecode", deflate(cmap))},
+			}, "/Root 1 0 R", xrefValid),
+			want: "Hi\nabc",
+		},
+		{
+			name: "form XObject",
+			pdf: buildPDF([]pdfObject{
+				{1, "<< /Type /Catalog /Pages 2 0 R >>"},
+				{2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"},
+				{3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /X1 5 0 R >> >> >>"},
+				{4, stream("", "q /X1 Do Q")},
+				{5, stream("/Type /XObject /Subtype /Form /BBox [0 0 100 100]", "BT (In a form) Tj ET")},
+			}, "/Root 1 0 R", xrefValid),
+			want: "In a form",
+		},
+		{
+			name: "self-referencing page tree",
+			pdf: buildPDF([]pdfObject{
+				{1, "<< /Type /Catalog /Pages 2 0 R >>"},
+				{2, "<< /Type /Pages /Kids [2 0 R 3 0 R] /Count 1 >>"},
+				{3, "<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"},
+				{4, stream("", helloContent)},
+			}, "/Root 1 0 R", xrefValid),
+			want: "Hello, world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Text(bytes.NewReader(tt.pdf), "application/pdf")
+			if err != nil {
+				t.Fatalf("Text: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFTextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pdf  []byte
+		want error
+	}{
+		{
+			name: "encrypted trailer",
+			pdf:  buildPDF(append(onePage(stream("", helloContent)), pdfObject{9, "<< /Filter /Standard /V 2 /R 3 >>"}), "/Root 1 0 R /Encrypt 9 0 R", xrefValid),
+			want: ErrEncrypted,
+		},
+		{
+			name: "encrypted cross-reference stream",
+			pdf: buildPDF(append(onePage(stream("", helloContent)),
+				pdfObject{8, stream("/Type /XRef /Size 10 /W [1 2 1] /Root 1 0 R /Encrypt 9 0 R", "\x00\x00\x00\x00")},
+				pdfObject{9, "<< /Filter /Standard /V 2 /R 3 >>"}), "", xrefMissing),
+			want: ErrEncrypted,
+		},
+		{
+			name: "encrypted incremental update",
+			pdf: func() []byte {
+				pdf := buildPDF(onePage(stream("", helloContent)), "/Root 1 0 R", xrefValid)
+				return append(pdf, "trailer\n<< /Size 6 /Root 1 0 R /Encrypt 9 0 R >>\n%%EOF\n"...)
+			}(),
+			want: ErrEncrypted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Text(bytes.NewReader(tt.pdf), "application/pdf"); !errors.Is(err, tt.want) {
+				t.Errorf("Text: err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Text(strings.NewReader("Hello, world"), "application/pdf"); err == nil {
+		t.Error("Text of a file that isn't a PDF: expected an error")
+	}
+}
+
+// FuzzPDFText checks that no file, however damaged, makes extraction panic
+// or return text that can't be stored
+func FuzzPDFText(f *testing.F) {
+	f.Add(buildPDF(onePage(stream("", helloContent)), "/Root 1 0 R", xrefValid))
+	f.Add(buildPDF(onePage(stream("/Filter /FlateDecode", deflate(helloContent))), "/Root 1 0 R", xrefBroken))
+	f.Add(buildPDF(onePage(stream("", "BT /F1 12 Tf [(a) -300 <0041>] TJ 0 -1 Td (b\\051) ' ET")), "", xrefMissing))
+	f.Add(buildPDF([]pdfObject{
+		{6, stream("/Type /ObjStm /N 2 /First 8", "1 0 2 10 << /A 1 >> [1 2 R]")},
+		{7, stream("/Type /XObject /Subtype /Form", "/X1 Do BI /W 1 ID \x00\xff EI")},
+	}, "/Encrypt 9 0 R", xrefValid))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /Type /Page /Contents [1 0 R 1 0 R] >> endobj"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		text, err := Text(bytes.NewReader(data), "application/pdf")
+		if err != nil {
+			return
+		}
+		if !utf8.ValidString(text) || strings.ContainsRune(text, 0) {
+			t.Errorf("Text returned text that isn't valid UTF-8 without NULs: %q", text)
+		}
+		if len(text) > MaxTextBytes {
+			t.Errorf("Text returned %d bytes, over MaxTextBytes", len(text))
+		}
+	})
+}
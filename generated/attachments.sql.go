@@ -11,6 +11,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const clearAttachmentsOrphaned = `-- name: ClearAttachmentsOrphaned :exec
+UPDATE attachments
+SET orphaned_at = NULL
+WHERE id = ANY($1::uuid[]) AND orphaned_at IS NOT NULL
+`
+
+func (q *Queries) ClearAttachmentsOrphaned(ctx context.Context, ids []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, clearAttachmentsOrphaned, ids)
+	return err
+}
+
 const createAttachment = `-- name: CreateAttachment :one
 INSERT INTO attachments (
   user_id,
@@ -18,20 +29,22 @@ INSERT INTO attachments (
   filename,
   mime_type,
   size_bytes,
-  data
+  storage_backend,
+  storage_key
 ) VALUES (
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7
 )
-RETURNING id, user_id, entry_id, filename, mime_type, size_bytes, data, created_at
+RETURNING id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at
 `
 
 type CreateAttachmentParams struct {
-	UserID    pgtype.UUID `json:"user_id"`
-	EntryID   pgtype.UUID `json:"entry_id"`
-	Filename  string      `json:"filename"`
-	MimeType  string      `json:"mime_type"`
-	SizeBytes int64       `json:"size_bytes"`
-	Data      []byte      `json:"data"`
+	UserID         pgtype.UUID `json:"user_id"`
+	EntryID        pgtype.UUID `json:"entry_id"`
+	Filename       string      `json:"filename"`
+	MimeType       string      `json:"mime_type"`
+	SizeBytes      int64       `json:"size_bytes"`
+	StorageBackend string      `json:"storage_backend"`
+	StorageKey     string      `json:"storage_key"`
 }
 
 func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error) {
@@ -41,7 +54,8 @@ func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentPara
 		arg.Filename,
 		arg.MimeType,
 		arg.SizeBytes,
-		arg.Data,
+		arg.StorageBackend,
+		arg.StorageKey,
 	)
 	var i Attachment
 	err := row.Scan(
@@ -51,8 +65,11 @@ func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentPara
 		&i.Filename,
 		&i.MimeType,
 		&i.SizeBytes,
-		&i.Data,
 		&i.CreatedAt,
+		&i.StorageBackend,
+		&i.StorageKey,
+		&i.OrphanedAt,
+		&i.ReferencedAt,
 	)
 	return i, err
 }
@@ -68,7 +85,7 @@ func (q *Queries) DeleteAttachment(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getAttachment = `-- name: GetAttachment :one
-SELECT id, user_id, entry_id, filename, mime_type, size_bytes, data, created_at FROM attachments
+SELECT id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at FROM attachments
 WHERE id = $1 LIMIT 1
 `
 
@@ -82,14 +99,185 @@ func (q *Queries) GetAttachment(ctx context.Context, id pgtype.UUID) (Attachment
 		&i.Filename,
 		&i.MimeType,
 		&i.SizeBytes,
-		&i.Data,
 		&i.CreatedAt,
+		&i.StorageBackend,
+		&i.StorageKey,
+		&i.OrphanedAt,
+		&i.ReferencedAt,
 	)
 	return i, err
 }
 
+const getAttachmentUsage = `-- name: GetAttachmentUsage :one
+SELECT COALESCE(SUM(size_bytes), 0)::bigint AS total_bytes
+FROM attachments
+WHERE user_id = $1
+`
+
+func (q *Queries) GetAttachmentUsage(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, getAttachmentUsage, userID)
+	var total_bytes int64
+	err := row.Scan(&total_bytes)
+	return total_bytes, err
+}
+
+const importAttachment = `-- name: ImportAttachment :one
+INSERT INTO attachments (
+  id,
+  user_id,
+  entry_id,
+  filename,
+  mime_type,
+  size_bytes,
+  storage_backend,
+  storage_key,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at
+`
+
+type ImportAttachmentParams struct {
+	ID             pgtype.UUID        `json:"id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	EntryID        pgtype.UUID        `json:"entry_id"`
+	Filename       string             `json:"filename"`
+	MimeType       string             `json:"mime_type"`
+	SizeBytes      int64              `json:"size_bytes"`
+	StorageBackend string             `json:"storage_backend"`
+	StorageKey     string             `json:"storage_key"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+// Restores an attachment from an export with its ID and upload time
+func (q *Queries) ImportAttachment(ctx context.Context, arg ImportAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, importAttachment,
+		arg.ID,
+		arg.UserID,
+		arg.EntryID,
+		arg.Filename,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.StorageBackend,
+		arg.StorageKey,
+		arg.CreatedAt,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.EntryID,
+		&i.Filename,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.StorageBackend,
+		&i.StorageKey,
+		&i.OrphanedAt,
+		&i.ReferencedAt,
+	)
+	return i, err
+}
+
+const listAttachmentsForCleanup = `-- name: ListAttachmentsForCleanup :many
+SELECT a.id, a.user_id, a.entry_id, a.filename, a.orphaned_at, a.referenced_at, e.body_delta
+FROM attachments a
+JOIN entries e ON e.id = a.entry_id
+WHERE e.archived = false
+  AND ($1::uuid IS NULL
+       OR (a.entry_id, a.id) > ($2::uuid, $1::uuid))
+ORDER BY a.entry_id, a.id
+LIMIT $3
+`
+
+type ListAttachmentsForCleanupParams struct {
+	AfterID      pgtype.UUID `json:"after_id"`
+	AfterEntryID pgtype.UUID `json:"after_entry_id"`
+	PageSize     int32       `json:"page_size"`
+}
+
+type ListAttachmentsForCleanupRow struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	EntryID      pgtype.UUID        `json:"entry_id"`
+	Filename     string             `json:"filename"`
+	OrphanedAt   pgtype.Timestamptz `json:"orphaned_at"`
+	ReferencedAt pgtype.Timestamptz `json:"referenced_at"`
+	BodyDelta    []byte             `json:"body_delta"`
+}
+
+// Pages through the attachments of entries that aren't archived, each with
+// the body of its entry, for the reference scan. The first page has a null
+// after_id; each later one starts after the last attachment of the page before.
+func (q *Queries) ListAttachmentsForCleanup(ctx context.Context, arg ListAttachmentsForCleanupParams) ([]ListAttachmentsForCleanupRow, error) {
+	rows, err := q.db.Query(ctx, listAttachmentsForCleanup, arg.AfterID, arg.AfterEntryID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAttachmentsForCleanupRow
+	for rows.Next() {
+		var i ListAttachmentsForCleanupRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.EntryID,
+			&i.Filename,
+			&i.OrphanedAt,
+			&i.ReferencedAt,
+			&i.BodyDelta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttachmentsForEntries = `-- name: ListAttachmentsForEntries :many
+SELECT id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at FROM attachments
+WHERE entry_id = ANY($1::uuid[])
+ORDER BY entry_id, created_at ASC
+`
+
+func (q *Queries) ListAttachmentsForEntries(ctx context.Context, entryIds []pgtype.UUID) ([]Attachment, error) {
+	rows, err := q.db.Query(ctx, listAttachmentsForEntries, entryIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.EntryID,
+			&i.Filename,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.StorageBackend,
+			&i.StorageKey,
+			&i.OrphanedAt,
+			&i.ReferencedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAttachmentsForEntry = `-- name: ListAttachmentsForEntry :many
-SELECT id, user_id, entry_id, filename, mime_type, size_bytes, data, created_at FROM attachments
+SELECT id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at FROM attachments
 WHERE entry_id = $1
 ORDER BY created_at ASC
 `
@@ -110,8 +298,87 @@ func (q *Queries) ListAttachmentsForEntry(ctx context.Context, entryID pgtype.UU
 			&i.Filename,
 			&i.MimeType,
 			&i.SizeBytes,
-			&i.Data,
 			&i.CreatedAt,
+			&i.StorageBackend,
+			&i.StorageKey,
+			&i.OrphanedAt,
+			&i.ReferencedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttachmentsInBackend = `-- name: ListAttachmentsInBackend :many
+SELECT id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at FROM attachments
+WHERE storage_backend = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListAttachmentsInBackend(ctx context.Context, storageBackend string) ([]Attachment, error) {
+	rows, err := q.db.Query(ctx, listAttachmentsInBackend, storageBackend)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.EntryID,
+			&i.Filename,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.StorageBackend,
+			&i.StorageKey,
+			&i.OrphanedAt,
+			&i.ReferencedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanedAttachments = `-- name: ListOrphanedAttachments :many
+SELECT id, user_id, entry_id, filename, mime_type, size_bytes, created_at, storage_backend, storage_key, orphaned_at, referenced_at FROM attachments
+WHERE user_id = $1 AND orphaned_at IS NOT NULL
+ORDER BY orphaned_at ASC
+`
+
+func (q *Queries) ListOrphanedAttachments(ctx context.Context, userID pgtype.UUID) ([]Attachment, error) {
+	rows, err := q.db.Query(ctx, listOrphanedAttachments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.EntryID,
+			&i.Filename,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.StorageBackend,
+			&i.StorageKey,
+			&i.OrphanedAt,
+			&i.ReferencedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -122,3 +389,47 @@ func (q *Queries) ListAttachmentsForEntry(ctx context.Context, entryID pgtype.UU
 	}
 	return items, nil
 }
+
+const markAttachmentsOrphaned = `-- name: MarkAttachmentsOrphaned :exec
+UPDATE attachments
+SET orphaned_at = NOW()
+WHERE id = ANY($1::uuid[]) AND orphaned_at IS NULL AND referenced_at IS NOT NULL
+`
+
+// Only attachments that were once referenced can be orphaned
+func (q *Queries) MarkAttachmentsOrphaned(ctx context.Context, ids []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markAttachmentsOrphaned, ids)
+	return err
+}
+
+const markAttachmentsReferenced = `-- name: MarkAttachmentsReferenced :exec
+UPDATE attachments
+SET referenced_at = NOW()
+WHERE entry_id = $1 AND id = ANY($2::uuid[]) AND referenced_at IS NULL
+`
+
+type MarkAttachmentsReferencedParams struct {
+	EntryID pgtype.UUID   `json:"entry_id"`
+	Ids     []pgtype.UUID `json:"ids"`
+}
+
+func (q *Queries) MarkAttachmentsReferenced(ctx context.Context, arg MarkAttachmentsReferencedParams) error {
+	_, err := q.db.Exec(ctx, markAttachmentsReferenced, arg.EntryID, arg.Ids)
+	return err
+}
+
+const setAttachmentBackend = `-- name: SetAttachmentBackend :exec
+UPDATE attachments
+SET storage_backend = $2
+WHERE id = $1
+`
+
+type SetAttachmentBackendParams struct {
+	ID             pgtype.UUID `json:"id"`
+	StorageBackend string      `json:"storage_backend"`
+}
+
+func (q *Queries) SetAttachmentBackend(ctx context.Context, arg SetAttachmentBackendParams) error {
+	_, err := q.db.Exec(ctx, setAttachmentBackend, arg.ID, arg.StorageBackend)
+	return err
+}
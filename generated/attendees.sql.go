@@ -11,14 +11,82 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const backfillAttendees = `-- name: BackfillAttendees :execrows
+INSERT INTO attendees (user_id, name, last_used, use_count)
+SELECT DISTINCT ON (e.user_id, lower(n.name)) e.user_id, n.name, e.updated_at, 0
+FROM entries e, unnest(e.attendees) AS n(name)
+WHERE e.archived = false
+  AND NOT EXISTS (
+    SELECT 1 FROM attendees a
+    WHERE a.user_id = e.user_id
+      AND (lower(a.name) = lower(n.name)
+           OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias))
+  )
+ORDER BY e.user_id, lower(n.name), e.updated_at DESC
+ON CONFLICT (user_id, name) DO NOTHING
+`
+
+// Creates attendee rows for names used in entries but missing from the table
+func (q *Queries) BackfillAttendees(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, backfillAttendees)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteAttendee = `-- name: DeleteAttendee :exec
+DELETE FROM attendees
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteAttendeeParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteAttendee(ctx context.Context, arg DeleteAttendeeParams) error {
+	_, err := q.db.Exec(ctx, deleteAttendee, arg.ID, arg.UserID)
+	return err
+}
+
+const getAttendeeByName = `-- name: GetAttendeeByName :one
+SELECT id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at FROM attendees
+WHERE user_id = $1 AND lower(name) = lower($2::text)
+LIMIT 1
+`
+
+type GetAttendeeByNameParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Name   string      `json:"name"`
+}
+
+func (q *Queries) GetAttendeeByName(ctx context.Context, arg GetAttendeeByNameParams) (Attendee, error) {
+	row := q.db.QueryRow(ctx, getAttendeeByName, arg.UserID, arg.Name)
+	var i Attendee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsed,
+		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getOrCreateAttendee = `-- name: GetOrCreateAttendee :one
 INSERT INTO attendees (user_id, name, last_used, use_count)
-VALUES ($1, $2, NOW(), 1)
+VALUES ($1, $2, NOW(), 0)
 ON CONFLICT (user_id, name) 
 DO UPDATE SET 
-  last_used = NOW(),
-  use_count = attendees.use_count + 1
-RETURNING id, user_id, name, created_at, last_used, use_count
+  last_used = NOW()
+RETURNING id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at
 `
 
 type GetOrCreateAttendeeParams struct {
@@ -26,6 +94,7 @@ type GetOrCreateAttendeeParams struct {
 	Name   string      `json:"name"`
 }
 
+// use_count is maintained by RecountAttendees
 func (q *Queries) GetOrCreateAttendee(ctx context.Context, arg GetOrCreateAttendeeParams) (Attendee, error) {
 	row := q.db.QueryRow(ctx, getOrCreateAttendee, arg.UserID, arg.Name)
 	var i Attendee
@@ -36,6 +105,40 @@ func (q *Queries) GetOrCreateAttendee(ctx context.Context, arg GetOrCreateAttend
 		&i.CreatedAt,
 		&i.LastUsed,
 		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPerson = `-- name: GetPerson :one
+SELECT id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at FROM attendees
+WHERE id = $1 AND user_id = $2 LIMIT 1
+`
+
+type GetPersonParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetPerson(ctx context.Context, arg GetPersonParams) (Attendee, error) {
+	row := q.db.QueryRow(ctx, getPerson, arg.ID, arg.UserID)
+	var i Attendee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsed,
+		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -118,6 +221,328 @@ func (q *Queries) GetTopAttendees(ctx context.Context, arg GetTopAttendeesParams
 	return items, nil
 }
 
+const listAttendeeNames = `-- name: ListAttendeeNames :many
+SELECT name, aliases FROM attendees
+WHERE user_id = $1
+`
+
+type ListAttendeeNamesRow struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+func (q *Queries) ListAttendeeNames(ctx context.Context, userID pgtype.UUID) ([]ListAttendeeNamesRow, error) {
+	rows, err := q.db.Query(ctx, listAttendeeNames, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAttendeeNamesRow
+	for rows.Next() {
+		var i ListAttendeeNamesRow
+		if err := rows.Scan(&i.Name, &i.Aliases); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesForAttendeeRewrite = `-- name: ListEntriesForAttendeeRewrite :many
+SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
+WHERE user_id = $1
+  AND EXISTS (
+    SELECT 1 FROM unnest(attendees) AS a(name)
+    WHERE lower(a.name) = ANY($2::text[])
+  )
+ORDER BY id
+FOR UPDATE
+`
+
+type ListEntriesForAttendeeRewriteParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Names  []string    `json:"names"`
+}
+
+// Every entry naming any of names, archived ones too, locked so it can be
+// rewritten when people are renamed or merged
+func (q *Queries) ListEntriesForAttendeeRewrite(ctx context.Context, arg ListEntriesForAttendeeRewriteParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesForAttendeeRewrite, arg.UserID, arg.Names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.BodyDelta,
+			&i.BodyHtml,
+			&i.RenderVersion,
+			&i.AttendeesOriginal,
+			&i.Attendees,
+			&i.Type,
+			&i.DayYear,
+			&i.DayMonth,
+			&i.DayDay,
+			&i.Archived,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EmbeddingVector,
+			&i.VectorsUpdatedAt,
+			&i.BodyText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesForPerson = `-- name: ListEntriesForPerson :many
+SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
+WHERE user_id = $1
+  AND archived = false
+  AND EXISTS (
+    SELECT 1 FROM unnest(attendees) AS a(name)
+    WHERE lower(a.name) = ANY($2::text[])
+  )
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC
+`
+
+type ListEntriesForPersonParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Names  []string    `json:"names"`
+}
+
+// Matches the person's name and aliases case-insensitively
+func (q *Queries) ListEntriesForPerson(ctx context.Context, arg ListEntriesForPersonParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesForPerson, arg.UserID, arg.Names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.BodyDelta,
+			&i.BodyHtml,
+			&i.RenderVersion,
+			&i.AttendeesOriginal,
+			&i.Attendees,
+			&i.Type,
+			&i.DayYear,
+			&i.DayMonth,
+			&i.DayDay,
+			&i.Archived,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EmbeddingVector,
+			&i.VectorsUpdatedAt,
+			&i.BodyText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPeople = `-- name: ListPeople :many
+SELECT id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at FROM attendees
+WHERE user_id = $1
+ORDER BY use_count DESC, name ASC
+`
+
+func (q *Queries) ListPeople(ctx context.Context, userID pgtype.UUID) ([]Attendee, error) {
+	rows, err := q.db.Query(ctx, listPeople, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attendee
+	for rows.Next() {
+		var i Attendee
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.LastUsed,
+			&i.UseCount,
+			&i.Email,
+			&i.Role,
+			&i.Notes,
+			&i.Aliases,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeAttendee = `-- name: MergeAttendee :one
+UPDATE attendees
+SET email = $3,
+    role = $4,
+    notes = $5,
+    aliases = $6,
+    use_count = $7,
+    last_used = $8,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at
+`
+
+type MergeAttendeeParams struct {
+	ID       pgtype.UUID        `json:"id"`
+	UserID   pgtype.UUID        `json:"user_id"`
+	Email    string             `json:"email"`
+	Role     string             `json:"role"`
+	Notes    string             `json:"notes"`
+	Aliases  []string           `json:"aliases"`
+	UseCount int32              `json:"use_count"`
+	LastUsed pgtype.Timestamptz `json:"last_used"`
+}
+
+func (q *Queries) MergeAttendee(ctx context.Context, arg MergeAttendeeParams) (Attendee, error) {
+	row := q.db.QueryRow(ctx, mergeAttendee,
+		arg.ID,
+		arg.UserID,
+		arg.Email,
+		arg.Role,
+		arg.Notes,
+		arg.Aliases,
+		arg.UseCount,
+		arg.LastUsed,
+	)
+	var i Attendee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsed,
+		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const recountAllAttendees = `-- name: RecountAllAttendees :execrows
+UPDATE attendees a
+SET use_count = (
+  SELECT COUNT(*) FROM entries e
+  WHERE e.user_id = a.user_id
+    AND e.archived = false
+    AND EXISTS (
+      SELECT 1 FROM unnest(e.attendees) AS n(name)
+      WHERE lower(n.name) = lower(a.name)
+         OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias)
+    )
+)
+`
+
+func (q *Queries) RecountAllAttendees(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, recountAllAttendees)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recountAttendees = `-- name: RecountAttendees :exec
+UPDATE attendees a
+SET use_count = (
+  SELECT COUNT(*) FROM entries e
+  WHERE e.user_id = a.user_id
+    AND e.archived = false
+    AND EXISTS (
+      SELECT 1 FROM unnest(e.attendees) AS n(name)
+      WHERE lower(n.name) = lower(a.name)
+         OR lower(n.name) IN (SELECT lower(alias) FROM unnest(a.aliases) AS alias)
+    )
+)
+WHERE a.user_id = $1
+  AND lower(a.name) = ANY($2::text[])
+`
+
+type RecountAttendeesParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Names  []string    `json:"names"`
+}
+
+// Derives use_count from the non-archived entries naming the attendee or an alias
+func (q *Queries) RecountAttendees(ctx context.Context, arg RecountAttendeesParams) error {
+	_, err := q.db.Exec(ctx, recountAttendees, arg.UserID, arg.Names)
+	return err
+}
+
+const renameAttendee = `-- name: RenameAttendee :one
+UPDATE attendees
+SET name = $3,
+    aliases = $4,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at
+`
+
+type RenameAttendeeParams struct {
+	ID      pgtype.UUID `json:"id"`
+	UserID  pgtype.UUID `json:"user_id"`
+	Name    string      `json:"name"`
+	Aliases []string    `json:"aliases"`
+}
+
+func (q *Queries) RenameAttendee(ctx context.Context, arg RenameAttendeeParams) (Attendee, error) {
+	row := q.db.QueryRow(ctx, renameAttendee,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Aliases,
+	)
+	var i Attendee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsed,
+		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const searchAttendees = `-- name: SearchAttendees :many
 SELECT name, last_used, use_count
 FROM attendees
@@ -158,3 +583,49 @@ func (q *Queries) SearchAttendees(ctx context.Context, arg SearchAttendeesParams
 	}
 	return items, nil
 }
+
+const updatePerson = `-- name: UpdatePerson :one
+UPDATE attendees
+SET email = $3,
+    role = $4,
+    notes = $5,
+    aliases = $6,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, created_at, last_used, use_count, email, role, notes, aliases, updated_at
+`
+
+type UpdatePersonParams struct {
+	ID      pgtype.UUID `json:"id"`
+	UserID  pgtype.UUID `json:"user_id"`
+	Email   string      `json:"email"`
+	Role    string      `json:"role"`
+	Notes   string      `json:"notes"`
+	Aliases []string    `json:"aliases"`
+}
+
+func (q *Queries) UpdatePerson(ctx context.Context, arg UpdatePersonParams) (Attendee, error) {
+	row := q.db.QueryRow(ctx, updatePerson,
+		arg.ID,
+		arg.UserID,
+		arg.Email,
+		arg.Role,
+		arg.Notes,
+		arg.Aliases,
+	)
+	var i Attendee
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.LastUsed,
+		&i.UseCount,
+		&i.Email,
+		&i.Role,
+		&i.Notes,
+		&i.Aliases,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -82,6 +82,78 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry
 	return i, err
 }
 
+const filterEntriesForExport = `-- name: FilterEntriesForExport :many
+SELECT id FROM entries
+WHERE user_id = $1
+  AND id = ANY($2::uuid[])
+  AND ($3::date IS NULL
+       OR make_date(day_year, day_month, day_day) >= $3::date)
+  AND ($4::date IS NULL
+       OR make_date(day_year, day_month, day_day) <= $4::date)
+  AND (cardinality($5::text[]) = 0 OR type = ANY($5::text[]))
+  AND (
+    cardinality($6::text[]) = 0
+    OR EXISTS (
+      SELECT 1 FROM unnest(attendees) AS a(name)
+      WHERE lower(a.name) = ANY($6::text[])
+    )
+  )
+  AND (
+    cardinality($7::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = $1
+        AND t.name = ANY($7::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality($7::text[])
+    )
+  )
+  AND ($8::bool OR archived = false)
+`
+
+type FilterEntriesForExportParams struct {
+	UserID          pgtype.UUID   `json:"user_id"`
+	EntryIds        []pgtype.UUID `json:"entry_ids"`
+	FromDate        pgtype.Date   `json:"from_date"`
+	ToDate          pgtype.Date   `json:"to_date"`
+	Types           []string      `json:"types"`
+	AttendeeNames   []string      `json:"attendee_names"`
+	Tags            []string      `json:"tags"`
+	IncludeArchived bool          `json:"include_archived"`
+}
+
+// Which of the given entries an export with the same filters includes
+func (q *Queries) FilterEntriesForExport(ctx context.Context, arg FilterEntriesForExportParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, filterEntriesForExport,
+		arg.UserID,
+		arg.EntryIds,
+		arg.FromDate,
+		arg.ToDate,
+		arg.Types,
+		arg.AttendeeNames,
+		arg.Tags,
+		arg.IncludeArchived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDaysWithEntries = `-- name: GetDaysWithEntries :many
 SELECT DISTINCT day_year, day_month, day_day
 FROM entries
@@ -155,14 +227,180 @@ func (q *Queries) GetEntry(ctx context.Context, id pgtype.UUID) (Entry, error) {
 	return i, err
 }
 
-const listAllEntries = `-- name: ListAllEntries :many
+const getEntryForUpdate = `-- name: GetEntryForUpdate :one
 SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
-WHERE archived = false
-ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at ASC
+WHERE id = $1 AND archived = false LIMIT 1
+FOR UPDATE
+`
+
+// Locks the entry row until the end of the transaction
+func (q *Queries) GetEntryForUpdate(ctx context.Context, id pgtype.UUID) (Entry, error) {
+	row := q.db.QueryRow(ctx, getEntryForUpdate, id)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.BodyDelta,
+		&i.BodyHtml,
+		&i.RenderVersion,
+		&i.AttendeesOriginal,
+		&i.Attendees,
+		&i.Type,
+		&i.DayYear,
+		&i.DayMonth,
+		&i.DayDay,
+		&i.Archived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmbeddingVector,
+		&i.VectorsUpdatedAt,
+		&i.BodyText,
+	)
+	return i, err
+}
+
+const getEntryForImport = `-- name: GetEntryForImport :one
+SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+// Includes archived entries, and locks the row until the end of the transaction
+func (q *Queries) GetEntryForImport(ctx context.Context, id pgtype.UUID) (Entry, error) {
+	row := q.db.QueryRow(ctx, getEntryForImport, id)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.BodyDelta,
+		&i.BodyHtml,
+		&i.RenderVersion,
+		&i.AttendeesOriginal,
+		&i.Attendees,
+		&i.Type,
+		&i.DayYear,
+		&i.DayMonth,
+		&i.DayDay,
+		&i.Archived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmbeddingVector,
+		&i.VectorsUpdatedAt,
+		&i.BodyText,
+	)
+	return i, err
+}
+
+const importEntry = `-- name: ImportEntry :one
+INSERT INTO entries (
+  id,
+  user_id,
+  title,
+  body_delta,
+  body_html,
+  body_text,
+  attendees_original,
+  attendees,
+  type,
+  day_year,
+  day_month,
+  day_day,
+  archived,
+  created_at,
+  updated_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+RETURNING id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text
+`
+
+type ImportEntryParams struct {
+	ID                pgtype.UUID        `json:"id"`
+	UserID            pgtype.UUID        `json:"user_id"`
+	Title             string             `json:"title"`
+	BodyDelta         []byte             `json:"body_delta"`
+	BodyHtml          string             `json:"body_html"`
+	BodyText          string             `json:"body_text"`
+	AttendeesOriginal string             `json:"attendees_original"`
+	Attendees         []string           `json:"attendees"`
+	Type              string             `json:"type"`
+	DayYear           int32              `json:"day_year"`
+	DayMonth          int32              `json:"day_month"`
+	DayDay            int32              `json:"day_day"`
+	Archived          bool               `json:"archived"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Restores an entry from an export with its ID, archived state and timestamps
+func (q *Queries) ImportEntry(ctx context.Context, arg ImportEntryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, importEntry,
+		arg.ID,
+		arg.UserID,
+		arg.Title,
+		arg.BodyDelta,
+		arg.BodyHtml,
+		arg.BodyText,
+		arg.AttendeesOriginal,
+		arg.Attendees,
+		arg.Type,
+		arg.DayYear,
+		arg.DayMonth,
+		arg.DayDay,
+		arg.Archived,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.BodyDelta,
+		&i.BodyHtml,
+		&i.RenderVersion,
+		&i.AttendeesOriginal,
+		&i.Attendees,
+		&i.Type,
+		&i.DayYear,
+		&i.DayMonth,
+		&i.DayDay,
+		&i.Archived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmbeddingVector,
+		&i.VectorsUpdatedAt,
+		&i.BodyText,
+	)
+	return i, err
+}
+
+const listEntriesForDay = `-- name: ListEntriesForDay :many
+SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
+WHERE user_id = $1
+  AND day_year = $2
+  AND day_month = $3
+  AND day_day = $4
+  AND archived = false
+ORDER BY created_at ASC
 `
 
-func (q *Queries) ListAllEntries(ctx context.Context) ([]Entry, error) {
-	rows, err := q.db.Query(ctx, listAllEntries)
+type ListEntriesForDayParams struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	DayYear  int32       `json:"day_year"`
+	DayMonth int32       `json:"day_month"`
+	DayDay   int32       `json:"day_day"`
+}
+
+func (q *Queries) ListEntriesForDay(ctx context.Context, arg ListEntriesForDayParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesForDay,
+		arg.UserID,
+		arg.DayYear,
+		arg.DayMonth,
+		arg.DayDay,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -200,29 +438,104 @@ func (q *Queries) ListAllEntries(ctx context.Context) ([]Entry, error) {
 	return items, nil
 }
 
-const listEntriesForDay = `-- name: ListEntriesForDay :many
+const listEntryIDs = `-- name: ListEntryIDs :many
+SELECT id FROM entries
+WHERE archived = false
+ORDER BY created_at, id
+`
+
+// Every live entry, oldest first, for backfills
+func (q *Queries) ListEntryIDs(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listEntryIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesForExport = `-- name: ListEntriesForExport :many
 SELECT id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text FROM entries
 WHERE user_id = $1
-  AND day_year = $2
-  AND day_month = $3
-  AND day_day = $4
-  AND archived = false
-ORDER BY created_at ASC
+  AND ($2::uuid IS NULL
+       OR (day_year, day_month, day_day, created_at, id) <
+          ($3::int, $4::int, $5::int,
+           $6::timestamptz, $2::uuid))
+  AND ($7::date IS NULL
+       OR make_date(day_year, day_month, day_day) >= $7::date)
+  AND ($8::date IS NULL
+       OR make_date(day_year, day_month, day_day) <= $8::date)
+  AND (cardinality($9::text[]) = 0 OR type = ANY($9::text[]))
+  AND (
+    cardinality($10::text[]) = 0
+    OR EXISTS (
+      SELECT 1 FROM unnest(attendees) AS a(name)
+      WHERE lower(a.name) = ANY($10::text[])
+    )
+  )
+  AND (
+    cardinality($11::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = $1
+        AND t.name = ANY($11::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality($11::text[])
+    )
+  )
+  AND ($12::bool OR archived = false)
+ORDER BY day_year DESC, day_month DESC, day_day DESC, created_at DESC, id DESC
+LIMIT $13
 `
 
-type ListEntriesForDayParams struct {
-	UserID   pgtype.UUID `json:"user_id"`
-	DayYear  int32       `json:"day_year"`
-	DayMonth int32       `json:"day_month"`
-	DayDay   int32       `json:"day_day"`
+type ListEntriesForExportParams struct {
+	UserID          pgtype.UUID        `json:"user_id"`
+	AfterID         pgtype.UUID        `json:"after_id"`
+	AfterYear       int32              `json:"after_year"`
+	AfterMonth      int32              `json:"after_month"`
+	AfterDay        int32              `json:"after_day"`
+	AfterCreatedAt  pgtype.Timestamptz `json:"after_created_at"`
+	FromDate        pgtype.Date        `json:"from_date"`
+	ToDate          pgtype.Date        `json:"to_date"`
+	Types           []string           `json:"types"`
+	AttendeeNames   []string           `json:"attendee_names"`
+	Tags            []string           `json:"tags"`
+	IncludeArchived bool               `json:"include_archived"`
+	PageSize        int32              `json:"page_size"`
 }
 
-func (q *Queries) ListEntriesForDay(ctx context.Context, arg ListEntriesForDayParams) ([]Entry, error) {
-	rows, err := q.db.Query(ctx, listEntriesForDay,
+// Pages through a user's entries, newest first, archived ones too when
+// include_archived is set. The first page has a null after_id; each later one
+// starts after the last entry of the page before. Null dates and empty lists
+// don't filter: types and attendees match any given, tags must all be present.
+func (q *Queries) ListEntriesForExport(ctx context.Context, arg ListEntriesForExportParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesForExport,
 		arg.UserID,
-		arg.DayYear,
-		arg.DayMonth,
-		arg.DayDay,
+		arg.AfterID,
+		arg.AfterYear,
+		arg.AfterMonth,
+		arg.AfterDay,
+		arg.AfterCreatedAt,
+		arg.FromDate,
+		arg.ToDate,
+		arg.Types,
+		arg.AttendeeNames,
+		arg.Tags,
+		arg.IncludeArchived,
+		arg.PageSize,
 	)
 	if err != nil {
 		return nil, err
@@ -261,6 +574,85 @@ func (q *Queries) ListEntriesForDay(ctx context.Context, arg ListEntriesForDayPa
 	return items, nil
 }
 
+const restoreEntry = `-- name: RestoreEntry :one
+UPDATE entries
+SET title = $2,
+    body_delta = $3,
+    body_html = $4,
+    body_text = $5,
+    attendees_original = $6,
+    attendees = $7,
+    type = $8,
+    day_year = $9,
+    day_month = $10,
+    day_day = $11,
+    archived = $12,
+    created_at = $13,
+    updated_at = $14,
+    vectors_updated_at = NULL
+WHERE id = $1
+RETURNING id, user_id, title, body_delta, body_html, render_version, attendees_original, attendees, type, day_year, day_month, day_day, archived, created_at, updated_at, embedding_vector, vectors_updated_at, body_text
+`
+
+type RestoreEntryParams struct {
+	ID                pgtype.UUID        `json:"id"`
+	Title             string             `json:"title"`
+	BodyDelta         []byte             `json:"body_delta"`
+	BodyHtml          string             `json:"body_html"`
+	BodyText          string             `json:"body_text"`
+	AttendeesOriginal string             `json:"attendees_original"`
+	Attendees         []string           `json:"attendees"`
+	Type              string             `json:"type"`
+	DayYear           int32              `json:"day_year"`
+	DayMonth          int32              `json:"day_month"`
+	DayDay            int32              `json:"day_day"`
+	Archived          bool               `json:"archived"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Overwrites an entry with its exported state; it is re-embedded afterwards
+func (q *Queries) RestoreEntry(ctx context.Context, arg RestoreEntryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, restoreEntry,
+		arg.ID,
+		arg.Title,
+		arg.BodyDelta,
+		arg.BodyHtml,
+		arg.BodyText,
+		arg.AttendeesOriginal,
+		arg.Attendees,
+		arg.Type,
+		arg.DayYear,
+		arg.DayMonth,
+		arg.DayDay,
+		arg.Archived,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.BodyDelta,
+		&i.BodyHtml,
+		&i.RenderVersion,
+		&i.AttendeesOriginal,
+		&i.Attendees,
+		&i.Type,
+		&i.DayYear,
+		&i.DayMonth,
+		&i.DayDay,
+		&i.Archived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmbeddingVector,
+		&i.VectorsUpdatedAt,
+		&i.BodyText,
+	)
+	return i, err
+}
+
 const softDeleteEntry = `-- name: SoftDeleteEntry :exec
 UPDATE entries
 SET archived = true,
@@ -332,3 +724,24 @@ func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry
 	)
 	return i, err
 }
+
+const updateEntryAttendees = `-- name: UpdateEntryAttendees :exec
+UPDATE entries
+SET attendees_original = $2,
+    attendees = $3,
+    updated_at = NOW(),
+    vectors_updated_at = NULL
+WHERE id = $1
+`
+
+type UpdateEntryAttendeesParams struct {
+	ID                pgtype.UUID `json:"id"`
+	AttendeesOriginal string      `json:"attendees_original"`
+	Attendees         []string    `json:"attendees"`
+}
+
+// Clears vectors_updated_at so the entry is re-embedded
+func (q *Queries) UpdateEntryAttendees(ctx context.Context, arg UpdateEntryAttendeesParams) error {
+	_, err := q.db.Exec(ctx, updateEntryAttendees, arg.ID, arg.AttendeesOriginal, arg.Attendees)
+	return err
+}
@@ -10,16 +10,44 @@ import (
 )
 
 type Attachment struct {
-	ID        pgtype.UUID        `json:"id"`
-	UserID    pgtype.UUID        `json:"user_id"`
-	EntryID   pgtype.UUID        `json:"entry_id"`
-	Filename  string             `json:"filename"`
-	MimeType  string             `json:"mime_type"`
-	SizeBytes int64              `json:"size_bytes"`
+	ID             pgtype.UUID        `json:"id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	EntryID        pgtype.UUID        `json:"entry_id"`
+	Filename       string             `json:"filename"`
+	MimeType       string             `json:"mime_type"`
+	SizeBytes      int64              `json:"size_bytes"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	StorageBackend string             `json:"storage_backend"`
+	StorageKey     string             `json:"storage_key"`
+	OrphanedAt     pgtype.Timestamptz `json:"orphaned_at"`
+	ReferencedAt   pgtype.Timestamptz `json:"referenced_at"`
+}
+
+type AttachmentBlob struct {
+	Key       string             `json:"key"`
 	Data      []byte             `json:"data"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type AttachmentText struct {
+	AttachmentID     pgtype.UUID         `json:"attachment_id"`
+	Content          string              `json:"content"`
+	EmbeddingVector  *pgvector_go.Vector `json:"embedding_vector"`
+	VectorsUpdatedAt pgtype.Timestamptz  `json:"vectors_updated_at"`
+	CreatedAt        pgtype.Timestamptz  `json:"created_at"`
+}
+
+type AttachmentThumbnail struct {
+	AttachmentID   pgtype.UUID        `json:"attachment_id"`
+	Width          int32              `json:"width"`
+	Height         int32              `json:"height"`
+	MimeType       string             `json:"mime_type"`
+	SizeBytes      int64              `json:"size_bytes"`
+	StorageBackend string             `json:"storage_backend"`
+	StorageKey     string             `json:"storage_key"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
 type Attendee struct {
 	ID        pgtype.UUID        `json:"id"`
 	UserID    pgtype.UUID        `json:"user_id"`
@@ -27,6 +55,19 @@ type Attendee struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	LastUsed  pgtype.Timestamptz `json:"last_used"`
 	UseCount  int32              `json:"use_count"`
+	Email     string             `json:"email"`
+	Role      string             `json:"role"`
+	Notes     string             `json:"notes"`
+	Aliases   []string           `json:"aliases"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Blob struct {
+	StorageBackend string             `json:"storage_backend"`
+	StorageKey     string             `json:"storage_key"`
+	SizeBytes      int64              `json:"size_bytes"`
+	RefCount       int32              `json:"ref_count"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
 
 type Entry struct {
@@ -50,6 +91,98 @@ type Entry struct {
 	BodyText          string              `json:"body_text"`
 }
 
+type EntryFlag struct {
+	EntryID          pgtype.UUID        `json:"entry_id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	Pinned           bool               `json:"pinned"`
+	PinnedPosition   int32              `json:"pinned_position"`
+	Favorite         bool               `json:"favorite"`
+	FavoritePosition int32              `json:"favorite_position"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+type EntryLink struct {
+	ID            pgtype.UUID        `json:"id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	SourceEntryID pgtype.UUID        `json:"source_entry_id"`
+	TargetEntryID pgtype.UUID        `json:"target_entry_id"`
+	TargetTitle   string             `json:"target_title"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type EntryTag struct {
+	EntryID pgtype.UUID `json:"entry_id"`
+	TagID   pgtype.UUID `json:"tag_id"`
+}
+
+type EntryType struct {
+	ID                   pgtype.UUID        `json:"id"`
+	UserID               pgtype.UUID        `json:"user_id"`
+	Name                 string             `json:"name"`
+	Color                string             `json:"color"`
+	Icon                 string             `json:"icon"`
+	DefaultTemplateDelta []byte             `json:"default_template_delta"`
+	HasAttendees         bool               `json:"has_attendees"`
+	Position             int32              `json:"position"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Recurrence struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	TemplateID pgtype.UUID        `json:"template_id"`
+	Rrule      string             `json:"rrule"`
+	StartsOn   pgtype.Date        `json:"starts_on"`
+	EndsOn     pgtype.Date        `json:"ends_on"`
+	Paused     bool               `json:"paused"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	ResumedAt  pgtype.Timestamptz `json:"resumed_at"`
+}
+
+type RecurrenceOccurrence struct {
+	RecurrenceID pgtype.UUID        `json:"recurrence_id"`
+	OccursOn     pgtype.Date        `json:"occurs_on"`
+	EntryID      pgtype.UUID        `json:"entry_id"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type Tag struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Name      string             `json:"name"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	LastUsed  pgtype.Timestamptz `json:"last_used"`
+}
+
+type Task struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	EntryID     pgtype.UUID        `json:"entry_id"`
+	Position    int32              `json:"position"`
+	Text        string             `json:"text"`
+	Done        bool               `json:"done"`
+	DueDate     pgtype.Date        `json:"due_date"`
+	Owner       pgtype.Text        `json:"owner"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Template struct {
+	ID               pgtype.UUID        `json:"id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	Name             string             `json:"name"`
+	TitlePattern     string             `json:"title_pattern"`
+	BodyDelta        []byte             `json:"body_delta"`
+	DefaultType      pgtype.Text        `json:"default_type"`
+	DefaultAttendees string             `json:"default_attendees"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
 type User struct {
 	ID          pgtype.UUID        `json:"id"`
 	Email       string             `json:"email"`
@@ -19,6 +19,25 @@ WHERE user_id = $1
     title ILIKE '%' || $2 || '%'
     OR body_html ILIKE '%' || $2 || '%'
     OR $2 = ANY(attendees)
+    OR id IN (
+      SELECT a.entry_id
+      FROM attachments a
+      JOIN attachment_texts at ON at.attachment_id = a.id
+      WHERE a.user_id = $1
+        AND at.content ILIKE '%' || $2 || '%'
+    )
+  )
+  AND (
+    cardinality($3::text[]) = 0
+    OR id IN (
+      SELECT et.entry_id
+      FROM entry_tags et
+      JOIN tags t ON t.id = et.tag_id
+      WHERE t.user_id = $1
+        AND t.name = ANY($3::text[])
+      GROUP BY et.entry_id
+      HAVING COUNT(*) = cardinality($3::text[])
+    )
   )
 ORDER BY created_at DESC
 LIMIT 100
@@ -27,10 +46,11 @@ LIMIT 100
 type SearchEntriesParams struct {
 	UserID  pgtype.UUID `json:"user_id"`
 	Column2 pgtype.Text `json:"column_2"`
+	Tags    []string    `json:"tags"`
 }
 
 func (q *Queries) SearchEntries(ctx context.Context, arg SearchEntriesParams) ([]Entry, error) {
-	rows, err := q.db.Query(ctx, searchEntries, arg.UserID, arg.Column2)
+	rows, err := q.db.Query(ctx, searchEntries, arg.UserID, arg.Column2, arg.Tags)
 	if err != nil {
 		return nil, err
 	}
@@ -13,6 +13,48 @@ import (
 	pgvector_go "github.com/pgvector/pgvector-go"
 )
 
+const getAttachmentsNeedingVectors = `-- name: GetAttachmentsNeedingVectors :many
+SELECT t.attachment_id, a.filename, t.content
+FROM attachment_texts t
+JOIN attachments a ON a.id = t.attachment_id
+WHERE a.user_id = $1
+  AND t.content <> ''
+  AND t.embedding_vector IS NULL
+ORDER BY t.created_at DESC
+LIMIT $2
+`
+
+type GetAttachmentsNeedingVectorsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Limit  int32       `json:"limit"`
+}
+
+type GetAttachmentsNeedingVectorsRow struct {
+	AttachmentID pgtype.UUID `json:"attachment_id"`
+	Filename     string      `json:"filename"`
+	Content      string      `json:"content"`
+}
+
+func (q *Queries) GetAttachmentsNeedingVectors(ctx context.Context, arg GetAttachmentsNeedingVectorsParams) ([]GetAttachmentsNeedingVectorsRow, error) {
+	rows, err := q.db.Query(ctx, getAttachmentsNeedingVectors, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAttachmentsNeedingVectorsRow
+	for rows.Next() {
+		var i GetAttachmentsNeedingVectorsRow
+		if err := rows.Scan(&i.AttachmentID, &i.Filename, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEntriesNeedingVectors = `-- name: GetEntriesNeedingVectors :many
 SELECT id, title, body_text, created_at, updated_at
 FROM entries
@@ -64,6 +106,71 @@ func (q *Queries) GetEntriesNeedingVectors(ctx context.Context, arg GetEntriesNe
 	return items, nil
 }
 
+const searchSimilarAttachments = `-- name: SearchSimilarAttachments :many
+SELECT a.id, a.entry_id, a.filename, a.mime_type,
+       left(t.content, 2000)::text AS excerpt,
+       e.title AS entry_title, e.day_year, e.day_month, e.day_day,
+       (t.embedding_vector <-> $2::vector) AS distance
+FROM attachment_texts t
+JOIN attachments a ON a.id = t.attachment_id
+JOIN entries e ON e.id = a.entry_id
+WHERE a.user_id = $1
+  AND e.archived = false
+  AND t.embedding_vector IS NOT NULL
+ORDER BY t.embedding_vector <-> $2::vector
+LIMIT $3
+`
+
+type SearchSimilarAttachmentsParams struct {
+	UserID  pgtype.UUID     `json:"user_id"`
+	Column2 pgvector.Vector `json:"column_2"`
+	Limit   int32           `json:"limit"`
+}
+
+type SearchSimilarAttachmentsRow struct {
+	ID         pgtype.UUID `json:"id"`
+	EntryID    pgtype.UUID `json:"entry_id"`
+	Filename   string      `json:"filename"`
+	MimeType   string      `json:"mime_type"`
+	Excerpt    string      `json:"excerpt"`
+	EntryTitle string      `json:"entry_title"`
+	DayYear    int32       `json:"day_year"`
+	DayMonth   int32       `json:"day_month"`
+	DayDay     int32       `json:"day_day"`
+	Distance   interface{} `json:"distance"`
+}
+
+func (q *Queries) SearchSimilarAttachments(ctx context.Context, arg SearchSimilarAttachmentsParams) ([]SearchSimilarAttachmentsRow, error) {
+	rows, err := q.db.Query(ctx, searchSimilarAttachments, arg.UserID, arg.Column2, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchSimilarAttachmentsRow
+	for rows.Next() {
+		var i SearchSimilarAttachmentsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EntryID,
+			&i.Filename,
+			&i.MimeType,
+			&i.Excerpt,
+			&i.EntryTitle,
+			&i.DayYear,
+			&i.DayMonth,
+			&i.DayDay,
+			&i.Distance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchSimilarEntries = `-- name: SearchSimilarEntries :many
 SELECT id, title, body_text, day_year, day_month, day_day, attendees, created_at, updated_at,
        (embedding_vector <-> $2::vector) AS distance
@@ -125,6 +232,23 @@ func (q *Queries) SearchSimilarEntries(ctx context.Context, arg SearchSimilarEnt
 	return items, nil
 }
 
+const updateAttachmentVector = `-- name: UpdateAttachmentVector :exec
+UPDATE attachment_texts
+SET embedding_vector = $2,
+    vectors_updated_at = CURRENT_TIMESTAMP
+WHERE attachment_id = $1
+`
+
+type UpdateAttachmentVectorParams struct {
+	AttachmentID    pgtype.UUID         `json:"attachment_id"`
+	EmbeddingVector *pgvector_go.Vector `json:"embedding_vector"`
+}
+
+func (q *Queries) UpdateAttachmentVector(ctx context.Context, arg UpdateAttachmentVectorParams) error {
+	_, err := q.db.Exec(ctx, updateAttachmentVector, arg.AttachmentID, arg.EmbeddingVector)
+	return err
+}
+
 const updateEntryVector = `-- name: UpdateEntryVector :exec
 UPDATE entries
 SET embedding_vector = $2,
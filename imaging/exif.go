@@ -0,0 +1,218 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	markerSOI  = 0xD8
+	markerAPP0 = 0xE0
+	markerAPP1 = 0xE1
+	markerAPPF = 0xEF
+
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// typeSizes are the byte sizes of TIFF field types, by type number
+var typeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiff is the TIFF structure inside an Exif APP1 segment
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(data []byte) (*tiff, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, false
+	}
+	return t, t.order.Uint16(data[2:]) == 42
+}
+
+// ifdEntry is a 12-byte IFD entry at offset pos of the TIFF data
+type ifdEntry struct {
+	pos   uint32
+	tag   uint16
+	typ   uint16
+	count uint32
+}
+
+// valuePos returns where the entry's value is: inline in the entry if it
+// fits in four bytes, else at the offset it holds
+func (t *tiff) valuePos(e ifdEntry) (uint32, uint32, bool) {
+	size := typeSizes[e.typ] * e.count
+	if size <= 4 {
+		return e.pos + 8, size, true
+	}
+	offset := t.order.Uint32(t.data[e.pos+8:])
+	return offset, size, uint64(offset)+uint64(size) <= uint64(len(t.data))
+}
+
+// entries returns the entries of the IFD at offset
+func (t *tiff) entries(offset uint32) []ifdEntry {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil
+	}
+	count := uint32(t.order.Uint16(t.data[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(t.data)) {
+		return nil
+	}
+	entries := make([]ifdEntry, count)
+	for i := range entries {
+		pos := offset + 2 + uint32(i)*12
+		entries[i] = ifdEntry{
+			pos:   pos,
+			tag:   t.order.Uint16(t.data[pos:]),
+			typ:   t.order.Uint16(t.data[pos+2:]),
+			count: t.order.Uint32(t.data[pos+4:]),
+		}
+	}
+	return entries
+}
+
+func (t *tiff) ifd0() uint32 {
+	return t.order.Uint32(t.data[4:])
+}
+
+// orientation returns the EXIF orientation (1-8), or 1 if there is none
+func (t *tiff) orientation() int {
+	for _, e := range t.entries(t.ifd0()) {
+		if e.tag == tagOrientation && e.typ == 3 {
+			if o := int(t.order.Uint16(t.data[e.pos+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+		}
+	}
+	return 1
+}
+
+// stripGPS zeroes the GPS IFD's entries and values in place and empties it.
+// Sizes and offsets are unchanged, so the rest of the EXIF data stays valid.
+func (t *tiff) stripGPS() {
+	for _, e := range t.entries(t.ifd0()) {
+		if e.tag != tagGPSInfo {
+			continue
+		}
+		gps := t.order.Uint32(t.data[e.pos+8:])
+		entries := t.entries(gps)
+		for _, g := range entries {
+			if pos, size, ok := t.valuePos(g); ok {
+				clear(t.data[pos : pos+size])
+			}
+			clear(t.data[g.pos : g.pos+12])
+		}
+		if entries != nil {
+			t.order.PutUint16(t.data[gps:], 0)
+		}
+	}
+}
+
+// readExif returns the TIFF data of a JPEG's Exif segment, or nil if it has none
+func readExif(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	if err := readSOI(br); err != nil {
+		return nil, err
+	}
+	for {
+		marker, segment, err := readAppSegment(br)
+		if err != nil || segment == nil {
+			return nil, err
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):], nil
+		}
+	}
+}
+
+// StripGPS returns a reader of the JPEG from r with any GPS location removed
+// from its EXIF metadata. Only the segments before the image data are
+// buffered; malformed input passes through unchanged.
+func StripGPS(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	var head bytes.Buffer
+	rest := func() io.Reader {
+		return io.MultiReader(bytes.NewReader(head.Bytes()), br)
+	}
+
+	if err := readSOI(io.TeeReader(br, &head)); err != nil {
+		return rest()
+	}
+	for {
+		if b, err := br.Peek(2); err != nil || b[0] != 0xFF || b[1] < markerAPP0 || b[1] > markerAPPF {
+			return rest()
+		}
+		marker, segment, err := readAppSegment(br)
+		if err != nil {
+			// The segment's bytes were consumed; there is no image to salvage
+			return io.MultiReader(bytes.NewReader(head.Bytes()), errorReader{err})
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(segment, exifHeader) {
+			if t, ok := parseTIFF(segment[len(exifHeader):]); ok {
+				t.stripGPS()
+			}
+		}
+		head.Write([]byte{0xFF, marker})
+		binary.Write(&head, binary.BigEndian, uint16(len(segment)+2))
+		head.Write(segment)
+	}
+}
+
+func readSOI(r io.Reader) error {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return err
+	}
+	if soi[0] != 0xFF || soi[1] != markerSOI {
+		return errors.New("not a JPEG")
+	}
+	return nil
+}
+
+// readAppSegment reads the next APPn segment, returning a nil segment when
+// the next marker is not an APPn
+func readAppSegment(br *bufio.Reader) (byte, []byte, error) {
+	b, err := br.Peek(4)
+	if err != nil {
+		return 0, nil, err
+	}
+	if b[0] != 0xFF || b[1] < markerAPP0 || b[1] > markerAPPF {
+		return 0, nil, nil
+	}
+	marker := b[1]
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	if length < 2 {
+		return 0, nil, errors.New("invalid JPEG segment length")
+	}
+	br.Discard(4)
+	segment := make([]byte, length-2)
+	if _, err := io.ReadFull(br, segment); err != nil {
+		return 0, nil, err
+	}
+	return marker, segment, nil
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,193 @@
+// Package imaging makes thumbnails of JPEG, PNG and GIF attachments using
+// only the standard library, and handles the EXIF metadata phones attach to
+// photos: orientation, and GPS location for privacy.
+package imaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// MaxPixels bounds the images that are decoded, so a small file declaring
+// huge dimensions can't exhaust memory
+const MaxPixels = 50_000_000
+
+// decoding bounds the pixels of all the images decoded at once. An image
+// takes around 10 bytes a pixel while it is decoded, converted and turned
+// upright, so one of the largest images is decoded at a time, or several
+// smaller ones.
+var decoding = semaphore.NewWeighted(MaxPixels)
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image too large")
+
+// Supported reports whether thumbnails can be made for a MIME type
+func Supported(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Decode decodes an image, turning it upright according to its EXIF
+// orientation. GIFs decode to their first frame. It waits while too many
+// other pixels are being decoded; release must be called once the image is
+// no longer used, to let others proceed.
+func Decode(ctx context.Context, r io.ReadSeeker) (img *image.RGBA, release func(), err error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if pixels > MaxPixels {
+		return nil, nil, ErrTooLarge
+	}
+
+	if err := decoding.Acquire(ctx, pixels); err != nil {
+		return nil, nil, err
+	}
+	release = func() { decoding.Release(pixels) }
+	img, err = decode(r, format)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return img, release, nil
+}
+
+// decode decodes an image of the given format into an upright RGBA image
+func decode(r io.ReadSeeker, format string) (*image.RGBA, error) {
+	orientation := 1
+	if format == "jpeg" {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if data, err := readExif(r); err == nil && data != nil {
+			if t, ok := parseTIFF(data); ok {
+				orientation = t.orientation()
+			}
+		}
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var img image.Image
+	var err error
+	switch format {
+	case "jpeg":
+		img, err = jpeg.Decode(r)
+	case "png":
+		img, err = png.Decode(r)
+	case "gif":
+		img, err = gif.Decode(r)
+	default:
+		return nil, fmt.Errorf("unsupported image format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return orient(rgba, orientation), nil
+}
+
+// orient applies an EXIF orientation, returning an upright image
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation == 1 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	// source maps a pixel of the upright image to the stored pixel
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2: // mirrored
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // rotated 180°
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // mirrored vertically
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // transposed
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // needs rotating 90° clockwise
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // transversed
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // needs rotating 90° counter-clockwise
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	default:
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// Resize scales src down to the given width, keeping its aspect ratio, by
+// averaging the source pixels under each output pixel. Images already no
+// wider than width are returned unchanged.
+func Resize(src *image.RGBA, width int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if width <= 0 || width >= sw {
+		return src
+	}
+	height := max(1, sh*width/sw)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := max(y0+1, (y+1)*sh/height)
+		for x := 0; x < width; x++ {
+			x0 := x * sw / width
+			x1 := max(x0+1, (x+1)*sw/width)
+
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += uint64(row[i])
+					sum[1] += uint64(row[i+1])
+					sum[2] += uint64(row[i+2])
+					sum[3] += uint64(row[i+3])
+				}
+			}
+			n := uint64((x1 - x0) * (y1 - y0))
+			offset := dst.PixOffset(x, y)
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// Encode writes a thumbnail as JPEG, or as PNG if it has transparency, and
+// returns its MIME type
+func Encode(w io.Writer, img *image.RGBA) (string, error) {
+	if !img.Opaque() {
+		return "image/png", png.Encode(w, img)
+	}
+	return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
@@ -0,0 +1,165 @@
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Delta returns the document as a Quill delta
+func (d *Document) Delta() []byte {
+	type deltaOp struct {
+		Insert     interface{}            `json:"insert"`
+		Attributes map[string]interface{} `json:"attributes,omitempty"`
+	}
+	var ops []deltaOp
+	insert := func(text string, attrs map[string]interface{}) {
+		// Runs of text with the same formats are one op, as Quill keeps them
+		if n := len(ops); n > 0 && sameAttrs(ops[n-1].Attributes, attrs) {
+			if prev, ok := ops[n-1].Insert.(string); ok {
+				ops[n-1].Insert = prev + text
+				return
+			}
+		}
+		ops = append(ops, deltaOp{Insert: text, Attributes: attrs})
+	}
+
+	for _, l := range d.lines {
+		for _, s := range l.segments {
+			if s.image != "" {
+				ops = append(ops, deltaOp{Insert: map[string]string{"image": s.image}})
+				continue
+			}
+			insert(s.text, s.attrs)
+		}
+		insert("\n", l.attrs)
+	}
+	if len(ops) == 0 {
+		ops = append(ops, deltaOp{Insert: "\n"})
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"ops": ops})
+	return data
+}
+
+// HTML returns the document as HTML shaped like the editor's, with lists as
+// <ol> items marked with data-list and tables as quill-better-table's
+func (d *Document) HTML() string {
+	var b strings.Builder
+	for i := 0; i < len(d.lines); {
+		l := d.lines[i]
+		switch {
+		case l.attrs["table-col"] != nil || l.attrs["table-cell-line"] != nil:
+			i = d.tableHTML(&b, i)
+		case l.attrs["code-block"] != nil:
+			b.WriteString("<pre>")
+			for first := true; i < len(d.lines) && d.lines[i].attrs["code-block"] != nil; i++ {
+				if !first {
+					b.WriteString("\n")
+				}
+				first = false
+				for _, s := range d.lines[i].segments {
+					b.WriteString(html.EscapeString(s.text))
+				}
+			}
+			b.WriteString("</pre>")
+		case l.attrs["list"] != nil:
+			b.WriteString("<ol>")
+			for ; i < len(d.lines) && d.lines[i].attrs["list"] != nil; i++ {
+				item := d.lines[i]
+				fmt.Fprintf(&b, `<li data-list="%s"`, html.EscapeString(fmt.Sprint(item.attrs["list"])))
+				if indent := boundedAttr(item.attrs["indent"], maxIndent); indent > 0 {
+					fmt.Fprintf(&b, ` class="ql-indent-%d"`, indent)
+				}
+				b.WriteString(">" + inlineHTML(item.segments) + "</li>")
+			}
+			b.WriteString("</ol>")
+		default:
+			tag := "p"
+			if level := boundedAttr(l.attrs["header"], maxHeader); level > 0 {
+				tag = fmt.Sprintf("h%d", level)
+			} else if l.attrs["blockquote"] != nil {
+				tag = "blockquote"
+			}
+			content := inlineHTML(l.segments)
+			if content == "" {
+				content = "<br>"
+			}
+			b.WriteString("<" + tag + ">" + content + "</" + tag + ">")
+			i++
+		}
+	}
+	return b.String()
+}
+
+// tableHTML writes the table starting at lines[i] and returns the index of
+// the line after it
+func (d *Document) tableHTML(b *strings.Builder, i int) int {
+	b.WriteString(`<table class="quill-better-table"><tbody>`)
+	row, cell := "", ""
+	for ; i < len(d.lines); i++ {
+		l := d.lines[i]
+		if l.attrs["table-col"] != nil {
+			continue
+		}
+		attrs, ok := l.attrs["table-cell-line"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		rowID, _ := attrs["row"].(string)
+		cellID, _ := attrs["cell"].(string)
+		if rowID != row {
+			if row != "" {
+				b.WriteString("</td></tr>")
+			}
+			fmt.Fprintf(b, `<tr data-row="%s"><td rowspan="1" colspan="1">`, html.EscapeString(rowID))
+		} else if cellID != cell {
+			b.WriteString(`</td><td rowspan="1" colspan="1">`)
+		}
+		row, cell = rowID, cellID
+
+		content := inlineHTML(l.segments)
+		if content == "" {
+			content = "<br>"
+		}
+		b.WriteString("<p>" + content + "</p>")
+	}
+	if row != "" {
+		b.WriteString("</td></tr>")
+	}
+	b.WriteString("</tbody></table>")
+	return i
+}
+
+// inlineHTML renders runs of text with their formats, and images
+func inlineHTML(segments []segment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		if s.image != "" {
+			fmt.Fprintf(&b, `<img src="%s">`, html.EscapeString(s.image))
+			continue
+		}
+		text := html.EscapeString(s.text)
+		if s.attrs["code"] == true {
+			text = "<code>" + text + "</code>"
+		}
+		if s.attrs["underline"] == true {
+			text = "<u>" + text + "</u>"
+		}
+		if s.attrs["strike"] == true {
+			text = "<s>" + text + "</s>"
+		}
+		if s.attrs["italic"] == true {
+			text = "<em>" + text + "</em>"
+		}
+		if s.attrs["bold"] == true {
+			text = "<strong>" + text + "</strong>"
+		}
+		if link, ok := s.attrs["link"].(string); ok && link != "" {
+			text = fmt.Sprintf(`<a href="%s" rel="noopener noreferrer" target="_blank">%s</a>`, html.EscapeString(link), text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
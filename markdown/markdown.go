@@ -0,0 +1,391 @@
+// Package markdown converts entry bodies between the Quill deltas the editor
+// produces and Markdown, for exports that people read in git or Obsidian and
+// for importing notes kept elsewhere. Tables from quill-better-table map to
+// GitHub-flavoured Markdown tables.
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// op is one Quill delta operation. Insert is a string, or an object for an
+// embed such as {"image": url}.
+type op struct {
+	Insert     json.RawMessage        `json:"insert"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// segment is a run of text, or an image, with its inline formats
+type segment struct {
+	text  string
+	image string
+	attrs map[string]interface{}
+}
+
+// line is a line of the document with its block formats, which Quill keeps
+// on the line's closing newline
+type line struct {
+	segments []segment
+	attrs    map[string]interface{}
+}
+
+// FromDelta renders a Quill delta as Markdown. rewrite maps the URL of each
+// link and image, for example to point attachments at files next to the
+// Markdown; nil leaves URLs unchanged. An invalid delta renders as nothing.
+func FromDelta(delta []byte, rewrite func(url string) string) string {
+	var doc struct {
+		Ops []op `json:"ops"`
+	}
+	if err := json.Unmarshal(delta, &doc); err != nil {
+		return ""
+	}
+	if rewrite == nil {
+		rewrite = func(url string) string { return url }
+	}
+
+	r := &renderer{rewrite: rewrite}
+	lines := splitLines(doc.Ops)
+	for i := 0; i < len(lines); {
+		i = r.block(lines, i)
+	}
+	return strings.TrimSpace(r.out.String()) + "\n"
+}
+
+// splitLines breaks the ops into lines, splitting inserts at newlines
+func splitLines(ops []op) []line {
+	var lines []line
+	var current line
+	for _, o := range ops {
+		var text string
+		if err := json.Unmarshal(o.Insert, &text); err != nil {
+			var embed struct {
+				Image string `json:"image"`
+			}
+			if json.Unmarshal(o.Insert, &embed) == nil && embed.Image != "" {
+				current.segments = append(current.segments, segment{image: embed.Image, attrs: o.Attributes})
+			}
+			continue
+		}
+
+		parts := strings.Split(text, "\n")
+		for i, part := range parts {
+			if part != "" {
+				current.segments = append(current.segments, segment{text: part, attrs: o.Attributes})
+			}
+			if i < len(parts)-1 {
+				current.attrs = o.Attributes
+				lines = append(lines, current)
+				current = line{}
+			}
+		}
+	}
+	if len(current.segments) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+type renderer struct {
+	out     strings.Builder
+	rewrite func(url string) string
+	last    string // kind of the last block written, to separate blocks
+}
+
+// block writes the block starting at lines[i] and returns the index of the
+// line after it
+func (r *renderer) block(lines []line, i int) int {
+	l := lines[i]
+	switch {
+	case l.attrs["table-col"] != nil:
+		return i + 1
+	case l.attrs["table-cell-line"] != nil:
+		return r.table(lines, i)
+	case l.attrs["code-block"] != nil:
+		r.start("code")
+		r.out.WriteString("```\n")
+		for ; i < len(lines) && lines[i].attrs["code-block"] != nil; i++ {
+			for _, s := range lines[i].segments {
+				r.out.WriteString(s.text)
+			}
+			r.out.WriteString("\n")
+		}
+		r.out.WriteString("```\n")
+		return i
+	case l.attrs["list"] != nil:
+		r.start("list")
+		counters := map[int]int{}
+		for ; i < len(lines) && lines[i].attrs["list"] != nil; i++ {
+			indent := boundedAttr(lines[i].attrs["indent"], maxIndent)
+			for level := range counters {
+				if level > indent {
+					delete(counters, level)
+				}
+			}
+			marker := "- "
+			switch lines[i].attrs["list"] {
+			case "ordered":
+				counters[indent]++
+				marker = fmt.Sprintf("%d. ", counters[indent])
+			case "checked":
+				marker = "- [x] "
+			case "unchecked":
+				marker = "- [ ] "
+			}
+			r.out.WriteString(strings.Repeat("    ", indent) + marker + r.inline(lines[i].segments) + "\n")
+		}
+		return i
+	}
+
+	text := r.inline(l.segments)
+	if strings.TrimSpace(text) == "" {
+		return i + 1
+	}
+	if level := boundedAttr(l.attrs["header"], maxHeader); level > 0 {
+		r.start("header")
+		r.out.WriteString(strings.Repeat("#", level) + " " + text + "\n")
+		return i + 1
+	}
+	if l.attrs["blockquote"] != nil {
+		r.start("quote")
+		r.out.WriteString("> " + text + "\n")
+		return i + 1
+	}
+	r.start("paragraph")
+	r.out.WriteString(escapeLineStart(text) + "\n")
+	return i + 1
+}
+
+// start separates a new block from the one before with a blank line.
+// Consecutive quote lines stay together as one quote.
+func (r *renderer) start(kind string) {
+	if r.out.Len() > 0 && !(kind == "quote" && r.last == "quote") {
+		r.out.WriteString("\n")
+	}
+	r.last = kind
+}
+
+// table writes consecutive table cell lines as a table. The first row is the
+// header, as Markdown tables need one; lines within a cell are joined with
+// <br>.
+func (r *renderer) table(lines []line, i int) int {
+	type cell struct {
+		id    string
+		lines []string
+	}
+	type row struct {
+		id    string
+		cells []*cell
+	}
+	var rows []*row
+	for ; i < len(lines); i++ {
+		attrs, ok := lines[i].attrs["table-cell-line"].(map[string]interface{})
+		if !ok {
+			if lines[i].attrs["table-col"] != nil {
+				continue
+			}
+			break
+		}
+		rowID, _ := attrs["row"].(string)
+		cellID, _ := attrs["cell"].(string)
+		if len(rows) == 0 || rows[len(rows)-1].id != rowID {
+			rows = append(rows, &row{id: rowID})
+		}
+		current := rows[len(rows)-1]
+		if len(current.cells) == 0 || current.cells[len(current.cells)-1].id != cellID {
+			current.cells = append(current.cells, &cell{id: cellID})
+		}
+		c := current.cells[len(current.cells)-1]
+		if text := r.inline(lines[i].segments); text != "" {
+			c.lines = append(c.lines, text)
+		}
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row.cells))
+	}
+	if columns == 0 {
+		return i
+	}
+
+	r.start("table")
+	for n, row := range rows {
+		cells := make([]string, columns)
+		for j, c := range row.cells {
+			cells[j] = strings.ReplaceAll(strings.Join(c.lines, "<br>"), "|", `\|`)
+		}
+		r.out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if n == 0 {
+			r.out.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+		}
+	}
+	return i
+}
+
+// inline renders a line's text with its bold, italic, strikethrough,
+// underline, code and link formats, and its images
+func (r *renderer) inline(segments []segment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		if s.image != "" {
+			fmt.Fprintf(&b, "![](%s)", EscapeURL(r.rewrite(s.image)))
+			continue
+		}
+
+		if s.attrs["code"] == true {
+			b.WriteString(codeSpan(s.text))
+			continue
+		}
+
+		// Markers must hug the text, so surrounding spaces go outside them
+		text := Escape(s.text)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			b.WriteString(text)
+			continue
+		}
+		lead := text[:strings.Index(text, trimmed)]
+		trail := text[len(lead)+len(trimmed):]
+
+		formatted := trimmed
+		if s.attrs["underline"] == true {
+			formatted = "<u>" + formatted + "</u>"
+		}
+		if s.attrs["strike"] == true {
+			formatted = "~~" + formatted + "~~"
+		}
+		if s.attrs["italic"] == true {
+			formatted = "*" + formatted + "*"
+		}
+		if s.attrs["bold"] == true {
+			formatted = "**" + formatted + "**"
+		}
+		if link, ok := s.attrs["link"].(string); ok && link != "" {
+			formatted = "[" + formatted + "](" + EscapeURL(r.rewrite(link)) + ")"
+		}
+		b.WriteString(lead + formatted + trail)
+	}
+	return b.String()
+}
+
+// escaper backslash-escapes the characters that would otherwise start
+// emphasis, code or HTML. Brackets are left alone so [[Title]] links keep
+// working in Obsidian.
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"~", `\~`,
+	"<", `\<`,
+)
+
+// Escape makes text safe to include in Markdown as it is
+func Escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// Unescape removes the backslashes Escape added
+func Unescape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\\*_`~<", text[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// escapeLineStart keeps a paragraph from being read as a heading, quote,
+// list item or rule
+func escapeLineStart(text string) string {
+	trimmed := strings.TrimLeft(text, " ")
+	switch {
+	case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, ">"),
+		strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "+ "),
+		trimmed == "-", trimmed == "+":
+		return `\` + trimmed
+	}
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	if digits > 0 && digits < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') {
+		return trimmed[:digits] + `\` + trimmed[digits:]
+	}
+	return trimmed
+}
+
+// EscapeURL keeps a link's URL from ending the link early
+func EscapeURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
+
+// codeSpan wraps text in enough backticks that none inside end it
+func codeSpan(text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+// Field is one key of front matter
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// FrontMatter renders YAML front matter with the fields in order. Values are
+// written as JSON, which YAML reads, so strings are safely quoted and lists
+// become flow sequences.
+func FrontMatter(fields ...Field) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, field := range fields {
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", field.Name, value)
+	}
+	b.WriteString("---\n")
+	return b.String()
+}
+
+// The deepest list indent and header level the editor produces. Deltas are
+// stored as sent, so attributes are clamped to these before use.
+const (
+	maxIndent = 8
+	maxHeader = 6
+)
+
+// boundedAttr reads a numeric attribute, clamped to 0..limit
+func boundedAttr(value interface{}, limit int) int {
+	return max(0, min(intAttr(value), limit))
+}
+
+// intAttr reads a numeric attribute such as header or indent, which JSON
+// decodes as a float64
+func intAttr(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		var n int
+		fmt.Sscanf(v, "%d", &n)
+		return n
+	}
+	return 0
+}
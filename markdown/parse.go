@@ -0,0 +1,652 @@
+package markdown
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextPattern  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	rulePattern    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	quotePattern   = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	listPattern    = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	taskPattern    = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	delimPattern   = regexp.MustCompile(`^ *\|? *:?-+:? *(?:\| *:?-+:? *)*\|? *$`)
+	brPattern      = regexp.MustCompile(`(?i)<br\s*/?>`)
+	autolinkRegexp = regexp.MustCompile(`^<((?:https?|mailto):[^<>\s]+)>`)
+	imageNameRegex = regexp.MustCompile(`(?i)\.(png|jpe?g|gif|webp|svg|bmp|heic)$`)
+)
+
+// Document is Markdown parsed into the lines of a Quill delta
+type Document struct {
+	lines []line
+}
+
+// Parse reads Markdown into a document. rewrite maps the URL of each link
+// and image, for example to point files next to the Markdown at stored
+// attachments; nil leaves URLs unchanged. Headings deeper than the editor's
+// three levels become level 3, rules are dropped and HTML is kept as text.
+// [[Title]] links are kept as they are, and Obsidian's ![[file]] embeds
+// become images or links.
+func Parse(src string, rewrite func(url string) string) *Document {
+	if rewrite == nil {
+		rewrite = func(url string) string { return url }
+	}
+	p := &parser{rewrite: rewrite}
+
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\r", "\n")
+	in := strings.Split(src, "\n")
+	for i := range in {
+		in[i] = expandTabs(in[i])
+	}
+	for i := 0; i < len(in); {
+		i = p.block(in, i)
+	}
+	return &Document{lines: p.lines}
+}
+
+type parser struct {
+	rewrite func(url string) string
+	lines   []line
+	kind    string // kind of the last block, to keep lists together
+	blank   bool   // whether a blank line came after it
+	indents []int  // leading spaces of each level of the current list
+	tables  int    // tables so far, to give rows and cells unique IDs
+}
+
+// block parses the block starting at in[i] and returns the index of the
+// line after it
+func (p *parser) block(in []string, i int) int {
+	text := in[i]
+	trimmed := strings.TrimSpace(text)
+
+	if trimmed == "" {
+		p.blank = true
+		return i + 1
+	}
+	if m := fencePattern.FindStringSubmatch(text); m != nil {
+		return p.code(in, i, m[1])
+	}
+	if m := headingPattern.FindStringSubmatch(text); m != nil {
+		p.add("header", line{segments: p.inline(m[2], nil), attrs: map[string]interface{}{"header": min(len(m[1]), 3)}})
+		return i + 1
+	}
+	if rulePattern.MatchString(text) {
+		p.start("rule")
+		return i + 1
+	}
+	if m := quotePattern.FindStringSubmatch(text); m != nil {
+		if strings.TrimSpace(m[1]) != "" {
+			p.add("quote", line{segments: p.inline(strings.TrimSpace(m[1]), nil), attrs: map[string]interface{}{"blockquote": true}})
+		}
+		return i + 1
+	}
+	if m := listPattern.FindStringSubmatch(text); m != nil {
+		return p.listItem(in, i, m)
+	}
+	if i+1 < len(in) && strings.Contains(text, "|") && strings.Contains(in[i+1], "|") && delimPattern.MatchString(in[i+1]) {
+		return p.table(in, i)
+	}
+	if i+1 < len(in) && setextPattern.MatchString(in[i+1]) && !p.startsBlock(in, i) {
+		level := 2
+		if strings.HasPrefix(strings.TrimSpace(in[i+1]), "=") {
+			level = 1
+		}
+		p.add("header", line{segments: p.inline(trimmed, nil), attrs: map[string]interface{}{"header": level}})
+		return i + 2
+	}
+	return p.paragraph(in, i)
+}
+
+// startsBlock reports whether in[i] starts a block other than a paragraph
+func (p *parser) startsBlock(in []string, i int) bool {
+	text := in[i]
+	return fencePattern.MatchString(text) || headingPattern.MatchString(text) || rulePattern.MatchString(text) ||
+		quotePattern.MatchString(text) || listPattern.MatchString(text) ||
+		(i+1 < len(in) && strings.Contains(text, "|") && strings.Contains(in[i+1], "|") && delimPattern.MatchString(in[i+1]))
+}
+
+// start begins a block, separating it from the one before with an empty
+// line if the Markdown had a blank line between them. Items of one list stay
+// together even when they are spaced out.
+func (p *parser) start(kind string) {
+	if kind != "list" {
+		p.indents = nil
+	}
+	if p.blank && len(p.lines) > 0 && !(kind == "list" && p.kind == "list") && p.kind != "rule" {
+		p.lines = append(p.lines, line{})
+	}
+	if kind == "rule" && len(p.lines) > 0 {
+		p.lines = append(p.lines, line{})
+	}
+	p.kind = kind
+	p.blank = false
+}
+
+func (p *parser) add(kind string, l line) {
+	p.start(kind)
+	p.lines = append(p.lines, l)
+}
+
+// code parses a fenced code block, which is kept as it is
+func (p *parser) code(in []string, i int, fence string) int {
+	p.start("code")
+	for i++; i < len(in); i++ {
+		closing := strings.TrimSpace(in[i])
+		if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+			i++
+			break
+		}
+		l := line{attrs: map[string]interface{}{"code-block": "plain"}}
+		if in[i] != "" {
+			l.segments = []segment{{text: in[i]}}
+		}
+		p.lines = append(p.lines, l)
+	}
+	return i
+}
+
+// listItem parses a list item and its continuation lines. Nesting follows
+// the items' indentation.
+func (p *parser) listItem(in []string, i int, m []string) int {
+	p.start("list")
+
+	spaces := len(m[1])
+	for len(p.indents) > 0 && spaces < p.indents[len(p.indents)-1] {
+		p.indents = p.indents[:len(p.indents)-1]
+	}
+	if len(p.indents) == 0 || spaces > p.indents[len(p.indents)-1] {
+		p.indents = append(p.indents, spaces)
+	}
+
+	list := "bullet"
+	if m[2][0] >= '0' && m[2][0] <= '9' {
+		list = "ordered"
+	}
+	text := m[3]
+	if t := taskPattern.FindStringSubmatch(text); t != nil {
+		list = "unchecked"
+		if t[1] != " " {
+			list = "checked"
+		}
+		text = text[len(t[0]):]
+	}
+
+	// Lines up to the next blank line or block continue the item
+	for i++; i < len(in) && strings.TrimSpace(in[i]) != "" && !p.startsBlock(in, i); i++ {
+		text += " " + strings.TrimSpace(in[i])
+	}
+
+	attrs := map[string]interface{}{"list": list}
+	if level := len(p.indents) - 1; level > 0 {
+		attrs["indent"] = level
+	}
+	p.lines = append(p.lines, line{segments: p.inline(strings.TrimSpace(text), nil), attrs: attrs})
+	return i
+}
+
+// table parses a GitHub-flavoured table into quill-better-table's lines: a
+// table-col line per column, then a line per cell with the cell's row and
+// ID. <br> in a cell starts another line in it.
+func (p *parser) table(in []string, i int) int {
+	p.start("table")
+	p.tables++
+
+	header := splitRow(in[i])
+	columns := len(header)
+	for range columns {
+		p.lines = append(p.lines, line{attrs: map[string]interface{}{"table-col": map[string]interface{}{"width": "150"}}})
+	}
+
+	rows := [][]string{header}
+	for i += 2; i < len(in) && strings.TrimSpace(in[i]) != "" && strings.Contains(in[i], "|"); i++ {
+		rows = append(rows, splitRow(in[i]))
+	}
+
+	for r, cells := range rows {
+		rowID := fmt.Sprintf("row-md%d-%d", p.tables, r+1)
+		for c := range columns {
+			cellAttrs := map[string]interface{}{
+				"table-cell-line": map[string]interface{}{
+					"rowspan": "1",
+					"colspan": "1",
+					"row":     rowID,
+					"cell":    fmt.Sprintf("cell-md%d-%d-%d", p.tables, r+1, c+1),
+				},
+			}
+			content := ""
+			if c < len(cells) {
+				content = cells[c]
+			}
+			for _, part := range brPattern.Split(content, -1) {
+				p.lines = append(p.lines, line{segments: p.inline(strings.TrimSpace(part), nil), attrs: cellAttrs})
+			}
+		}
+	}
+	return i
+}
+
+// splitRow splits a table row into its cells at pipes that aren't escaped
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// paragraph parses lines up to the next blank line or block. Its lines are
+// joined, except at hard breaks and <br>, which start another line.
+func (p *parser) paragraph(in []string, i int) int {
+	p.start("paragraph")
+
+	var current []string
+	var texts []string
+	for first := true; i < len(in) && strings.TrimSpace(in[i]) != "" && (first || !p.startsBlock(in, i)); i++ {
+		first = false
+		text := strings.TrimLeft(in[i], " ")
+		hard := strings.HasSuffix(text, "  ")
+		text = strings.TrimRight(text, " ")
+		if strings.HasSuffix(text, `\`) && !strings.HasSuffix(text, `\\`) {
+			hard = true
+			text = strings.TrimSuffix(text, `\`)
+		}
+		current = append(current, text)
+		if hard {
+			texts = append(texts, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		texts = append(texts, strings.Join(current, " "))
+	}
+
+	for _, text := range texts {
+		for _, part := range brPattern.Split(text, -1) {
+			if part = strings.TrimSpace(part); part != "" {
+				p.lines = append(p.lines, line{segments: p.inline(part, nil)})
+			}
+		}
+	}
+	return i
+}
+
+// inline parses a line's text into runs of text with their formats, and
+// images
+func (p *parser) inline(s string, attrs map[string]interface{}) []segment {
+	var out []segment
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			out = appendSegment(out, segment{text: text.String(), attrs: attrs})
+			text.Reset()
+		}
+	}
+	emit := func(segments ...segment) {
+		flush()
+		for _, s := range segments {
+			out = appendSegment(out, s)
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(s, i)
+			if end := closingRun(s, i+n, n); end >= 0 {
+				code := s[i+n : end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				emit(segment{text: code, attrs: with(attrs, "code", true)})
+				i = end + n
+				continue
+			}
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case strings.HasPrefix(rest, "![["):
+			if end := strings.Index(rest[3:], "]]"); end > 0 {
+				target, _, _ := strings.Cut(rest[3:3+end], "|")
+				target = strings.TrimSpace(target)
+				if imageNameRegex.MatchString(target) {
+					emit(segment{image: p.rewrite(target)})
+				} else {
+					emit(segment{text: target, attrs: with(attrs, "link", p.rewrite(target))})
+				}
+				i += 3 + end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "[["):
+			if end := strings.Index(rest[2:], "]]"); end > 0 {
+				text.WriteString(rest[:2+end+2])
+				i += 2 + end + 2
+				continue
+			}
+
+		case c == '!' && strings.HasPrefix(rest, "!["):
+			if _, url, n, ok := parseLink(rest[1:]); ok {
+				emit(segment{image: p.rewrite(url)})
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if label, url, n, ok := parseLink(rest); ok {
+				emit(p.inline(label, with(attrs, "link", p.rewrite(url)))...)
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if m := autolinkRegexp.FindStringSubmatch(rest); m != nil {
+				emit(segment{text: m[1], attrs: with(attrs, "link", p.rewrite(m[1]))})
+				i += len(m[0])
+				continue
+			}
+			if strings.HasPrefix(strings.ToLower(rest), "<u>") {
+				if end := strings.Index(strings.ToLower(rest), "</u>"); end > 3 {
+					emit(p.inline(rest[3:end], with(attrs, "underline", true))...)
+					i += end + 4
+					continue
+				}
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if segments, n, ok := p.emphasis(s, i, attrs); ok {
+				emit(segments...)
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return out
+}
+
+// emphasis parses bold, italic or strikethrough text starting at s[i],
+// returning its runs and length
+func (p *parser) emphasis(s string, i int, attrs map[string]interface{}) ([]segment, int, bool) {
+	c := s[i]
+	n := runLength(s, i)
+	if c == '~' && n != 2 {
+		return nil, 0, false
+	}
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return nil, 0, false
+	}
+	// Underscores inside words, as in snake_case, aren't emphasis
+	if c == '_' && i > 0 && isWordChar(s[i-1]) {
+		return nil, 0, false
+	}
+
+	for k := min(n, 3); k >= 1; k-- {
+		end := closingDelimiter(s, i+k, c, k)
+		if end < 0 {
+			continue
+		}
+		var inner map[string]interface{}
+		switch {
+		case c == '~':
+			inner = with(attrs, "strike", true)
+		case k == 3:
+			inner = with(with(attrs, "bold", true), "italic", true)
+		case k == 2:
+			inner = with(attrs, "bold", true)
+		default:
+			inner = with(attrs, "italic", true)
+		}
+		return p.inline(s[i+k:end], inner), end + k - i, true
+	}
+	return nil, 0, false
+}
+
+// closingDelimiter finds the run of exactly k c's that closes emphasis
+// opened before from, or -1
+func closingDelimiter(s string, from int, c byte, k int) int {
+	for j := from + 1; j+k <= len(s); j++ {
+		if s[j-1] == '\\' {
+			continue
+		}
+		if s[j] == '`' {
+			if end := closingRun(s, j+runLength(s, j), runLength(s, j)); end >= 0 {
+				j = end + runLength(s, end) - 1
+			}
+			continue
+		}
+		if s[j] != c || runLength(s, j) != k || s[j-1] == ' ' || s[j-1] == c {
+			continue
+		}
+		if c == '_' && j+k < len(s) && isWordChar(s[j+k]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// parseLink parses [text](url "title") at the start of s, returning the
+// text, the URL and the length of the link
+func parseLink(s string) (string, string, int, bool) {
+	depth := 0
+	closeText := -1
+	for i := 0; i < len(s) && closeText < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = i
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+
+	start := closeText + 2
+	depth = 1
+	end := -1
+	for i := start; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return "", "", 0, false
+	}
+
+	dest := strings.TrimSpace(s[start:end])
+	if strings.HasPrefix(dest, "<") {
+		if close := strings.Index(dest, ">"); close > 0 {
+			dest = dest[1:close]
+		}
+	} else if space := strings.IndexAny(dest, " \t"); space >= 0 {
+		// A space starts the link's title; without one, as many notes are
+		// written, it is part of the file name
+		if title := strings.TrimSpace(dest[space:]); strings.ContainsAny(title[:1], `"'(`) {
+			dest = dest[:space]
+		}
+	}
+	return s[1:closeText], dest, end + 1, true
+}
+
+// appendSegment adds a run, merging it into the one before if they have the
+// same formats
+func appendSegment(segments []segment, s segment) []segment {
+	if n := len(segments); n > 0 && s.image == "" && segments[n-1].image == "" && sameAttrs(segments[n-1].attrs, s.attrs) {
+		segments[n-1].text += s.text
+		return segments
+	}
+	return append(segments, s)
+}
+
+func sameAttrs(a, b map[string]interface{}) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// with copies attrs with another format added
+func with(attrs map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(attrs)+1)
+	for k, v := range attrs {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// runLength counts the repeats of s[i] starting at i
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// closingRun finds the next run of exactly n of s[from-1]'s backticks at or
+// after from, or -1
+func closingRun(s string, from, n int) int {
+	for j := from; j < len(s); j++ {
+		if s[j] != '`' {
+			continue
+		}
+		run := runLength(s, j)
+		if run == n {
+			return j
+		}
+		j += run - 1
+	}
+	return -1
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// expandTabs turns the tabs indenting a line into four spaces each
+func expandTabs(s string) string {
+	n := 0
+	for n < len(s) && (s[n] == '\t' || s[n] == ' ') {
+		n++
+	}
+	return strings.ReplaceAll(s[:n], "\t", "    ") + s[n:]
+}
+
+// Fields is YAML front matter
+type Fields map[string]interface{}
+
+// SplitFrontMatter separates YAML front matter from the Markdown after it.
+// Without front matter the fields are nil.
+func SplitFrontMatter(src string) (Fields, string, error) {
+	src = strings.TrimPrefix(src, "\ufeff")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	if !strings.HasPrefix(src, "---\n") {
+		return nil, src, nil
+	}
+
+	lines := strings.SplitAfter(src[len("---\n"):], "\n")
+	for i, l := range lines {
+		if end := strings.TrimRight(l, " \n"); end == "---" || end == "..." {
+			fields := Fields{}
+			if err := yaml.Unmarshal([]byte(strings.Join(lines[:i], "")), &fields); err != nil {
+				return nil, src, fmt.Errorf("invalid front matter: %w", err)
+			}
+			return fields, strings.Join(lines[i+1:], ""), nil
+		}
+	}
+	return nil, src, nil
+}
+
+// String reads a field as text. Dates YAML reads as timestamps come back
+// as YYYY-MM-DD, or RFC 3339 if they have a time.
+func (f Fields) String(key string) string {
+	switch v := f[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		if v.Equal(v.Truncate(24*time.Hour)) && v.Location() == time.UTC {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	case nil:
+		return ""
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
+// List reads a field as a list, splitting text at commas
+func (f Fields) List(key string) []string {
+	var items []string
+	switch v := f[key].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s := strings.TrimSpace(fmt.Sprint(item)); item != nil && s != "" {
+				items = append(items, s)
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if s := strings.TrimSpace(item); s != "" {
+				items = append(items, s)
+			}
+		}
+	}
+	return items
+}
+
+// Bool reads a field as true or false
+func (f Fields) Bool(key string) bool {
+	v, _ := f[key].(bool)
+	return v
+}
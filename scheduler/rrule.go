@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a recurrence rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// maxLookahead bounds how far Next searches for an occurrence
+const maxLookahead = 5 * 366
+
+// Rule is the supported subset of an RFC 5545 RRULE:
+// FREQ=DAILY|WEEKLY|MONTHLY with optional INTERVAL, BYDAY (weekdays only,
+// e.g. MO,WE,FR), BYMONTHDAY (1..31 or -1 for the last day), COUNT and UNTIL.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+	Count      int
+	Until      time.Time // zero if unbounded
+}
+
+// ParseRule parses an RRULE string such as "FREQ=WEEKLY;BYDAY=MO,WE,FR".
+// A leading "RRULE:" prefix is accepted.
+func ParseRule(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, fmt.Errorf("rrule is required")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("invalid rrule part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly:
+				rule.Freq = Frequency(value)
+			default:
+				return rule, fmt.Errorf("unsupported FREQ %q (use DAILY, WEEKLY or MONTHLY)", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY value %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return rule, fmt.Errorf("BYMONTHDAY must be 1..31 or -1")
+			}
+			rule.ByMonthDay = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return rule, fmt.Errorf("UNTIL must be a date like 20251231")
+			}
+			rule.Until = until
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return rule, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("FREQ is required")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != Monthly {
+		return rule, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Monthly {
+		return rule, fmt.Errorf("BYDAY is not supported with FREQ=MONTHLY")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	return rule, nil
+}
+
+// Occurs reports whether the series starting on start has an occurrence on day.
+// Both are civil dates; only their year, month and day are used.
+func (r Rule) Occurs(start, day time.Time) bool {
+	start, day = civil(start), civil(day)
+	if !r.matches(start, day) {
+		return false
+	}
+	if r.Count > 0 {
+		return r.countThrough(start, day) <= r.Count
+	}
+	return true
+}
+
+// Next returns the first occurrence on or after from, or false if there is none
+func (r Rule) Next(start, from time.Time) (time.Time, bool) {
+	start, from = civil(start), civil(from)
+	limit := from.AddDate(0, 0, maxLookahead)
+
+	// COUNT needs every occurrence since the start; otherwise skip ahead
+	day := start
+	if r.Count == 0 && from.After(start) {
+		day = from
+	}
+
+	count := 0
+	for ; !day.After(limit); day = day.AddDate(0, 0, 1) {
+		if !r.Until.IsZero() && day.After(r.Until) {
+			return time.Time{}, false
+		}
+		if !r.matches(start, day) {
+			continue
+		}
+		count++
+		if r.Count > 0 && count > r.Count {
+			return time.Time{}, false
+		}
+		if !day.Before(from) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// matches checks the frequency, interval, by-rules and UNTIL, ignoring COUNT
+func (r Rule) matches(start, day time.Time) bool {
+	if day.Before(start) {
+		return false
+	}
+	if !r.Until.IsZero() && day.After(r.Until) {
+		return false
+	}
+
+	switch r.Freq {
+	case Daily:
+		days := daysBetween(start, day)
+		if days%r.Interval != 0 {
+			return false
+		}
+		return len(r.ByDay) == 0 || containsWeekday(r.ByDay, day.Weekday())
+	case Weekly:
+		weeks := daysBetween(weekStart(start), weekStart(day)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return containsWeekday(r.ByDay, day.Weekday())
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		monthDay := r.ByMonthDay
+		if monthDay == 0 {
+			monthDay = start.Day()
+		}
+		if monthDay == -1 {
+			return day.AddDate(0, 0, 1).Month() != day.Month()
+		}
+		return day.Day() == monthDay
+	}
+	return false
+}
+
+// countThrough returns how many occurrences fall between start and day inclusive
+func (r Rule) countThrough(start, day time.Time) int {
+	count := 0
+	for d := start; !d.After(day); d = d.AddDate(0, 0, 1) {
+		if r.matches(start, d) {
+			count++
+		}
+	}
+	return count
+}
+
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// weekStart returns the Monday of the week containing t
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func containsWeekday(days []time.Weekday, weekday time.Weekday) bool {
+	for _, d := range days {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
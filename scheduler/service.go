@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxCatchUpDays limits how many missed days are materialized after downtime
+const maxCatchUpDays = 7
+
+// EntryCreator creates an entry for a civil date from a template, within a
+// transaction it runs
+type EntryCreator interface {
+	WithTx(ctx context.Context, fn func(q *db.Queries) error) error
+	CreateEntryFromTemplate(ctx context.Context, q *db.Queries, userID pgtype.UUID, template db.Template, date time.Time) (db.Entry, error)
+}
+
+// Scheduler materializes entries for recurring series on the right day in
+// each user's timezone
+type Scheduler struct {
+	queries         *db.Queries
+	creator         EntryCreator
+	defaultTimezone string
+	interval        time.Duration
+	runMu           sync.Mutex // held for a run, so runs don't overlap
+	mu              sync.Mutex
+	running         bool
+	stopCh          chan struct{}
+}
+
+func New(queries *db.Queries, creator EntryCreator, defaultTimezone string, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		queries:         queries,
+		creator:         creator,
+		defaultTimezone: defaultTimezone,
+		interval:        interval,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	log.Println("Recurrence scheduler started")
+
+	// Initial run
+	go s.materialize(ctx)
+
+	// Periodic runs
+	ticker := time.NewTicker(s.interval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				s.materialize(ctx)
+			case <-s.stopCh:
+				ticker.Stop()
+				return
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopCh)
+	s.running = false
+	log.Println("Recurrence scheduler stopped")
+}
+
+func (s *Scheduler) materialize(ctx context.Context) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	series, err := s.queries.ListActiveRecurrences(ctx)
+	if err != nil {
+		log.Printf("Error fetching recurrences: %v", err)
+		return
+	}
+
+	created := 0
+	for _, recurrence := range series {
+		rule, err := ParseRule(recurrence.Rrule)
+		if err != nil {
+			log.Printf("Skipping recurrence %s with invalid rule %q: %v", recurrence.ID, recurrence.Rrule, err)
+			continue
+		}
+
+		loc := s.location(recurrence.Timezone)
+		today := civilDate(time.Now(), loc)
+		start := recurrence.StartsOn.Time
+
+		// Resume after the last materialized day, but never backfill more than a week
+		from := today.AddDate(0, 0, -maxCatchUpDays)
+		if recurrence.LastOccurrence.Valid && !recurrence.LastOccurrence.Time.Before(from) {
+			from = recurrence.LastOccurrence.Time.AddDate(0, 0, 1)
+		}
+		// Days the series spent paused are skipped, not caught up on
+		if recurrence.ResumedAt.Valid {
+			if resumed := civilDate(recurrence.ResumedAt.Time, loc); from.Before(resumed) {
+				from = resumed
+			}
+		}
+
+		for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+			if s.stopped(ctx) {
+				return
+			}
+			if recurrence.EndsOn.Valid && day.After(recurrence.EndsOn.Time) {
+				break
+			}
+			if !rule.Occurs(start, day) {
+				continue
+			}
+			ok, err := s.materializeOccurrence(ctx, recurrence, day)
+			if err != nil {
+				log.Printf("Error materializing recurrence %s on %s: %v", recurrence.ID, day.Format("2006-01-02"), err)
+				continue
+			}
+			if ok {
+				created++
+			}
+		}
+	}
+
+	if created > 0 {
+		log.Printf("Created %d recurring entries", created)
+	}
+}
+
+// materializeOccurrence claims the occurrence and creates its entry in one
+// transaction, so a failure leaves the day unclaimed for the next run. It
+// returns false if another run already claimed the day.
+func (s *Scheduler) materializeOccurrence(ctx context.Context, recurrence db.ListActiveRecurrencesRow, day time.Time) (bool, error) {
+	occursOn := pgtype.Date{Time: day, Valid: true}
+
+	created := false
+	err := s.creator.WithTx(ctx, func(q *db.Queries) error {
+		_, err := q.ClaimOccurrence(ctx, db.ClaimOccurrenceParams{
+			RecurrenceID: recurrence.ID,
+			OccursOn:     occursOn,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		template, err := q.GetTemplate(ctx, db.GetTemplateParams{
+			ID:     recurrence.TemplateID,
+			UserID: recurrence.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		entry, err := s.creator.CreateEntryFromTemplate(ctx, q, recurrence.UserID, template, day)
+		if err != nil {
+			return err
+		}
+		if err := q.SetOccurrenceEntry(ctx, db.SetOccurrenceEntryParams{
+			RecurrenceID: recurrence.ID,
+			OccursOn:     occursOn,
+			EntryID:      entry.ID,
+		}); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created && err == nil, err
+}
+
+// stopped reports whether the scheduler has been stopped or its context
+// cancelled, so a run can end early
+func (s *Scheduler) stopped(ctx context.Context) bool {
+	select {
+	case <-s.stopCh:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// location loads the given timezone, falling back to the default and then UTC
+func (s *Scheduler) location(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc, err = time.LoadLocation(s.defaultTimezone)
+		if err != nil {
+			loc = time.UTC
+		}
+	}
+	return loc
+}
+
+// civilDate returns the civil date of t in loc, as midnight UTC
+func civilDate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
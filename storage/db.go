@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5"
+)
+
+// DBStore keeps contents in the attachment_blobs table. Contents are read
+// into memory whole, so it suits small attachments and single-host setups.
+type DBStore struct {
+	queries *db.Queries
+}
+
+func NewDBStore(queries *db.Queries) *DBStore {
+	return &DBStore{queries: queries}
+}
+
+func (s *DBStore) Name() string {
+	return "db"
+}
+
+func (s *DBStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("read %d bytes, expected %d", len(data), size)
+	}
+	return s.queries.PutAttachmentBlob(ctx, db.PutAttachmentBlobParams{Key: key, Data: data})
+}
+
+func (s *DBStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	data, err := s.queries.GetAttachmentBlob(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	return s.queries.DeleteAttachmentBlob(ctx, key)
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
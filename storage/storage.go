@@ -0,0 +1,191 @@
+// Package storage keeps attachment contents outside the attachments table.
+// Contents are addressed by the hex SHA-256 of their bytes, so identical
+// files share one stored object, and the blobs table counts its uses.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisbakker/journal/config"
+	db "github.com/chrisbakker/journal/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// ErrTooLarge is returned by Spool when the content exceeds its limit
+var ErrTooLarge = errors.New("content too large")
+
+// Store is an attachment storage backend
+type Store interface {
+	// Name is recorded in attachments.storage_backend
+	Name() string
+	// Put stores size bytes from r under key, the hex SHA-256 of the content.
+	// Putting a key that already exists is not an error.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns the content stored under key
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the content stored under key, if any
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the configured storage backend
+func New(cfg config.StorageConfig, queries *db.Queries) (Store, error) {
+	return ForBackend(cfg.Backend, cfg, queries)
+}
+
+// ForBackend returns the named backend, using cfg for its settings. Reads of
+// attachments stored before a backend change go through the backend they
+// were written to.
+func ForBackend(name string, cfg config.StorageConfig, queries *db.Queries) (Store, error) {
+	switch name {
+	case "db":
+		return NewDBStore(queries), nil
+	case "fs":
+		return NewFSStore(cfg.Dir), nil
+	case "s3":
+		return NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", name)
+	}
+}
+
+// Acquire records a use of the contents stored under key in a backend. Call
+// it before putting the contents, so that a concurrent Purge of the same
+// contents either finishes first or finds them in use.
+func Acquire(ctx context.Context, queries *db.Queries, backend, key string, size int64) error {
+	_, err := queries.AcquireBlob(ctx, db.AcquireBlobParams{
+		StorageBackend: backend,
+		StorageKey:     key,
+		SizeBytes:      size,
+	})
+	return err
+}
+
+// Release records that a use of the contents stored under key has gone,
+// reporting whether it was the last. Purge unused contents once the
+// release is committed.
+func Release(ctx context.Context, queries *db.Queries, backend, key string) (bool, error) {
+	remaining, err := queries.ReleaseBlob(ctx, db.ReleaseBlobParams{
+		StorageBackend: backend,
+		StorageKey:     key,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return remaining == 0, nil
+}
+
+// Purge deletes the contents stored under key in a backend, and their blob,
+// if nothing uses them. The blob stays locked until the contents are gone,
+// so an upload of the same file waits and then stores them afresh.
+func Purge(ctx context.Context, dbpool *pgxpool.Pool, backend, key string, cfg config.StorageConfig) error {
+	tx, err := dbpool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	queries := db.New(tx)
+
+	_, err = queries.LockUnusedBlob(ctx, db.LockUnusedBlobParams{
+		StorageBackend: backend,
+		StorageKey:     key,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	store, err := ForBackend(backend, cfg, queries)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return err
+	}
+	if err := queries.DeleteBlob(ctx, db.DeleteBlobParams{
+		StorageBackend: backend,
+		StorageKey:     key,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Upload is content spooled to a temporary file by Spool, ready to Put
+type Upload struct {
+	file *os.File
+	Key  string
+	Size int64
+}
+
+// Spool copies r to a temporary file, hashing it on the way, so the content
+// can be stored under its key without being held in memory. It fails with
+// ErrTooLarge if r holds more than limit bytes.
+func Spool(r io.Reader, limit int64) (*Upload, error) {
+	file, err := os.CreateTemp("", "journal-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	upload := &Upload{file: file}
+
+	hash := sha256.New()
+	upload.Size, err = io.Copy(io.MultiWriter(file, hash), io.LimitReader(r, limit+1))
+	if err == nil && upload.Size > limit {
+		err = ErrTooLarge
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+
+	upload.Key = hex.EncodeToString(hash.Sum(nil))
+	return upload, nil
+}
+
+func (u *Upload) Read(p []byte) (int, error) {
+	return u.file.Read(p)
+}
+
+// Seek rewinds the spooled content, for reading it again after a Put
+func (u *Upload) Seek(offset int64, whence int) (int64, error) {
+	return u.file.Seek(offset, whence)
+}
+
+// ReadAt reads the spooled content at an offset, so an uploaded archive can
+// be opened with archive/zip
+func (u *Upload) ReadAt(p []byte, off int64) (int, error) {
+	return u.file.ReadAt(p, off)
+}
+
+// Close removes the spool file
+func (u *Upload) Close() error {
+	u.file.Close()
+	return os.Remove(u.file.Name())
+}
+
+// validKey reports whether key is a hex SHA-256, which keeps keys safe to
+// use in paths and URLs
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
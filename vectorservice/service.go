@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	db "github.com/chrisbakker/journal/generated"
 	"github.com/chrisbakker/journal/ollama"
@@ -15,6 +16,10 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+// maxAttachmentEmbeddingBytes is how much of an attachment's text is
+// embedded; embedding models only read a few thousand tokens
+const maxAttachmentEmbeddingBytes = 8000
+
 type VectorService struct {
 	queries        *db.Queries
 	ollamaClient   *ollama.Client
@@ -90,8 +95,13 @@ func (s *VectorService) updateVectors(ctx context.Context) {
 		Valid: true,
 	}
 
+	s.updateEntryVectors(ctx, pgUUID)
+	s.updateAttachmentVectors(ctx, pgUUID)
+}
+
+func (s *VectorService) updateEntryVectors(ctx context.Context, userID pgtype.UUID) {
 	entries, err := s.queries.GetEntriesNeedingVectors(ctx, db.GetEntriesNeedingVectorsParams{
-		UserID: pgUUID,
+		UserID: userID,
 		Limit:  s.batchSize,
 	})
 	if err != nil {
@@ -133,6 +143,58 @@ func (s *VectorService) updateVectors(ctx context.Context) {
 	log.Printf("Successfully updated %d vectors", len(entries))
 }
 
+// updateAttachmentVectors embeds the extracted text of attachments, headed
+// by the filename
+func (s *VectorService) updateAttachmentVectors(ctx context.Context, userID pgtype.UUID) {
+	attachments, err := s.queries.GetAttachmentsNeedingVectors(ctx, db.GetAttachmentsNeedingVectorsParams{
+		UserID: userID,
+		Limit:  s.batchSize,
+	})
+	if err != nil {
+		log.Printf("Error fetching attachments needing vectors: %v", err)
+		return
+	}
+
+	if len(attachments) == 0 {
+		return
+	}
+
+	log.Printf("Updating vectors for %d attachments", len(attachments))
+
+	for _, attachment := range attachments {
+		text := s.prepareTextForEmbedding(attachment.Filename, truncate(attachment.Content, maxAttachmentEmbeddingBytes))
+
+		embedding, err := s.ollamaClient.GenerateEmbedding(ctx, text)
+		if err != nil {
+			log.Printf("Error generating embedding for attachment %s: %v", attachment.AttachmentID, err)
+			continue
+		}
+
+		vec := pgvector.NewVector(embedding)
+		err = s.queries.UpdateAttachmentVector(ctx, db.UpdateAttachmentVectorParams{
+			AttachmentID:    attachment.AttachmentID,
+			EmbeddingVector: &vec,
+		})
+		if err != nil {
+			log.Printf("Error updating vector for attachment %s: %v", attachment.AttachmentID, err)
+			continue
+		}
+	}
+
+	log.Printf("Successfully updated %d attachment vectors", len(attachments))
+}
+
+// truncate cuts text to at most n bytes on a character boundary
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
 func (s *VectorService) prepareTextForEmbedding(title, bodyText string) string {
 	// Combine title and body (plain text from Quill, no HTML stripping needed)
 	if title != "" {
@@ -159,6 +221,37 @@ func stripHTML(html string) string {
 	return strings.TrimSpace(result.String())
 }
 
+// SearchSimilar finds the entries and attachments closest to a query,
+// embedding the query once for both searches
+func (s *VectorService) SearchSimilar(ctx context.Context, userID uuid.UUID, query string, entryLimit, attachmentLimit int32) ([]db.SearchSimilarEntriesRow, []db.SearchSimilarAttachmentsRow, error) {
+	embedding, err := s.ollamaClient.GenerateEmbedding(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+	vec := pgvector.NewVector(embedding)
+	pgUUID := pgtype.UUID{Bytes: userID, Valid: true}
+
+	entries, err := s.queries.SearchSimilarEntries(ctx, db.SearchSimilarEntriesParams{
+		UserID:  pgUUID,
+		Column2: vec,
+		Limit:   entryLimit,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search similar entries: %w", err)
+	}
+
+	attachments, err := s.queries.SearchSimilarAttachments(ctx, db.SearchSimilarAttachmentsParams{
+		UserID:  pgUUID,
+		Column2: vec,
+		Limit:   attachmentLimit,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search similar attachments: %w", err)
+	}
+
+	return entries, attachments, nil
+}
+
 func (s *VectorService) SearchSimilarEntries(ctx context.Context, userID uuid.UUID, query string, limit int32) ([]db.SearchSimilarEntriesRow, error) {
 	// Generate embedding for the query
 	embedding, err := s.ollamaClient.GenerateEmbedding(ctx, query)
@@ -15,7 +15,7 @@ interface Entry {
   body_html: string;
   attendees_original: string;
   attendees: string[];
-  type: 'meeting' | 'notes' | 'other';
+  type: string;
   day_year: number;
   day_month: number;
   day_day: number;
@@ -23,10 +23,20 @@ interface Entry {
   updated_at: string;
 }
 
+interface EntryType {
+  id: string;
+  name: string;
+  color: string;
+  icon: string;
+  has_attendees: boolean;
+  position: number;
+}
+
 class JournalApp {
   private currentDate: Date;
   private selectedDate: Date;
   private entries: Entry[] = [];
+  private entryTypes: EntryType[] = [];
   private daysWithEntries: Set<number> = new Set();
   private currentEditingEntry: Entry | null = null;
   private quill: Quill | null = null;
@@ -51,6 +61,7 @@ class JournalApp {
     }
 
     this.renderCalendar();
+    await this.loadEntryTypes();
     this.loadDaysWithEntries();
     this.loadEntries();
     this.setupEventListeners();
@@ -351,8 +362,12 @@ class JournalApp {
         const assistantMessageEl = document.createElement('div');
         assistantMessageEl.className = 'chat-message assistant';
         assistantMessageEl.dataset.messageId = data.message_id;
+        const sourceFiles = (data.source_attachments || []).map((a: { id: string; filename: string; entry_title: string }) =>
+          `<a href="${API_BASE}/attachments/${encodeURIComponent(a.id)}" target="_blank" rel="noopener" title="Attached to ${this.escapeHtml(a.entry_title).replace(/"/g, '&quot;')}">${this.escapeHtml(a.filename)}</a>`
+        ).join(', ');
         assistantMessageEl.innerHTML = `
           <div class="chat-message-content">${this.escapeHtml(data.response)}</div>
+          ${sourceFiles ? `<div class="chat-message-sources">Files: ${sourceFiles}</div>` : ''}
           <div class="chat-message-time">${new Date().toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })}</div>
         `;
         
@@ -539,6 +554,21 @@ class JournalApp {
            d1.getDate() === d2.getDate();
   }
 
+  private async loadEntryTypes() {
+    try {
+      const response = await fetch(`${API_BASE}/entry-types`);
+      if (response.ok) {
+        this.entryTypes = await response.json();
+      }
+    } catch (error) {
+      console.error('Failed to load entry types:', error);
+    }
+  }
+
+  private entryType(name: string): EntryType | undefined {
+    return this.entryTypes.find(t => t.name === name);
+  }
+
   private async loadDaysWithEntries() {
     const year = this.currentDate.getFullYear();
     const month = this.currentDate.getMonth() + 1;
@@ -614,7 +644,8 @@ class JournalApp {
     const info = document.createElement('div');
     info.className = 'entry-info';
     
-    const typeSpan = `<span class="entry-type">${entry.type}</span>`;
+    const color = this.entryType(entry.type)?.color;
+    const typeSpan = `<span class="entry-type"${color ? ` style="color: ${color}"` : ''}>${this.escapeHtml(entry.type)}</span>`;
     const attendeesSpan = entry.attendees.length > 0 
       ? `<span class="entry-attendees">with ${entry.attendees.join(', ')}</span>` 
       : '';
@@ -648,13 +679,12 @@ class JournalApp {
     const day = this.selectedDate.getDate();
     const dateStr = `${year}-${String(month).padStart(2, '0')}-${String(day).padStart(2, '0')}`;
 
+    // The body is left out so the server starts it from the type's template
+    const defaultType = this.entryType('notes') ?? this.entryTypes[0];
     const newEntry = {
       title: '',
-      body_delta: { ops: [{ insert: '\n' }] },
-      body_html: '<p><br></p>',
-      body_text: '',
       attendees_original: '',
-      type: 'notes',
+      type: defaultType?.name ?? 'notes',
       date: dateStr
     };
 
@@ -757,14 +787,17 @@ class JournalApp {
     const formFields = document.createElement('div');
     formFields.className = 'entry-form-fields';
 
+    // The user's own types; an entry's type is kept even if the list lacks it
+    const typeNames = this.entryTypes.map(t => t.name);
+    if (!typeNames.includes(entry.type)) {
+      typeNames.push(entry.type);
+    }
     const typeField = document.createElement('div');
     typeField.className = 'form-field';
     typeField.innerHTML = `
       <label>Type</label>
       <select id="entry-type">
-        <option value="notes" ${entry.type === 'notes' ? 'selected' : ''}>Notes</option>
-        <option value="meeting" ${entry.type === 'meeting' ? 'selected' : ''}>Meeting</option>
-        <option value="other" ${entry.type === 'other' ? 'selected' : ''}>Other</option>
+        ${typeNames.map(name => `<option value="${this.escapeHtml(name).replace(/"/g, '&quot;')}" ${entry.type === name ? 'selected' : ''}>${this.escapeHtml(name.charAt(0).toUpperCase() + name.slice(1))}</option>`).join('')}
       </select>
     `;
 
@@ -772,9 +805,20 @@ class JournalApp {
     attendeesField.className = 'form-field';
     attendeesField.innerHTML = `
       <label>Attendees (comma-separated)</label>
-      <input type="text" id="entry-attendees" value="${entry.attendees_original}" placeholder="Alice, Bob, Carol" autocomplete="off">
+      <input type="text" id="entry-attendees" value="${this.escapeHtml(entry.attendees_original).replace(/"/g, '&quot;')}" placeholder="Alice, Bob, Carol" autocomplete="off">
     `;
 
+    // Only types with attendees show the field, unless the entry already has some
+    const updateAttendeesField = (typeName: string) => {
+      const hasAttendees = this.entryType(typeName)?.has_attendees ?? true;
+      const input = attendeesField.querySelector('#entry-attendees') as HTMLInputElement | null;
+      attendeesField.style.display = hasAttendees || input?.value ? '' : 'none';
+    };
+    updateAttendeesField(entry.type);
+    typeField.querySelector('#entry-type')?.addEventListener('change', (e) => {
+      updateAttendeesField((e.target as HTMLSelectElement).value);
+    });
+
     formFields.appendChild(typeField);
     formFields.appendChild(attendeesField);
     card.appendChild(formFields);